@@ -0,0 +1,301 @@
+package archivemuri_test
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	archivemuri "github.com/MuriData/muri-zkproof/circuits/archive_muri"
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/MuriData/muri-zkproof/pkg/merkle"
+	"github.com/MuriData/muri-zkproof/pkg/setup"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+)
+
+// buildReplica is a test helper that generates random member files with the
+// given chunk counts, builds their sparse Merkle trees and seals the archive.
+func buildReplica(t *testing.T, chunkCounts []int) *archivemuri.Replica {
+	t.Helper()
+	zeroLeaf := crypto.ComputeZeroLeafHashFr(archivemuri.ElementSize, archivemuri.ElementsPerChunk)
+
+	var files []archivemuri.ArchiveFile
+	for _, n := range chunkCounts {
+		data := make([]byte, n*archivemuri.FileSize)
+		if _, err := rand.Read(data); err != nil {
+			t.Fatalf("generate random data: %v", err)
+		}
+		chunks := merkle.SplitIntoChunks(data, archivemuri.FileSize)
+		tree, err := merkle.GenerateSparseMerkleTree(chunks, archivemuri.MaxTreeDepth, archivemuri.HashChunk, zeroLeaf)
+		if err != nil {
+			t.Fatalf("build SMT: %v", err)
+		}
+		files = append(files, archivemuri.ArchiveFile{Chunks: chunks, Tree: tree})
+	}
+
+	secretKey, err := crypto.GenerateSecretKey()
+	if err != nil {
+		t.Fatalf("generate secret key: %v", err)
+	}
+	replica, err := archivemuri.SealArchive(crypto.DerivePublicKey(secretKey), files)
+	if err != nil {
+		t.Fatalf("seal archive: %v", err)
+	}
+	return replica
+}
+
+// TestArchiveMuriCircuitEndToEnd compiles the circuit, performs a dev setup,
+// seals a random two-file archive, prepares a witness, generates a proof,
+// and verifies it.
+func TestArchiveMuriCircuitEndToEnd(t *testing.T) {
+	// 1. Compile
+	ccs, err := setup.CompileCircuit(&archivemuri.ArchiveMuriCircuit{})
+	if err != nil {
+		t.Fatalf("compile circuit: %v", err)
+	}
+	t.Logf("Constraints: %d", ccs.GetNbConstraints())
+
+	// 2. Dev setup (single-party, not for production)
+	pk, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		t.Fatalf("groth16 setup: %v", err)
+	}
+
+	// 3. Seal a random archive (3 + 2 chunks)
+	replica := buildReplica(t, []int{3, 2})
+	t.Logf("Sealed %d chunks, sealed root: 0x%x", replica.Archive.TotalRealChunks, replica.SealedTree.Root.Bytes())
+
+	// 4. Prepare witness
+	seed, err := rand.Int(rand.Reader, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatalf("generate seed: %v", err)
+	}
+	result, err := archivemuri.PrepareWitness(replica, seed, 0)
+	if err != nil {
+		t.Fatalf("prepare witness: %v", err)
+	}
+	t.Logf("Challenged positions: %v", result.Positions)
+
+	// 5. Prove and verify
+	witness, err := frontend.NewWitness(&result.Assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatalf("create witness: %v", err)
+	}
+	publicWitness, err := witness.Public()
+	if err != nil {
+		t.Fatalf("extract public witness: %v", err)
+	}
+	proof, err := groth16.Prove(ccs, pk, witness)
+	if err != nil {
+		t.Fatalf("prove: %v", err)
+	}
+	if err := groth16.Verify(proof, vk, publicWitness); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	t.Log("ZK proof verified successfully!")
+}
+
+// TestArchiveMuriBoundaryPositions checks that the first and last elements of
+// the archive (seeded keys, dummy successor/predecessor openings) and an
+// archive larger than the back-pointer window all satisfy the circuit.
+func TestArchiveMuriBoundaryPositions(t *testing.T) {
+	ccs, err := setup.CompileCircuit(&archivemuri.ArchiveMuriCircuit{})
+	if err != nil {
+		t.Fatalf("compile circuit: %v", err)
+	}
+
+	solve := func(t *testing.T, replica *archivemuri.Replica, seed *big.Int) {
+		t.Helper()
+		result, err := archivemuri.PrepareWitness(replica, seed, 0)
+		if err != nil {
+			t.Fatalf("prepare witness: %v", err)
+		}
+		witness, err := frontend.NewWitness(&result.Assignment, ecc.BN254.ScalarField())
+		if err != nil {
+			t.Fatalf("create witness: %v", err)
+		}
+		if err := ccs.IsSolved(witness); err != nil {
+			t.Fatalf("position %v: %v", result.Positions, err)
+		}
+	}
+
+	t.Run("first_and_last", func(t *testing.T) {
+		replica := buildReplica(t, []int{1})
+		n := len(replica.Sealed)
+		wanted := map[int]bool{0: true, n - 1: true}
+		for rnd := int64(1); len(wanted) > 0; rnd++ {
+			if rnd > 100*int64(n) {
+				t.Fatalf("no seed found for positions %v", wanted)
+			}
+			seed := big.NewInt(rnd)
+			result, err := archivemuri.PrepareWitness(replica, seed, 0)
+			if err != nil {
+				t.Fatalf("prepare witness: %v", err)
+			}
+			if pos := result.Positions[0]; wanted[pos] {
+				delete(wanted, pos)
+				solve(t, replica, seed)
+			}
+		}
+	})
+
+	t.Run("beyond_pointer_window", func(t *testing.T) {
+		// 130 chunks = 68 770 elements > BackPointerWindow.
+		replica := buildReplica(t, []int{100, 30})
+		solve(t, replica, big.NewInt(7))
+	})
+}
+
+// TestArchiveMuriSealProofs checks that the SealProofsPerReplica sealing
+// proofs of one replica use distinct randomness and all satisfy the circuit,
+// and that the circuit binds each proof to its seed and proof index.
+func TestArchiveMuriSealProofs(t *testing.T) {
+	ccs, err := setup.CompileCircuit(&archivemuri.ArchiveMuriCircuit{})
+	if err != nil {
+		t.Fatalf("compile circuit: %v", err)
+	}
+
+	replica := buildReplica(t, []int{3})
+	seed := big.NewInt(2024)
+	results, err := archivemuri.PrepareSealProofWitnesses(replica, seed)
+	if err != nil {
+		t.Fatalf("prepare sealing proofs: %v", err)
+	}
+	if len(results) != archivemuri.SealProofsPerReplica {
+		t.Fatalf("got %d sealing proofs, expected %d", len(results), archivemuri.SealProofsPerReplica)
+	}
+
+	seen := make(map[string]bool)
+	for i, result := range results {
+		randomness := archivemuri.SealProofRandomness(seed, replica.SealedTree.Root, i)
+		if result.Randomness.Cmp(randomness) != 0 {
+			t.Fatalf("proof %d: randomness does not match SealProofRandomness", i)
+		}
+		if seen[randomness.String()] {
+			t.Fatalf("proof %d: randomness repeats an earlier proof", i)
+		}
+		seen[randomness.String()] = true
+
+		witness, err := frontend.NewWitness(&result.Assignment, ecc.BN254.ScalarField())
+		if err != nil {
+			t.Fatalf("proof %d: create witness: %v", i, err)
+		}
+		if err := ccs.IsSolved(witness); err != nil {
+			t.Fatalf("proof %d at position %v: %v", i, result.Positions, err)
+		}
+	}
+
+	// Openings of proof 0 do not satisfy another proof index or seed.
+	for name, mutate := range map[string]func(*archivemuri.ArchiveMuriCircuit){
+		"wrong proof index": func(c *archivemuri.ArchiveMuriCircuit) { c.ProofIndex = 1 },
+		"wrong seed":        func(c *archivemuri.ArchiveMuriCircuit) { c.Seed = big.NewInt(2025) },
+		"index out of range": func(c *archivemuri.ArchiveMuriCircuit) {
+			c.ProofIndex = archivemuri.SealProofsPerReplica
+		},
+	} {
+		assignment := results[0].Assignment
+		mutate(&assignment)
+		witness, err := frontend.NewWitness(&assignment, ecc.BN254.ScalarField())
+		if err != nil {
+			t.Fatalf("%s: create witness: %v", name, err)
+		}
+		if err := ccs.IsSolved(witness); err == nil {
+			t.Fatalf("%s: expected circuit to reject the witness", name)
+		}
+	}
+	if _, err := archivemuri.PrepareWitness(replica, seed, archivemuri.SealProofsPerReplica); err == nil {
+		t.Fatal("expected PrepareWitness to reject an out-of-range proof index")
+	}
+}
+
+// TestArchiveMuriRejectsTamperedSeal verifies that a single altered sealed
+// element breaks the witness.
+func TestArchiveMuriRejectsTamperedSeal(t *testing.T) {
+	replica := buildReplica(t, []int{2})
+	result, err := archivemuri.PrepareWitness(replica, big.NewInt(42), 0)
+	if err != nil {
+		t.Fatalf("prepare witness: %v", err)
+	}
+
+	pos := result.Positions[0]
+	replica.Sealed[pos].SetUint64(12345)
+	tampered, err := archivemuri.PrepareWitness(replica, big.NewInt(42), 0)
+	if err != nil {
+		t.Fatalf("prepare witness: %v", err)
+	}
+
+	ccs, err := setup.CompileCircuit(&archivemuri.ArchiveMuriCircuit{})
+	if err != nil {
+		t.Fatalf("compile circuit: %v", err)
+	}
+	witness, err := frontend.NewWitness(&tampered.Assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatalf("create witness: %v", err)
+	}
+	if err := ccs.IsSolved(witness); err == nil {
+		t.Fatal("expected circuit to reject tampered sealed element")
+	}
+}
+
+// TestArchiveMuriExportFixture generates a deterministic fixture and verifies
+// that it round-trips through JSON.
+func TestArchiveMuriExportFixture(t *testing.T) {
+	// 1. Compile and dev setup
+	ccs, err := setup.CompileCircuit(&archivemuri.ArchiveMuriCircuit{})
+	if err != nil {
+		t.Fatalf("compile circuit: %v", err)
+	}
+	pk, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		t.Fatalf("groth16 setup: %v", err)
+	}
+
+	// 2. Write keys to temp directory
+	tmpDir := t.TempDir()
+	if err := setup.ExportKeys(pk, vk, tmpDir, "archive_muri"); err != nil {
+		t.Fatalf("export keys: %v", err)
+	}
+
+	// 3. Generate fixture
+	jsonOut, err := archivemuri.ExportProofFixture(tmpDir)
+	if err != nil {
+		t.Fatalf("export proof fixture: %v", err)
+	}
+
+	// 4. Verify JSON round-trips
+	var fixture archivemuri.ProofFixture
+	if err := json.Unmarshal(jsonOut, &fixture); err != nil {
+		t.Fatalf("unmarshal fixture: %v", err)
+	}
+
+	if fixture.PublicKey == "" {
+		t.Fatal("fixture public key is empty")
+	}
+	if fixture.ArchiveOriginalRoot == "" {
+		t.Fatal("fixture archive original root is empty")
+	}
+	if fixture.SealedRoot == "" {
+		t.Fatal("fixture sealed root is empty")
+	}
+	if fixture.TotalRealChunks == "" {
+		t.Fatal("fixture total real chunks is empty")
+	}
+	for i, p := range fixture.SolidityProof {
+		if p == "" {
+			t.Fatalf("fixture solidity proof[%d] is empty", i)
+		}
+	}
+
+	jsonRoundTrip, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		t.Fatalf("re-marshal fixture: %v", err)
+	}
+	if string(jsonRoundTrip) != string(jsonOut) {
+		t.Fatal("fixture JSON round-trip mismatch")
+	}
+
+	fmt.Println("Fixture round-trip OK")
+}
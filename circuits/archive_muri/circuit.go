@@ -0,0 +1,123 @@
+package archivemuri
+
+import (
+	"github.com/MuriData/muri-zkproof/circuits/shared"
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/bits"
+)
+
+// ArchiveMuriCircuit proves that SealedRoot commits to the Archive MURI
+// sealing of the archive identified by ArchiveOriginalRoot, under the
+// replica randomness r = H(publicKey, archiveOriginalRoot).
+//
+// Proof proofIndex of a replica challenges the positions selected by
+// randomness = H(DomainTagSealProof, seed, sealedRoot, proofIndex), derived
+// in the circuit so that it is bound to the sealed root. The contract draws
+// seed only after the sealed root is registered and accepts the root once it
+// has verified proofs for every proofIndex in [0, SealProofsPerReplica).
+//
+// For each of ChallengeCount elements j (selected by the randomness), the
+// circuit traces the sealing DAG backwards from sealed[j] — through the
+// pass-2 key of j and the pass-2 routes of its pass-1 parents — to recover
+// orig[j], and checks it against the original element opened through the
+// archive's slot tree.
+type ArchiveMuriCircuit struct {
+	// Public inputs (6)
+	PublicKey           frontend.Variable `gnark:"publicKey,public"`
+	ArchiveOriginalRoot frontend.Variable `gnark:"archiveOriginalRoot,public"`
+	SealedRoot          frontend.Variable `gnark:"sealedRoot,public"`
+	TotalRealChunks     frontend.Variable `gnark:"totalRealChunks,public"`
+	Seed                frontend.Variable `gnark:"seed,public"`
+	ProofIndex          frontend.Variable `gnark:"proofIndex,public"`
+
+	// Private inputs
+	SlotTreeRoot frontend.Variable                 `gnark:"slotTreeRoot"`
+	Quotients    [ChallengeCount]frontend.Variable `gnark:"quotients"`
+	Positions    [ChallengeCount]frontend.Variable `gnark:"positions"`
+	Originals    [ChallengeCount]OriginalOpening   `gnark:"originals"`
+	Routes       [ChallengeCount]SealRoute         `gnark:"routes"`
+}
+
+func (circuit *ArchiveMuriCircuit) Define(api frontend.API) error {
+	sponge, err := shared.NewSpongeHasher(api)
+	if err != nil {
+		return err
+	}
+
+	// ---------------------------------------------------------------
+	// 1. Public input sanity: non-zero key and seed, proofIndex in
+	//    [0, SealProofsPerReplica), totalRealChunks in [1, TotalLeaves].
+	// ---------------------------------------------------------------
+	api.AssertIsEqual(api.IsZero(circuit.PublicKey), 0)
+	api.AssertIsEqual(api.IsZero(circuit.Seed), 0)
+	api.AssertIsLessOrEqual(circuit.ProofIndex, SealProofsPerReplica-1)
+	api.AssertIsEqual(api.IsZero(circuit.TotalRealChunks), 0)
+	api.AssertIsLessOrEqual(circuit.TotalRealChunks, TotalLeaves)
+
+	// Challenge randomness of this proof, bound to the sealed root.
+	randomness, err := sponge.Hash(
+		frontend.Variable(crypto.DomainTagSealProof),
+		circuit.Seed, circuit.SealedRoot, circuit.ProofIndex,
+	)
+	if err != nil {
+		return err
+	}
+
+	// ---------------------------------------------------------------
+	// 2. Archive root: archiveOriginalRoot == H(slotTreeRoot, totalRealChunks).
+	// ---------------------------------------------------------------
+	archiveRoot, err := sponge.Hash(
+		frontend.Variable(crypto.DomainTagArchiveRoot),
+		circuit.SlotTreeRoot, circuit.TotalRealChunks,
+	)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(circuit.ArchiveOriginalRoot, archiveRoot)
+
+	// ---------------------------------------------------------------
+	// 3. Replica randomness r and the two pass seeds.
+	// ---------------------------------------------------------------
+	r, err := sponge.Hash(frontend.Variable(crypto.DomainTagGlobalR), circuit.PublicKey, circuit.ArchiveOriginalRoot)
+	if err != nil {
+		return err
+	}
+	ctx, err := newRouteContext(api, sponge, r, circuit.SealedRoot, circuit.TotalRealChunks)
+	if err != nil {
+		return err
+	}
+
+	for k := 0; k < ChallengeCount; k++ {
+		// -----------------------------------------------------------
+		// 4. Challenge position: low 64 bits of H(randomness, k) mod N.
+		// -----------------------------------------------------------
+		challenge, err := sponge.Hash(frontend.Variable(crypto.DomainTagChallengeIdx), randomness, k)
+		if err != nil {
+			return err
+		}
+		challengeBits := api.ToBinary(challenge, api.Compiler().FieldBitLen())
+		window := bits.FromBinary(api, challengeBits[:PointerWindowBits], bits.WithUnconstrainedInputs())
+		ctx.reduceWindow(window, ctx.numElements, circuit.Quotients[k], circuit.Positions[k])
+		pos := circuit.Positions[k]
+
+		// -----------------------------------------------------------
+		// 5. Original element via slot tree + member file tree.
+		// -----------------------------------------------------------
+		orig, err := circuit.Originals[k].Open(ctx, circuit.SlotTreeRoot, pos)
+		if err != nil {
+			return err
+		}
+
+		// -----------------------------------------------------------
+		// 6. Unseal through the DAG route and compare.
+		// -----------------------------------------------------------
+		unsealed, err := circuit.Routes[k].Unseal(ctx, pos)
+		if err != nil {
+			return err
+		}
+		api.AssertIsEqual(unsealed, orig)
+	}
+
+	return nil
+}
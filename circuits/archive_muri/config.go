@@ -0,0 +1,45 @@
+package archivemuri
+
+import (
+	"github.com/MuriData/muri-zkproof/pkg/archive"
+	"github.com/MuriData/muri-zkproof/pkg/muri"
+)
+
+const (
+	FileSize         = muri.FileSize         // 16 KB chunk size (must match PoI)
+	ElementSize      = muri.ElementSize      // bytes per field element (must match PoI)
+	ElementsPerChunk = muri.ElementsPerChunk // 529 — field elements per chunk (must match PoI NumChunks)
+
+	MaxTreeDepth = 20
+	TotalLeaves  = 1 << MaxTreeDepth // max real chunks per archive and per member file
+
+	SlotTreeDepth = archive.SlotTreeDepth
+	MaxSlots      = archive.MaxSlots // max member files per archive
+
+	// MaxElements bounds the archive element sequence (TotalLeaves chunks of
+	// ElementsPerChunk elements); every element position fits in 30 bits.
+	MaxElements = TotalLeaves * ElementsPerChunk
+
+	// Sealing parameters are defined by the native sealer in pkg/muri.
+	BackPointerCount  = muri.BackPointerCount
+	BackPointerWindow = muri.BackPointerWindow
+	PointerWindowBits = muri.PointerWindowBits
+
+	// ChallengeCount is the number of sealed elements verified per
+	// ArchiveMuriCircuit or ReplicationCircuit proof. Each challenge traces a
+	// full sealing route (about one million constraints), so one proof only
+	// catches a replica with a fraction f of wrongly sealed elements with
+	// probability f. Soundness instead comes from SealProofsPerReplica
+	// proofs, see ArchiveMuriCircuit.
+	ChallengeCount = 1
+
+	// SealProofsPerReplica is the number of sealing proofs, with proof
+	// indices 0..SealProofsPerReplica-1, the contract requires before it
+	// accepts a sealed root (matching the 8 openings of a PoI proof). A
+	// replica with a fraction f of wrongly sealed elements then passes with
+	// probability (1-f)^8, about 0.4% for f = 1/2.
+	SealProofsPerReplica = 8
+
+	ReplicaCount     = 2  // replicas verified per ReplicationCircuit proof
+	ReplicaIndexBits = 32 // replica indices are uint32 on-chain
+)
@@ -0,0 +1,187 @@
+package archivemuri
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/MuriData/muri-zkproof/pkg/merkle"
+	"github.com/MuriData/muri-zkproof/pkg/setup"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/frontend"
+)
+
+// ProofFixture holds all values needed for Solidity tests.
+type ProofFixture struct {
+	SolidityProof       [8]string `json:"solidity_proof"`
+	PublicKey           string    `json:"public_key"`
+	ArchiveOriginalRoot string    `json:"archive_original_root"`
+	SealedRoot          string    `json:"sealed_root"`
+	TotalRealChunks     string    `json:"total_real_chunks"`
+	Seed                string    `json:"seed"`
+	ProofIndex          string    `json:"proof_index"`
+}
+
+// ExportProofFixture generates a deterministic proof fixture for Solidity tests.
+// keysDir is the directory containing the proving and verifying keys.
+func ExportProofFixture(keysDir string) ([]byte, error) {
+	// 1. Compile the circuit
+	fmt.Println("Compiling circuit...")
+	ccs, err := setup.CompileCircuit(&ArchiveMuriCircuit{})
+	if err != nil {
+		return nil, fmt.Errorf("compile circuit: %w", err)
+	}
+
+	// 2. Load proving and verifying keys
+	fmt.Println("Loading keys...")
+	pk, vk, err := setup.LoadKeys(keysDir, "archive_muri")
+	if err != nil {
+		return nil, fmt.Errorf("load keys: %w", err)
+	}
+
+	// 3. Create a deterministic two-file archive (4 + 2 chunks).
+	files, err := fixtureFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. Deterministic key, seed and proof index, then seal the archive.
+	secretKey := new(big.Int).SetUint64(12345)
+	publicKey := crypto.DerivePublicKey(secretKey)
+	seed := new(big.Int).SetUint64(42)
+	proofIndex := 0
+
+	replica, err := SealArchive(publicKey, files)
+	if err != nil {
+		return nil, fmt.Errorf("seal archive: %w", err)
+	}
+	fmt.Printf("Archive original root: 0x%x\n", replica.Archive.OriginalRoot.Bytes())
+	fmt.Printf("Sealed root: 0x%x\n", replica.SealedTree.Root.Bytes())
+	fmt.Printf("Total real chunks: %d\n", replica.Archive.TotalRealChunks)
+
+	result, err := PrepareWitness(replica, seed, proofIndex)
+	if err != nil {
+		return nil, fmt.Errorf("prepare witness: %w", err)
+	}
+	fmt.Printf("Challenged positions: %v\n", result.Positions)
+
+	// 5. Create witness and generate proof
+	witness, err := frontend.NewWitness(&result.Assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		return nil, fmt.Errorf("create witness: %w", err)
+	}
+
+	publicWitness, err := witness.Public()
+	if err != nil {
+		return nil, fmt.Errorf("extract public witness: %w", err)
+	}
+
+	fmt.Println("Generating proof...")
+	proof, err := groth16.Prove(ccs, pk, witness)
+	if err != nil {
+		return nil, fmt.Errorf("prove: %w", err)
+	}
+
+	// 6. Verify proof in Go
+	err = groth16.Verify(proof, vk, publicWitness)
+	if err != nil {
+		return nil, fmt.Errorf("verify: %w", err)
+	}
+	fmt.Println("Proof verified successfully in Go!")
+
+	// 7. Extract proof points for Solidity
+	bn254Proof := proof.(*groth16bn254.Proof)
+
+	aX := new(big.Int)
+	aY := new(big.Int)
+	bn254Proof.Ar.X.BigInt(aX)
+	bn254Proof.Ar.Y.BigInt(aY)
+
+	bX0 := new(big.Int)
+	bX1 := new(big.Int)
+	bY0 := new(big.Int)
+	bY1 := new(big.Int)
+	bn254Proof.Bs.X.A0.BigInt(bX0)
+	bn254Proof.Bs.X.A1.BigInt(bX1)
+	bn254Proof.Bs.Y.A0.BigInt(bY0)
+	bn254Proof.Bs.Y.A1.BigInt(bY1)
+
+	cX := new(big.Int)
+	cY := new(big.Int)
+	bn254Proof.Krs.X.BigInt(cX)
+	bn254Proof.Krs.Y.BigInt(cY)
+
+	// Solidity format: [A.x, A.y, B.x1, B.x0, B.y1, B.y0, C.x, C.y]
+	solidityProof := [8]*big.Int{aX, aY, bX1, bX0, bY1, bY0, cX, cY}
+
+	fixture := ProofFixture{
+		PublicKey:           fmt.Sprintf("0x%064x", publicKey),
+		ArchiveOriginalRoot: fmt.Sprintf("0x%064x", replica.Archive.OriginalRootBigInt()),
+		SealedRoot:          fmt.Sprintf("0x%064x", replica.SealedTree.RootBigInt()),
+		TotalRealChunks:     fmt.Sprintf("%d", replica.Archive.TotalRealChunks),
+		Seed:                fmt.Sprintf("0x%064x", seed),
+		ProofIndex:          fmt.Sprintf("%d", proofIndex),
+	}
+	for i := 0; i < 8; i++ {
+		fixture.SolidityProof[i] = fmt.Sprintf("0x%064x", solidityProof[i])
+	}
+
+	jsonOut, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal fixture: %w", err)
+	}
+
+	// Print diagnostic info
+	fmt.Println("\n=== PROOF FIXTURE (JSON) ===")
+	fmt.Println(string(jsonOut))
+
+	fmt.Println("\n=== SOLIDITY CONSTANTS ===")
+	fmt.Printf("    // Public inputs\n")
+	fmt.Printf("    uint256 constant MURI_PUB_KEY = %s;\n", fixture.PublicKey)
+	fmt.Printf("    uint256 constant MURI_ARCHIVE_ROOT = %s;\n", fixture.ArchiveOriginalRoot)
+	fmt.Printf("    uint256 constant MURI_SEALED_ROOT = %s;\n", fixture.SealedRoot)
+	fmt.Printf("    uint32 constant MURI_TOTAL_CHUNKS = %s;\n", fixture.TotalRealChunks)
+	fmt.Printf("    uint256 constant MURI_SEED = %s;\n", fixture.Seed)
+	fmt.Printf("    uint8 constant MURI_PROOF_INDEX = %s;\n", fixture.ProofIndex)
+	fmt.Println()
+	fmt.Printf("    // Proof (uint256[8])\n")
+	for i := 0; i < 8; i++ {
+		fmt.Printf("    uint256 constant MURI_PROOF_%d = %s;\n", i, fixture.SolidityProof[i])
+	}
+
+	// Public witness info
+	fmt.Println("\n=== PUBLIC WITNESS ORDER ===")
+	fmt.Println("In gnark circuit (= Solidity order): [publicKey, archiveOriginalRoot, sealedRoot, totalRealChunks, seed, proofIndex]")
+	var pubWitBuf bytes.Buffer
+	_, err = publicWitness.WriteTo(&pubWitBuf)
+	if err != nil {
+		return nil, fmt.Errorf("write public witness: %w", err)
+	}
+	fmt.Printf("Public witness size: %d bytes\n", pubWitBuf.Len())
+
+	return jsonOut, nil
+}
+
+// fixtureFiles returns the deterministic two-file archive (4 + 2 chunks)
+// used by the proof fixtures.
+func fixtureFiles() ([]ArchiveFile, error) {
+	zeroLeaf := crypto.ComputeZeroLeafHashFr(ElementSize, ElementsPerChunk)
+	var files []ArchiveFile
+	for f, numChunks := range []int{4, 2} {
+		fileData := make([]byte, numChunks*FileSize)
+		for i := range fileData {
+			fileData[i] = byte((i + f) % 256)
+		}
+		chunks := merkle.SplitIntoChunks(fileData, FileSize)
+		tree, err := merkle.GenerateSparseMerkleTree(chunks, MaxTreeDepth, HashChunk, zeroLeaf)
+		if err != nil {
+			return nil, fmt.Errorf("build file %d SMT: %w", f, err)
+		}
+		files = append(files, ArchiveFile{Chunks: chunks, Tree: tree})
+	}
+	return files, nil
+}
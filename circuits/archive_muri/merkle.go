@@ -0,0 +1,30 @@
+package archivemuri
+
+import (
+	"github.com/MuriData/muri-zkproof/circuits/shared"
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/consensys/gnark/frontend"
+)
+
+// computeMerkleRoot hashes leaf up through proofPath and returns the computed
+// root. Direction bits come from the binary decomposition of the leaf index
+// (0 = sibling on right, 1 = sibling on left), so the path is bound to the
+// position being opened. All levels are always hashed — no conditional skip.
+func computeMerkleRoot(api frontend.API, sponge *shared.SpongeHasher, leaf frontend.Variable, proofPath, directions []frontend.Variable) (frontend.Variable, error) {
+	currentHash := leaf
+
+	for i := range proofPath {
+		sibling := proofPath[i]
+		direction := directions[i]
+
+		leftHash := api.Select(direction, sibling, currentHash)
+		rightHash := api.Select(direction, currentHash, sibling)
+		var err error
+		currentHash, err = sponge.Hash(frontend.Variable(crypto.DomainTagNode), leftHash, rightHash)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return currentHash, nil
+}
@@ -0,0 +1,153 @@
+package archivemuri
+
+import (
+	"github.com/MuriData/muri-zkproof/circuits/shared"
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/bits"
+)
+
+// ReplicationCircuit proves that ReplicaCount sealed roots held by the same
+// key are MURI sealings of the same archive under pairwise distinct replica
+// randomness, so that each counts as an independent replica.
+//
+// Replica i is sealed under r_i = DeriveReplicaR(publicKey,
+// archiveOriginalRoot, replicaIndices[i]). The challenged positions and
+// their original elements are shared; each replica traces its own sealing
+// route back to the same original element, exactly as ArchiveMuriCircuit
+// does for a single replica.
+//
+// sealedRoots[ReplicaCount-1] is the replica being added. Like
+// ArchiveMuriCircuit, proof proofIndex challenges the positions selected by
+// randomness = H(DomainTagSealProof, seed, sealedRoots[ReplicaCount-1],
+// proofIndex), and the contract credits the replica once it has verified
+// proofs for every proofIndex in [0, SealProofsPerReplica).
+type ReplicationCircuit struct {
+	// Public inputs (5 + 2*ReplicaCount)
+	PublicKey           frontend.Variable               `gnark:"publicKey,public"`
+	ArchiveOriginalRoot frontend.Variable               `gnark:"archiveOriginalRoot,public"`
+	TotalRealChunks     frontend.Variable               `gnark:"totalRealChunks,public"`
+	Seed                frontend.Variable               `gnark:"seed,public"`
+	ProofIndex          frontend.Variable               `gnark:"proofIndex,public"`
+	SealedRoots         [ReplicaCount]frontend.Variable `gnark:"sealedRoots,public"`
+	ReplicaIndices      [ReplicaCount]frontend.Variable `gnark:"replicaIndices,public"`
+
+	// Private inputs
+	SlotTreeRoot frontend.Variable                       `gnark:"slotTreeRoot"`
+	Quotients    [ChallengeCount]frontend.Variable       `gnark:"quotients"`
+	Positions    [ChallengeCount]frontend.Variable       `gnark:"positions"`
+	Originals    [ChallengeCount]OriginalOpening         `gnark:"originals"`
+	Routes       [ReplicaCount][ChallengeCount]SealRoute `gnark:"routes"`
+}
+
+func (circuit *ReplicationCircuit) Define(api frontend.API) error {
+	sponge, err := shared.NewSpongeHasher(api)
+	if err != nil {
+		return err
+	}
+
+	// ---------------------------------------------------------------
+	// 1. Public input sanity: non-zero key and seed, proofIndex in
+	//    [0, SealProofsPerReplica), totalRealChunks in [1, TotalLeaves].
+	// ---------------------------------------------------------------
+	api.AssertIsEqual(api.IsZero(circuit.PublicKey), 0)
+	api.AssertIsEqual(api.IsZero(circuit.Seed), 0)
+	api.AssertIsLessOrEqual(circuit.ProofIndex, SealProofsPerReplica-1)
+	api.AssertIsEqual(api.IsZero(circuit.TotalRealChunks), 0)
+	api.AssertIsLessOrEqual(circuit.TotalRealChunks, TotalLeaves)
+
+	// Challenge randomness of this proof, bound to the added replica.
+	randomness, err := sponge.Hash(
+		frontend.Variable(crypto.DomainTagSealProof),
+		circuit.Seed, circuit.SealedRoots[ReplicaCount-1], circuit.ProofIndex,
+	)
+	if err != nil {
+		return err
+	}
+
+	// ---------------------------------------------------------------
+	// 2. Archive root: archiveOriginalRoot == H(slotTreeRoot, totalRealChunks).
+	// ---------------------------------------------------------------
+	archiveRoot, err := sponge.Hash(
+		frontend.Variable(crypto.DomainTagArchiveRoot),
+		circuit.SlotTreeRoot, circuit.TotalRealChunks,
+	)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(circuit.ArchiveOriginalRoot, archiveRoot)
+
+	// ---------------------------------------------------------------
+	// 3. Per-replica randomness: index 0 uses the primary
+	//    r = H(publicKey, archiveOriginalRoot), any other index
+	//    r = H(publicKey, archiveOriginalRoot, index).
+	// ---------------------------------------------------------------
+	primaryR, err := sponge.Hash(frontend.Variable(crypto.DomainTagGlobalR), circuit.PublicKey, circuit.ArchiveOriginalRoot)
+	if err != nil {
+		return err
+	}
+
+	var rs [ReplicaCount]frontend.Variable
+	var ctxs [ReplicaCount]*routeContext
+	for i := 0; i < ReplicaCount; i++ {
+		index := circuit.ReplicaIndices[i]
+		api.ToBinary(index, ReplicaIndexBits)
+
+		extraR, err := sponge.Hash(frontend.Variable(crypto.DomainTagReplicaR), circuit.PublicKey, circuit.ArchiveOriginalRoot, index)
+		if err != nil {
+			return err
+		}
+		rs[i] = api.Select(api.IsZero(index), primaryR, extraR)
+
+		ctxs[i], err = newRouteContext(api, sponge, rs[i], circuit.SealedRoots[i], circuit.TotalRealChunks)
+		if err != nil {
+			return err
+		}
+	}
+
+	// ---------------------------------------------------------------
+	// 4. Uniqueness: indices, and hence r values, are pairwise distinct.
+	// ---------------------------------------------------------------
+	for i := 0; i < ReplicaCount; i++ {
+		for j := i + 1; j < ReplicaCount; j++ {
+			api.AssertIsEqual(api.IsZero(api.Sub(circuit.ReplicaIndices[i], circuit.ReplicaIndices[j])), 0)
+			api.AssertIsEqual(api.IsZero(api.Sub(rs[i], rs[j])), 0)
+		}
+	}
+
+	ctx := ctxs[0]
+	for k := 0; k < ChallengeCount; k++ {
+		// -----------------------------------------------------------
+		// 5. Challenge position: low 64 bits of H(randomness, k) mod N.
+		// -----------------------------------------------------------
+		challenge, err := sponge.Hash(frontend.Variable(crypto.DomainTagChallengeIdx), randomness, k)
+		if err != nil {
+			return err
+		}
+		challengeBits := api.ToBinary(challenge, api.Compiler().FieldBitLen())
+		window := bits.FromBinary(api, challengeBits[:PointerWindowBits], bits.WithUnconstrainedInputs())
+		ctx.reduceWindow(window, ctx.numElements, circuit.Quotients[k], circuit.Positions[k])
+		pos := circuit.Positions[k]
+
+		// -----------------------------------------------------------
+		// 6. Original element via slot tree + member file tree.
+		// -----------------------------------------------------------
+		orig, err := circuit.Originals[k].Open(ctx, circuit.SlotTreeRoot, pos)
+		if err != nil {
+			return err
+		}
+
+		// -----------------------------------------------------------
+		// 7. Every replica unseals to the same original element.
+		// -----------------------------------------------------------
+		for i := 0; i < ReplicaCount; i++ {
+			unsealed, err := circuit.Routes[i][k].Unseal(ctxs[i], pos)
+			if err != nil {
+				return err
+			}
+			api.AssertIsEqual(unsealed, orig)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,178 @@
+package archivemuri
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/MuriData/muri-zkproof/pkg/setup"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/frontend"
+)
+
+// ReplicationProofFixture holds all values needed for Solidity tests of the
+// ReplicationCircuit.
+type ReplicationProofFixture struct {
+	SolidityProof       [8]string            `json:"solidity_proof"`
+	PublicKey           string               `json:"public_key"`
+	ArchiveOriginalRoot string               `json:"archive_original_root"`
+	TotalRealChunks     string               `json:"total_real_chunks"`
+	Seed                string               `json:"seed"`
+	ProofIndex          string               `json:"proof_index"`
+	SealedRoots         [ReplicaCount]string `json:"sealed_roots"`
+	ReplicaIndices      [ReplicaCount]string `json:"replica_indices"`
+}
+
+// ExportReplicationProofFixture generates a deterministic ReplicationCircuit
+// proof fixture for Solidity tests. keysDir is the directory containing the
+// "archive_replication" proving and verifying keys.
+func ExportReplicationProofFixture(keysDir string) ([]byte, error) {
+	// 1. Compile the circuit
+	fmt.Println("Compiling circuit...")
+	ccs, err := setup.CompileCircuit(&ReplicationCircuit{})
+	if err != nil {
+		return nil, fmt.Errorf("compile circuit: %w", err)
+	}
+
+	// 2. Load proving and verifying keys
+	fmt.Println("Loading keys...")
+	pk, vk, err := setup.LoadKeys(keysDir, "archive_replication")
+	if err != nil {
+		return nil, fmt.Errorf("load keys: %w", err)
+	}
+
+	// 3. Create a deterministic two-file archive (4 + 2 chunks).
+	files, err := fixtureFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. Deterministic key, seed and proof index, then seal replicas 0
+	// and 1 (replica 1 is the one being added).
+	secretKey := new(big.Int).SetUint64(12345)
+	publicKey := crypto.DerivePublicKey(secretKey)
+	seed := new(big.Int).SetUint64(42)
+	proofIndex := 0
+
+	replicas := make([]*Replica, ReplicaCount)
+	for i := range replicas {
+		replicas[i], err = SealArchiveReplica(publicKey, uint32(i), files)
+		if err != nil {
+			return nil, fmt.Errorf("seal replica %d: %w", i, err)
+		}
+		fmt.Printf("Replica %d sealed root: 0x%x\n", i, replicas[i].SealedTree.Root.Bytes())
+	}
+	fmt.Printf("Archive original root: 0x%x\n", replicas[0].Archive.OriginalRoot.Bytes())
+
+	result, err := PrepareReplicationWitness(replicas, seed, proofIndex)
+	if err != nil {
+		return nil, fmt.Errorf("prepare witness: %w", err)
+	}
+	fmt.Printf("Challenged positions: %v\n", result.Positions)
+
+	// 5. Create witness and generate proof
+	witness, err := frontend.NewWitness(&result.Assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		return nil, fmt.Errorf("create witness: %w", err)
+	}
+
+	publicWitness, err := witness.Public()
+	if err != nil {
+		return nil, fmt.Errorf("extract public witness: %w", err)
+	}
+
+	fmt.Println("Generating proof...")
+	proof, err := groth16.Prove(ccs, pk, witness)
+	if err != nil {
+		return nil, fmt.Errorf("prove: %w", err)
+	}
+
+	// 6. Verify proof in Go
+	err = groth16.Verify(proof, vk, publicWitness)
+	if err != nil {
+		return nil, fmt.Errorf("verify: %w", err)
+	}
+	fmt.Println("Proof verified successfully in Go!")
+
+	// 7. Extract proof points for Solidity
+	bn254Proof := proof.(*groth16bn254.Proof)
+
+	aX := new(big.Int)
+	aY := new(big.Int)
+	bn254Proof.Ar.X.BigInt(aX)
+	bn254Proof.Ar.Y.BigInt(aY)
+
+	bX0 := new(big.Int)
+	bX1 := new(big.Int)
+	bY0 := new(big.Int)
+	bY1 := new(big.Int)
+	bn254Proof.Bs.X.A0.BigInt(bX0)
+	bn254Proof.Bs.X.A1.BigInt(bX1)
+	bn254Proof.Bs.Y.A0.BigInt(bY0)
+	bn254Proof.Bs.Y.A1.BigInt(bY1)
+
+	cX := new(big.Int)
+	cY := new(big.Int)
+	bn254Proof.Krs.X.BigInt(cX)
+	bn254Proof.Krs.Y.BigInt(cY)
+
+	// Solidity format: [A.x, A.y, B.x1, B.x0, B.y1, B.y0, C.x, C.y]
+	solidityProof := [8]*big.Int{aX, aY, bX1, bX0, bY1, bY0, cX, cY}
+
+	fixture := ReplicationProofFixture{
+		PublicKey:           fmt.Sprintf("0x%064x", publicKey),
+		ArchiveOriginalRoot: fmt.Sprintf("0x%064x", replicas[0].Archive.OriginalRootBigInt()),
+		TotalRealChunks:     fmt.Sprintf("%d", replicas[0].Archive.TotalRealChunks),
+		Seed:                fmt.Sprintf("0x%064x", seed),
+		ProofIndex:          fmt.Sprintf("%d", proofIndex),
+	}
+	for i, rp := range replicas {
+		fixture.SealedRoots[i] = fmt.Sprintf("0x%064x", rp.SealedTree.RootBigInt())
+		fixture.ReplicaIndices[i] = fmt.Sprintf("%d", rp.ReplicaIndex)
+	}
+	for i := 0; i < 8; i++ {
+		fixture.SolidityProof[i] = fmt.Sprintf("0x%064x", solidityProof[i])
+	}
+
+	jsonOut, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal fixture: %w", err)
+	}
+
+	// Print diagnostic info
+	fmt.Println("\n=== PROOF FIXTURE (JSON) ===")
+	fmt.Println(string(jsonOut))
+
+	fmt.Println("\n=== SOLIDITY CONSTANTS ===")
+	fmt.Printf("    // Public inputs\n")
+	fmt.Printf("    uint256 constant REPL_PUB_KEY = %s;\n", fixture.PublicKey)
+	fmt.Printf("    uint256 constant REPL_ARCHIVE_ROOT = %s;\n", fixture.ArchiveOriginalRoot)
+	fmt.Printf("    uint32 constant REPL_TOTAL_CHUNKS = %s;\n", fixture.TotalRealChunks)
+	fmt.Printf("    uint256 constant REPL_SEED = %s;\n", fixture.Seed)
+	fmt.Printf("    uint8 constant REPL_PROOF_INDEX = %s;\n", fixture.ProofIndex)
+	for i := 0; i < ReplicaCount; i++ {
+		fmt.Printf("    uint256 constant REPL_SEALED_ROOT_%d = %s;\n", i, fixture.SealedRoots[i])
+		fmt.Printf("    uint32 constant REPL_REPLICA_INDEX_%d = %s;\n", i, fixture.ReplicaIndices[i])
+	}
+	fmt.Println()
+	fmt.Printf("    // Proof (uint256[8])\n")
+	for i := 0; i < 8; i++ {
+		fmt.Printf("    uint256 constant REPL_PROOF_%d = %s;\n", i, fixture.SolidityProof[i])
+	}
+
+	// Public witness info
+	fmt.Println("\n=== PUBLIC WITNESS ORDER ===")
+	fmt.Println("In gnark circuit (= Solidity order): [publicKey, archiveOriginalRoot, totalRealChunks, seed, proofIndex, sealedRoots[0..1], replicaIndices[0..1]]")
+	var pubWitBuf bytes.Buffer
+	_, err = publicWitness.WriteTo(&pubWitBuf)
+	if err != nil {
+		return nil, fmt.Errorf("write public witness: %w", err)
+	}
+	fmt.Printf("Public witness size: %d bytes\n", pubWitBuf.Len())
+
+	return jsonOut, nil
+}
@@ -0,0 +1,123 @@
+package archivemuri_test
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	archivemuri "github.com/MuriData/muri-zkproof/circuits/archive_muri"
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/MuriData/muri-zkproof/pkg/merkle"
+	"github.com/MuriData/muri-zkproof/pkg/setup"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+)
+
+// buildReplicas seals one random archive with the given chunk counts under
+// a single key, once per replica index.
+func buildReplicas(t *testing.T, chunkCounts []int, indices []uint32) []*archivemuri.Replica {
+	t.Helper()
+	zeroLeaf := crypto.ComputeZeroLeafHashFr(archivemuri.ElementSize, archivemuri.ElementsPerChunk)
+
+	var files []archivemuri.ArchiveFile
+	for _, n := range chunkCounts {
+		data := make([]byte, n*archivemuri.FileSize)
+		if _, err := rand.Read(data); err != nil {
+			t.Fatalf("generate random data: %v", err)
+		}
+		chunks := merkle.SplitIntoChunks(data, archivemuri.FileSize)
+		tree, err := merkle.GenerateSparseMerkleTree(chunks, archivemuri.MaxTreeDepth, archivemuri.HashChunk, zeroLeaf)
+		if err != nil {
+			t.Fatalf("build SMT: %v", err)
+		}
+		files = append(files, archivemuri.ArchiveFile{Chunks: chunks, Tree: tree})
+	}
+
+	secretKey, err := crypto.GenerateSecretKey()
+	if err != nil {
+		t.Fatalf("generate secret key: %v", err)
+	}
+	publicKey := crypto.DerivePublicKey(secretKey)
+
+	replicas := make([]*archivemuri.Replica, len(indices))
+	for i, idx := range indices {
+		replicas[i], err = archivemuri.SealArchiveReplica(publicKey, idx, files)
+		if err != nil {
+			t.Fatalf("seal replica %d: %v", idx, err)
+		}
+	}
+	return replicas
+}
+
+// TestReplicationCircuit checks that two distinct replicas of an archive
+// satisfy the circuit and that a duplicated replica does not.
+func TestReplicationCircuit(t *testing.T) {
+	ccs, err := setup.CompileCircuit(&archivemuri.ReplicationCircuit{})
+	if err != nil {
+		t.Fatalf("compile circuit: %v", err)
+	}
+	t.Logf("Constraints: %d", ccs.GetNbConstraints())
+
+	solved := func(assignment *archivemuri.ReplicationCircuit) bool {
+		witness, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+		if err != nil {
+			t.Fatalf("create witness: %v", err)
+		}
+		return ccs.IsSolved(witness) == nil
+	}
+
+	replicas := buildReplicas(t, []int{2, 1}, []uint32{0, 1, 5})
+	if replicas[0].SealedTree.Root == replicas[1].SealedTree.Root {
+		t.Fatal("replicas 0 and 1 have the same sealed root")
+	}
+
+	// Replica 0 is the primary replica sealed by SealArchive.
+	primary, err := archivemuri.SealArchive(replicas[0].PublicKey, replicas[0].Files)
+	if err != nil {
+		t.Fatalf("seal archive: %v", err)
+	}
+	if primary.SealedTree.Root != replicas[0].SealedTree.Root {
+		t.Fatal("replica 0 differs from SealArchive")
+	}
+
+	for _, pair := range [][2]int{{0, 1}, {2, 1}} {
+		result, err := archivemuri.PrepareReplicationWitness(
+			[]*archivemuri.Replica{replicas[pair[0]], replicas[pair[1]]}, big.NewInt(42), 0)
+		if err != nil {
+			t.Fatalf("prepare witness: %v", err)
+		}
+		if !solved(&result.Assignment) {
+			t.Fatalf("replicas %v: circuit not solved", pair)
+		}
+	}
+
+	// The same replica claimed twice must be rejected.
+	if _, err := archivemuri.PrepareReplicationWitness(
+		[]*archivemuri.Replica{replicas[1], replicas[1]}, big.NewInt(42), 0); err == nil {
+		t.Fatal("expected duplicate replica index to be rejected")
+	}
+	result, err := archivemuri.PrepareReplicationWitness(
+		[]*archivemuri.Replica{replicas[0], replicas[1]}, big.NewInt(42), 0)
+	if err != nil {
+		t.Fatalf("prepare witness: %v", err)
+	}
+	duplicate := result.Assignment
+	duplicate.SealedRoots[1] = duplicate.SealedRoots[0]
+	duplicate.ReplicaIndices[1] = duplicate.ReplicaIndices[0]
+	duplicate.Routes[1] = duplicate.Routes[0]
+	if solved(&duplicate) {
+		t.Fatal("expected duplicated replica to be rejected")
+	}
+
+	// ArchiveMuriCircuit only proves the primary replica.
+	if _, err := archivemuri.PrepareWitness(replicas[1], big.NewInt(42), 0); err == nil {
+		t.Fatal("expected PrepareWitness to reject a non-primary replica")
+	}
+
+	// Claiming a sealed root under another replica's index must be rejected.
+	mislabeled := result.Assignment
+	mislabeled.ReplicaIndices[1] = 5
+	if solved(&mislabeled) {
+		t.Fatal("expected mislabeled replica index to be rejected")
+	}
+}
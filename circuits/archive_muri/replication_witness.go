@@ -0,0 +1,72 @@
+package archivemuri
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// ReplicationWitnessResult holds the fully populated ReplicationCircuit
+// assignment and the challenged positions.
+type ReplicationWitnessResult struct {
+	Assignment ReplicationCircuit
+	Positions  [ChallengeCount]int // challenged element positions
+}
+
+// PrepareReplicationWitness assembles proof proofIndex of a
+// ReplicationCircuit witness for ReplicaCount replicas of the same archive
+// held by the same key, under the contract's seed. The replicas must have
+// pairwise distinct replica indices; the last one is the replica being added
+// and selects the challenge randomness (see SealProofRandomness).
+func PrepareReplicationWitness(replicas []*Replica, seed *big.Int, proofIndex int) (*ReplicationWitnessResult, error) {
+	if len(replicas) != ReplicaCount {
+		return nil, fmt.Errorf("expected %d replicas, got %d", ReplicaCount, len(replicas))
+	}
+	if proofIndex < 0 || proofIndex >= SealProofsPerReplica {
+		return nil, fmt.Errorf("proof index %d out of range [0, %d)", proofIndex, SealProofsPerReplica)
+	}
+	first := replicas[0]
+	for i, rp := range replicas {
+		if rp.PublicKey.Cmp(first.PublicKey) != 0 {
+			return nil, fmt.Errorf("replica %d is held by a different public key", i)
+		}
+		if rp.Archive.OriginalRoot != first.Archive.OriginalRoot {
+			return nil, fmt.Errorf("replica %d seals a different archive", i)
+		}
+		for j := 0; j < i; j++ {
+			if replicas[j].ReplicaIndex == rp.ReplicaIndex {
+				return nil, fmt.Errorf("replicas %d and %d share replica index %d", j, i, rp.ReplicaIndex)
+			}
+		}
+	}
+
+	randomness := SealProofRandomness(seed, replicas[ReplicaCount-1].SealedTree.Root, proofIndex)
+
+	var assignment ReplicationCircuit
+	var positions [ChallengeCount]int
+	for i, rp := range replicas {
+		single, err := prepareWitness(rp, randomness)
+		if err != nil {
+			return nil, fmt.Errorf("replica %d: %w", i, err)
+		}
+		if i == 0 {
+			assignment.PublicKey = single.Assignment.PublicKey
+			assignment.ArchiveOriginalRoot = single.Assignment.ArchiveOriginalRoot
+			assignment.TotalRealChunks = single.Assignment.TotalRealChunks
+			assignment.Seed = seed
+			assignment.ProofIndex = proofIndex
+			assignment.SlotTreeRoot = single.Assignment.SlotTreeRoot
+			assignment.Quotients = single.Assignment.Quotients
+			assignment.Positions = single.Assignment.Positions
+			assignment.Originals = single.Assignment.Originals
+			positions = single.Positions
+		}
+		assignment.SealedRoots[i] = single.Assignment.SealedRoot
+		assignment.ReplicaIndices[i] = rp.ReplicaIndex
+		assignment.Routes[i] = single.Assignment.Routes
+	}
+
+	return &ReplicationWitnessResult{
+		Assignment: assignment,
+		Positions:  positions,
+	}, nil
+}
@@ -0,0 +1,274 @@
+package archivemuri
+
+import (
+	"math/big"
+
+	"github.com/MuriData/muri-zkproof/circuits/shared"
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/bits"
+	"github.com/consensys/gnark/std/math/cmp"
+	"github.com/consensys/gnark/std/selector"
+)
+
+// positionBits is enough bits to range-check any element position or
+// modular-reduction remainder (MaxElements < 2^30).
+const positionBits = 30
+
+// offsetBits range-checks an in-chunk element offset (ElementsPerChunk < 2^10).
+const offsetBits = 10
+
+// routeContext carries the values shared by every opening of a route.
+type routeContext struct {
+	api         frontend.API
+	sponge      *shared.SpongeHasher
+	comparator  *cmp.BoundedComparator
+	r           frontend.Variable // per-replica sealing randomness
+	keySeed1    frontend.Variable // H(KeySeed1, r)
+	keySeed2    frontend.Variable // H(KeySeed2, r)
+	sealedRoot  frontend.Variable
+	numElements frontend.Variable // N = totalRealChunks * ElementsPerChunk
+}
+
+// newRouteContext derives the two pass seeds from the replica randomness r
+// and returns the context for routes into the replica committed to by
+// sealedRoot.
+func newRouteContext(api frontend.API, sponge *shared.SpongeHasher, r, sealedRoot, totalRealChunks frontend.Variable) (*routeContext, error) {
+	keySeed1, err := sponge.Hash(frontend.Variable(crypto.DomainTagKeySeed1), r)
+	if err != nil {
+		return nil, err
+	}
+	keySeed2, err := sponge.Hash(frontend.Variable(crypto.DomainTagKeySeed2), r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &routeContext{
+		api:         api,
+		sponge:      sponge,
+		comparator:  cmp.NewBoundedComparator(api, big.NewInt(int64(MaxElements)+1), false),
+		r:           r,
+		keySeed1:    keySeed1,
+		keySeed2:    keySeed2,
+		sealedRoot:  sealedRoot,
+		numElements: api.Mul(totalRealChunks, ElementsPerChunk),
+	}, nil
+}
+
+// reduceWindow asserts window = quotient * modulus + remainder with
+// remainder in [0, modulus) and quotient in [0, 2^PointerWindowBits).
+func (ctx *routeContext) reduceWindow(window, modulus, quotient, remainder frontend.Variable) {
+	api := ctx.api
+	api.ToBinary(quotient, PointerWindowBits)
+	api.ToBinary(remainder, positionBits)
+	ctx.comparator.AssertIsLess(remainder, modulus)
+	api.AssertIsEqual(api.Add(api.Mul(quotient, modulus), remainder), window)
+}
+
+// ElementOpening opens a single field element of a chunk committed in a
+// depth-20 sparse Merkle tree. The chunk is hashed in full (DomainTagReal
+// over its ElementsPerChunk elements) and the element at Offset is selected.
+type ElementOpening struct {
+	Elements   [ElementsPerChunk]frontend.Variable `gnark:"elements"`
+	ChunkIndex frontend.Variable                   `gnark:"chunkIndex"`
+	Offset     frontend.Variable                   `gnark:"offset"`
+	ProofPath  [MaxTreeDepth]frontend.Variable     `gnark:"proofPath"`
+}
+
+// Open verifies that the opened chunk is leaf ChunkIndex of root and that
+// ChunkIndex * ElementsPerChunk + Offset == pos, then returns the element.
+func (o *ElementOpening) Open(ctx *routeContext, root, pos frontend.Variable) (frontend.Variable, error) {
+	api := ctx.api
+
+	api.ToBinary(o.Offset, offsetBits)
+	ctx.comparator.AssertIsLess(o.Offset, ElementsPerChunk)
+	api.AssertIsEqual(api.Add(api.Mul(o.ChunkIndex, ElementsPerChunk), o.Offset), pos)
+
+	leafHash, err := ctx.sponge.Hash(frontend.Variable(crypto.DomainTagReal), o.Elements[:]...)
+	if err != nil {
+		return nil, err
+	}
+	chunkBits := api.ToBinary(o.ChunkIndex, MaxTreeDepth)
+	computed, err := computeMerkleRoot(api, ctx.sponge, leafHash, o.ProofPath[:], chunkBits)
+	if err != nil {
+		return nil, err
+	}
+	api.AssertIsEqual(computed, root)
+
+	return selector.Mux(api, o.Offset, o.Elements[:]...), nil
+}
+
+// OriginalOpening opens an original (unsealed) archive element. The element
+// lives in a member file whose slot leaf (fileRoot, numChunks,
+// cumulativeChunks) is proven against the archive's slot tree root; the
+// element itself is opened against that file's FSP-certified root.
+type OriginalOpening struct {
+	Element        ElementOpening                   `gnark:"element"`
+	FileRoot       frontend.Variable                `gnark:"fileRoot"`
+	FileNumChunks  frontend.Variable                `gnark:"fileNumChunks"`
+	FileFirstChunk frontend.Variable                `gnark:"fileFirstChunk"` // cumulativeChunks
+	SlotIndex      frontend.Variable                `gnark:"slotIndex"`
+	SlotPath       [SlotTreeDepth]frontend.Variable `gnark:"slotPath"`
+}
+
+// Open verifies slot membership and returns the original element at the
+// archive-global position pos.
+func (o *OriginalOpening) Open(ctx *routeContext, slotTreeRoot, pos frontend.Variable) (frontend.Variable, error) {
+	api := ctx.api
+
+	slotLeaf, err := ctx.sponge.Hash(
+		frontend.Variable(crypto.DomainTagSlot),
+		o.FileRoot, o.FileNumChunks, o.FileFirstChunk,
+	)
+	if err != nil {
+		return nil, err
+	}
+	slotBits := api.ToBinary(o.SlotIndex, SlotTreeDepth)
+	computed, err := computeMerkleRoot(api, ctx.sponge, slotLeaf, o.SlotPath[:], slotBits)
+	if err != nil {
+		return nil, err
+	}
+	api.AssertIsEqual(computed, slotTreeRoot)
+
+	// The opened chunk must be a real chunk of this file.
+	ctx.comparator.AssertIsLess(o.Element.ChunkIndex, o.FileNumChunks)
+
+	localPos := api.Sub(pos, api.Mul(o.FileFirstChunk, ElementsPerChunk))
+	return o.Element.Open(ctx, o.FileRoot, localPos)
+}
+
+// PointerReduction is the modular-reduction witness of one back-pointer
+// seed: window_i = Quotients[i] * modulus + Remainders[i].
+type PointerReduction struct {
+	Quotients  [BackPointerCount]frontend.Variable `gnark:"quotients"`
+	Remainders [BackPointerCount]frontend.Variable `gnark:"remainders"`
+}
+
+// reduce derives seed = H(tag, pos, r), slices it into BackPointerCount
+// windows and returns the verified remainders modulo modulus.
+func (p *PointerReduction) reduce(ctx *routeContext, tag int, pos, modulus frontend.Variable) ([BackPointerCount]frontend.Variable, error) {
+	api := ctx.api
+	var remainders [BackPointerCount]frontend.Variable
+
+	seed, err := ctx.sponge.Hash(frontend.Variable(tag), pos, ctx.r)
+	if err != nil {
+		return remainders, err
+	}
+	seedBits := api.ToBinary(seed, api.Compiler().FieldBitLen())
+
+	for i := 0; i < BackPointerCount; i++ {
+		lo := i * PointerWindowBits
+		window := bits.FromBinary(api, seedBits[lo:lo+PointerWindowBits], bits.WithUnconstrainedInputs())
+		ctx.reduceWindow(window, modulus, p.Quotients[i], p.Remainders[i])
+		remainders[i] = p.Remainders[i]
+	}
+	return remainders, nil
+}
+
+// Pass2Route opens a sealed element together with the sealed elements its
+// pass-2 key depends on, recovering enc1[pos] = sealed[pos] - key2[pos].
+type Pass2Route struct {
+	Self      ElementOpening                   `gnark:"self"`
+	Successor ElementOpening                   `gnark:"successor"`
+	Pointers  [BackPointerCount]ElementOpening `gnark:"pointers"`
+	Reduction PointerReduction                 `gnark:"reduction"`
+}
+
+// Enc1 returns the pass-1 encoding at pos. isLast must be 1 exactly when pos
+// is the final element, in which case key2 is the pass-2 seed and the
+// successor/pointer openings are dummies at pos itself.
+func (p *Pass2Route) Enc1(ctx *routeContext, pos, isLast frontend.Variable) (frontend.Variable, error) {
+	api := ctx.api
+
+	self, err := p.Self.Open(ctx, ctx.sealedRoot, pos)
+	if err != nil {
+		return nil, err
+	}
+
+	next := api.Add(pos, 1)
+	successor, err := p.Successor.Open(ctx, ctx.sealedRoot, api.Select(isLast, pos, next))
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := api.Sub(api.Sub(ctx.numElements, 1), pos)
+	modulus := api.Select(isLast, 1, ctx.comparator.Min(remaining, BackPointerWindow))
+	remainders, err := p.Reduction.reduce(ctx, crypto.DomainTagBackPtr2, pos, modulus)
+	if err != nil {
+		return nil, err
+	}
+
+	keyInputs := make([]frontend.Variable, 0, BackPointerCount+2)
+	keyInputs = append(keyInputs, successor)
+	for i := 0; i < BackPointerCount; i++ {
+		ptrPos := api.Select(isLast, pos, api.Add(next, remainders[i]))
+		v, err := p.Pointers[i].Open(ctx, ctx.sealedRoot, ptrPos)
+		if err != nil {
+			return nil, err
+		}
+		keyInputs = append(keyInputs, v)
+	}
+	keyInputs = append(keyInputs, ctx.r)
+
+	key2, err := ctx.sponge.Hash(frontend.Variable(crypto.DomainTagKeyElem2), keyInputs...)
+	if err != nil {
+		return nil, err
+	}
+	key2 = api.Select(isLast, ctx.keySeed2, key2)
+
+	return api.Sub(self, key2), nil
+}
+
+// SealRoute traces the sealing DAG backwards from one challenged element:
+// its own pass-2 route plus a pass-2 route for its predecessor and each
+// pass-1 back-pointer, which together determine key1.
+type SealRoute struct {
+	Head      Pass2Route                       `gnark:"head"`
+	Reduction PointerReduction                 `gnark:"reduction"`
+	Parents   [BackPointerCount + 1]Pass2Route `gnark:"parents"` // predecessor, then pass-1 back-pointers
+}
+
+// Unseal verifies the route for pos and returns the original element
+// orig[pos] = enc1[pos] - key1[pos] that the sealed replica commits to.
+func (s *SealRoute) Unseal(ctx *routeContext, pos frontend.Variable) (frontend.Variable, error) {
+	api := ctx.api
+
+	isFirst := api.IsZero(pos)
+	isLast := api.IsZero(api.Sub(api.Sub(ctx.numElements, 1), pos))
+
+	enc1, err := s.Head.Enc1(ctx, pos, isLast)
+	if err != nil {
+		return nil, err
+	}
+
+	modulus := api.Select(isFirst, 1, ctx.comparator.Min(pos, BackPointerWindow))
+	remainders, err := s.Reduction.reduce(ctx, crypto.DomainTagBackPtr1, pos, modulus)
+	if err != nil {
+		return nil, err
+	}
+
+	prev := api.Sub(pos, 1)
+	var parentPos [BackPointerCount + 1]frontend.Variable
+	parentPos[0] = api.Select(isFirst, 0, prev)
+	for i := 0; i < BackPointerCount; i++ {
+		parentPos[i+1] = api.Select(isFirst, 0, api.Sub(prev, remainders[i]))
+	}
+
+	keyInputs := make([]frontend.Variable, 0, BackPointerCount+2)
+	for i := range s.Parents {
+		v, err := s.Parents[i].Enc1(ctx, parentPos[i], 0)
+		if err != nil {
+			return nil, err
+		}
+		keyInputs = append(keyInputs, v)
+	}
+	keyInputs = append(keyInputs, ctx.r)
+
+	key1, err := ctx.sponge.Hash(frontend.Variable(crypto.DomainTagKeyElem1), keyInputs...)
+	if err != nil {
+		return nil, err
+	}
+	key1 = api.Select(isFirst, ctx.keySeed1, key1)
+
+	return api.Sub(enc1, key1), nil
+}
@@ -0,0 +1,307 @@
+package archivemuri
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/MuriData/muri-zkproof/pkg/archive"
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/MuriData/muri-zkproof/pkg/merkle"
+	"github.com/MuriData/muri-zkproof/pkg/muri"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/frontend"
+)
+
+// ArchiveFile is one member file of an archive: its chunks and the
+// FSP-certified sparse Merkle tree built over them.
+type ArchiveFile struct {
+	Chunks [][]byte
+	Tree   *merkle.SparseMerkleTree
+}
+
+// Replica is an in-memory sealed replica of an archive together with every
+// tree needed to build MURI witnesses.
+type Replica struct {
+	PublicKey    *big.Int
+	ReplicaIndex uint32 // 0 for the primary replica, see crypto.DeriveReplicaR
+	R            fr.Element
+	Files        []ArchiveFile
+	Archive      *archive.Archive // slot tree and archiveOriginalRoot
+	Sealed       []fr.Element     // flattened sealed elements
+	SealedTree   *merkle.SparseMerkleTree
+}
+
+// WitnessResult holds the fully populated circuit assignment and derived
+// values that callers typically need for logging or fixture export.
+type WitnessResult struct {
+	Assignment ArchiveMuriCircuit
+	Randomness *big.Int            // SealProofRandomness of the proof
+	Positions  [ChallengeCount]int // challenged element positions
+}
+
+// SealArchive builds the slot tree for files, derives the replica randomness
+// r = H(publicKey, archiveOriginalRoot) and seals the archive's chunks in
+// memory with muri.Seal, which also builds the sealed replica tree.
+func SealArchive(publicKey *big.Int, files []ArchiveFile) (*Replica, error) {
+	return SealArchiveReplica(publicKey, 0, files)
+}
+
+// SealArchiveReplica is SealArchive for replica replicaIndex of the archive,
+// sealed under r = DeriveReplicaR(publicKey, archiveOriginalRoot, replicaIndex).
+// Replica 0 is identical to SealArchive.
+func SealArchiveReplica(publicKey *big.Int, replicaIndex uint32, files []ArchiveFile) (*Replica, error) {
+	members := make([]archive.File, len(files))
+	for i, f := range files {
+		if f.Tree == nil || f.Tree.NumLeaves == 0 {
+			return nil, fmt.Errorf("file %d has no leaves", i)
+		}
+		if len(f.Chunks) != f.Tree.NumLeaves {
+			return nil, fmt.Errorf("file %d: chunk count %d does not match tree numLeaves %d", i, len(f.Chunks), f.Tree.NumLeaves)
+		}
+		members[i] = archive.File{Root: f.Tree.Root, NumChunks: f.Tree.NumLeaves}
+	}
+	arc, err := archive.Build(members)
+	if err != nil {
+		return nil, err
+	}
+	total := arc.TotalRealChunks
+	if total > TotalLeaves {
+		return nil, fmt.Errorf("archive has %d chunks, exceeds capacity %d", total, TotalLeaves)
+	}
+
+	var r fr.Element
+	r.SetBigInt(crypto.DeriveReplicaR(publicKey, arc.OriginalRootBigInt(), replicaIndex))
+
+	chunks := make([][]byte, 0, total)
+	for _, f := range files {
+		chunks = append(chunks, f.Chunks...)
+	}
+	sealed := muri.NewMemoryStore(total)
+	sealedTree, err := muri.Seal(muri.NewByteElementReader(chunks), sealed, r, MaxTreeDepth)
+	if err != nil {
+		return nil, fmt.Errorf("seal archive: %w", err)
+	}
+
+	return &Replica{
+		PublicKey:    publicKey,
+		ReplicaIndex: replicaIndex,
+		R:            r,
+		Files:        files,
+		Archive:      arc,
+		Sealed:       sealed.Elements(),
+		SealedTree:   sealedTree,
+	}, nil
+}
+
+// PrepareWitness prepares sealing proof proofIndex of replica under the
+// contract's seed: it derives the challenge randomness with
+// SealProofRandomness and assembles every opening of the challenged
+// positions' sealing routes. ArchiveMuriCircuit only accepts the primary
+// replica (index 0); further replicas are proven with
+// PrepareReplicationWitness.
+func PrepareWitness(replica *Replica, seed *big.Int, proofIndex int) (*WitnessResult, error) {
+	if replica.ReplicaIndex != 0 {
+		return nil, fmt.Errorf("replica index %d: ArchiveMuriCircuit only proves the primary replica, use PrepareReplicationWitness", replica.ReplicaIndex)
+	}
+	if proofIndex < 0 || proofIndex >= SealProofsPerReplica {
+		return nil, fmt.Errorf("proof index %d out of range [0, %d)", proofIndex, SealProofsPerReplica)
+	}
+	result, err := prepareWitness(replica, SealProofRandomness(seed, replica.SealedTree.Root, proofIndex))
+	if err != nil {
+		return nil, err
+	}
+	result.Assignment.Seed = seed
+	result.Assignment.ProofIndex = proofIndex
+	return result, nil
+}
+
+// prepareWitness fills every assignment field except Seed and ProofIndex for
+// the positions selected by randomness.
+func prepareWitness(replica *Replica, randomness *big.Int) (*WitnessResult, error) {
+	if replica.Archive.TotalRealChunks == 0 {
+		return nil, fmt.Errorf("replica has no chunks")
+	}
+
+	n := len(replica.Sealed)
+
+	var assignment ArchiveMuriCircuit
+	assignment.PublicKey = replica.PublicKey
+	assignment.ArchiveOriginalRoot = replica.Archive.OriginalRoot
+	assignment.SealedRoot = replica.SealedTree.Root
+	assignment.TotalRealChunks = replica.Archive.TotalRealChunks
+	assignment.SlotTreeRoot = replica.Archive.SlotTree.Root
+
+	var positions [ChallengeCount]int
+	for k := 0; k < ChallengeCount; k++ {
+		quotient, pos := challengePosition(randomness, k, n)
+		positions[k] = pos
+		assignment.Quotients[k] = quotient
+		assignment.Positions[k] = pos
+		original, err := replica.originalOpening(pos)
+		if err != nil {
+			return nil, err
+		}
+		assignment.Originals[k] = original
+		assignment.Routes[k] = replica.sealRoute(pos)
+	}
+
+	return &WitnessResult{
+		Assignment: assignment,
+		Randomness: randomness,
+		Positions:  positions,
+	}, nil
+}
+
+// SealProofRandomness returns the challenge randomness of sealing proof i,
+// for i in [0, SealProofsPerReplica), of the replica committed to by
+// sealedRoot. It mirrors the derivation in ArchiveMuriCircuit, so with seed
+// drawn after the sealed root is registered (e.g. from a later block hash)
+// the prover can neither choose nor grind the challenged positions. A
+// ReplicationCircuit proof uses sealedRoots[ReplicaCount-1], the root of the
+// replica being added.
+func SealProofRandomness(seed *big.Int, sealedRoot fr.Element, i int) *big.Int {
+	root := new(big.Int)
+	sealedRoot.BigInt(root)
+	return crypto.DeriveSealProofRandomness(seed, root, i)
+}
+
+// PrepareSealProofWitnesses prepares the SealProofsPerReplica witnesses the
+// contract requires to accept replica's sealed root under seed.
+func PrepareSealProofWitnesses(replica *Replica, seed *big.Int) ([]*WitnessResult, error) {
+	results := make([]*WitnessResult, SealProofsPerReplica)
+	for i := range results {
+		result, err := PrepareWitness(replica, seed, i)
+		if err != nil {
+			return nil, fmt.Errorf("sealing proof %d: %w", i, err)
+		}
+		results[i] = result
+	}
+	return results, nil
+}
+
+// challengePosition reduces the low 64 bits of H(randomness, k) modulo the
+// element count n, returning (quotient, position).
+func challengePosition(randomness *big.Int, k, n int) (uint64, int) {
+	var h fr.Element
+	h.SetBigInt(crypto.DeriveChallengeIdx(randomness, big.NewInt(int64(k))))
+	window := muri.SeedWindows(h)[0]
+	return window / uint64(n), int(window % uint64(n))
+}
+
+// sealedOpening opens the sealed element at pos against the replica tree.
+func (rp *Replica) sealedOpening(pos int) ElementOpening {
+	chunk := pos / ElementsPerChunk
+	return newElementOpening(
+		rp.Sealed[chunk*ElementsPerChunk:(chunk+1)*ElementsPerChunk],
+		rp.SealedTree, chunk, pos%ElementsPerChunk,
+	)
+}
+
+// originalOpening opens the original element at archive position pos
+// through its member file and slot.
+func (rp *Replica) originalOpening(pos int) (OriginalOpening, error) {
+	chunk := pos / ElementsPerChunk
+	slot, err := rp.Archive.SlotOf(chunk)
+	if err != nil {
+		return OriginalOpening{}, err
+	}
+	proof, err := rp.Archive.SlotProof(slot)
+	if err != nil {
+		return OriginalOpening{}, err
+	}
+	file := rp.Files[slot]
+	localChunk := chunk - proof.FirstChunk
+
+	var slotPath [SlotTreeDepth]frontend.Variable
+	for i := 0; i < SlotTreeDepth; i++ {
+		slotPath[i] = proof.Siblings[i]
+	}
+
+	return OriginalOpening{
+		Element: newElementOpening(
+			crypto.ChunkToElements(file.Chunks[localChunk], ElementSize, ElementsPerChunk),
+			file.Tree, localChunk, pos%ElementsPerChunk,
+		),
+		FileRoot:       proof.File.Root,
+		FileNumChunks:  proof.File.NumChunks,
+		FileFirstChunk: proof.FirstChunk,
+		SlotIndex:      slot,
+		SlotPath:       slotPath,
+	}, nil
+}
+
+// pass2Route assembles the pass-2 route for pos.
+func (rp *Replica) pass2Route(pos int) Pass2Route {
+	n := len(rp.Sealed)
+	ptrs, red := muri.Pass2Pointers(pos, n, rp.R)
+
+	successor := pos
+	if pos < n-1 {
+		successor = pos + 1
+	}
+
+	route := Pass2Route{
+		Self:      rp.sealedOpening(pos),
+		Successor: rp.sealedOpening(successor),
+		Reduction: reductionWitness(red),
+	}
+	for i, p := range ptrs {
+		route.Pointers[i] = rp.sealedOpening(p)
+	}
+	return route
+}
+
+// sealRoute assembles the full DAG route for the challenged position pos.
+func (rp *Replica) sealRoute(pos int) SealRoute {
+	ptrs, red := muri.Pass1Pointers(pos, rp.R)
+
+	route := SealRoute{
+		Head:      rp.pass2Route(pos),
+		Reduction: reductionWitness(red),
+	}
+	parents := [BackPointerCount + 1]int{}
+	if pos > 0 {
+		parents[0] = pos - 1
+		for i, p := range ptrs {
+			parents[i+1] = p
+		}
+	}
+	for i, p := range parents {
+		route.Parents[i] = rp.pass2Route(p)
+	}
+	return route
+}
+
+// reductionWitness converts a native pointer reduction into its circuit
+// assignment.
+func reductionWitness(red muri.PointerReduction) PointerReduction {
+	var w PointerReduction
+	for i := 0; i < BackPointerCount; i++ {
+		w.Quotients[i] = red.Quotients[i]
+		w.Remainders[i] = red.Remainders[i]
+	}
+	return w
+}
+
+// newElementOpening builds the opening of element offset within chunk of
+// tree, whose elements are elems.
+func newElementOpening(elems []fr.Element, tree *merkle.SparseMerkleTree, chunk, offset int) ElementOpening {
+	var opening ElementOpening
+	for i := 0; i < ElementsPerChunk; i++ {
+		opening.Elements[i] = elems[i]
+	}
+	opening.ChunkIndex = chunk
+	opening.Offset = offset
+
+	siblings, _ := tree.GetProof(chunk)
+	for i := 0; i < MaxTreeDepth; i++ {
+		opening.ProofPath[i] = siblings[i]
+	}
+	return opening
+}
+
+// HashChunk hashes a single original chunk using Poseidon2 with domain tag
+// = 1 (real leaf). This is the leaf hash function of member file trees.
+func HashChunk(chunk []byte) fr.Element {
+	return crypto.HashLeafFr(crypto.DomainTagReal, chunk, ElementSize, ElementsPerChunk)
+}
@@ -0,0 +1,230 @@
+package archivepoi_test
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	archivemuri "github.com/MuriData/muri-zkproof/circuits/archive_muri"
+	archivepoi "github.com/MuriData/muri-zkproof/circuits/archive_poi"
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/MuriData/muri-zkproof/pkg/merkle"
+	"github.com/MuriData/muri-zkproof/pkg/setup"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+)
+
+// buildReplica is a test helper that generates random member files with the
+// given chunk counts and seals them into an archive replica owned by
+// secretKey.
+func buildReplica(t *testing.T, secretKey *big.Int, chunkCounts []int) *archivemuri.Replica {
+	t.Helper()
+	zeroLeaf := crypto.ComputeZeroLeafHashFr(archivepoi.ElementSize, archivepoi.ElementsPerChunk)
+
+	var files []archivemuri.ArchiveFile
+	for _, n := range chunkCounts {
+		data := make([]byte, n*archivepoi.FileSize)
+		if _, err := rand.Read(data); err != nil {
+			t.Fatalf("generate random data: %v", err)
+		}
+		chunks := merkle.SplitIntoChunks(data, archivepoi.FileSize)
+		tree, err := merkle.GenerateSparseMerkleTree(chunks, archivepoi.MaxTreeDepth, archivemuri.HashChunk, zeroLeaf)
+		if err != nil {
+			t.Fatalf("build SMT: %v", err)
+		}
+		files = append(files, archivemuri.ArchiveFile{Chunks: chunks, Tree: tree})
+	}
+
+	replica, err := archivemuri.SealArchive(crypto.DerivePublicKey(secretKey), files)
+	if err != nil {
+		t.Fatalf("seal archive: %v", err)
+	}
+	return replica
+}
+
+// TestArchivePoICircuitEndToEnd compiles the circuit, performs a dev setup,
+// seals a random three-file archive, prepares a witness, generates a proof,
+// and verifies it.
+func TestArchivePoICircuitEndToEnd(t *testing.T) {
+	// 1. Compile
+	ccs, err := setup.CompileCircuit(&archivepoi.ArchivePoICircuit{})
+	if err != nil {
+		t.Fatalf("compile circuit: %v", err)
+	}
+	t.Logf("Constraints: %d", ccs.GetNbConstraints())
+
+	// 2. Dev setup (single-party, not for production)
+	pk, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		t.Fatalf("groth16 setup: %v", err)
+	}
+
+	// 3. Seal a random archive (3 + 1 + 4 chunks)
+	secretKey, err := crypto.GenerateSecretKey()
+	if err != nil {
+		t.Fatalf("generate secret key: %v", err)
+	}
+	replica := buildReplica(t, secretKey, []int{3, 1, 4})
+
+	// 4. Prepare witness
+	randomness, err := rand.Int(rand.Reader, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatalf("generate randomness: %v", err)
+	}
+	result, err := archivepoi.PrepareWitness(secretKey, randomness, replica)
+	if err != nil {
+		t.Fatalf("prepare witness: %v", err)
+	}
+	t.Logf("Selected chunk indices: %v (slots %v)", result.ChunkIndices, result.SlotIndices)
+
+	// 5. Prove and verify
+	witness, err := frontend.NewWitness(&result.Assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatalf("create witness: %v", err)
+	}
+	publicWitness, err := witness.Public()
+	if err != nil {
+		t.Fatalf("extract public witness: %v", err)
+	}
+	proof, err := groth16.Prove(ccs, pk, witness)
+	if err != nil {
+		t.Fatalf("prove: %v", err)
+	}
+	if err := groth16.Verify(proof, vk, publicWitness); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	t.Log("ZK proof verified successfully!")
+}
+
+// TestArchivePoIRejectsInvalidWitness checks that the circuit rejects a wrong
+// slot for an opened chunk, a tampered sealed element and a mismatched
+// archive root.
+func TestArchivePoIRejectsInvalidWitness(t *testing.T) {
+	ccs, err := setup.CompileCircuit(&archivepoi.ArchivePoICircuit{})
+	if err != nil {
+		t.Fatalf("compile circuit: %v", err)
+	}
+
+	secretKey, err := crypto.GenerateSecretKey()
+	if err != nil {
+		t.Fatalf("generate secret key: %v", err)
+	}
+	replica := buildReplica(t, secretKey, []int{2, 2})
+	randomness := big.NewInt(42)
+
+	prepare := func(t *testing.T) *archivepoi.WitnessResult {
+		t.Helper()
+		result, err := archivepoi.PrepareWitness(secretKey, randomness, replica)
+		if err != nil {
+			t.Fatalf("prepare witness: %v", err)
+		}
+		return result
+	}
+	isSolved := func(t *testing.T, assignment *archivepoi.ArchivePoICircuit) error {
+		t.Helper()
+		witness, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+		if err != nil {
+			t.Fatalf("create witness: %v", err)
+		}
+		return ccs.IsSolved(witness)
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		result := prepare(t)
+		if err := isSolved(t, &result.Assignment); err != nil {
+			t.Fatalf("expected valid witness to solve: %v", err)
+		}
+	})
+
+	t.Run("wrong_slot", func(t *testing.T) {
+		result := prepare(t)
+		// Substitute the other member file's (valid) slot opening.
+		other := 1 - result.SlotIndices[0]
+		for k := range result.SlotIndices {
+			if result.SlotIndices[k] == other {
+				result.Assignment.Slots[0] = result.Assignment.Slots[k]
+				if err := isSolved(t, &result.Assignment); err == nil {
+					t.Fatal("expected circuit to reject chunk outside its slot range")
+				}
+				return
+			}
+		}
+		t.Skip("all openings fell in the same slot")
+	})
+
+	t.Run("tampered_element", func(t *testing.T) {
+		result := prepare(t)
+		result.Assignment.Elements[0][0] = 12345
+		if err := isSolved(t, &result.Assignment); err == nil {
+			t.Fatal("expected circuit to reject tampered sealed element")
+		}
+	})
+
+	t.Run("wrong_archive_root", func(t *testing.T) {
+		result := prepare(t)
+		result.Assignment.ArchiveOriginalRoot = 1
+		if err := isSolved(t, &result.Assignment); err == nil {
+			t.Fatal("expected circuit to reject mismatched archive root")
+		}
+	})
+}
+
+// TestArchivePoIExportFixture generates a deterministic fixture and verifies
+// that it round-trips through JSON.
+func TestArchivePoIExportFixture(t *testing.T) {
+	// 1. Compile and dev setup
+	ccs, err := setup.CompileCircuit(&archivepoi.ArchivePoICircuit{})
+	if err != nil {
+		t.Fatalf("compile circuit: %v", err)
+	}
+	pk, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		t.Fatalf("groth16 setup: %v", err)
+	}
+
+	// 2. Write keys to temp directory
+	tmpDir := t.TempDir()
+	if err := setup.ExportKeys(pk, vk, tmpDir, "archive_poi"); err != nil {
+		t.Fatalf("export keys: %v", err)
+	}
+
+	// 3. Generate fixture
+	jsonOut, err := archivepoi.ExportProofFixture(tmpDir)
+	if err != nil {
+		t.Fatalf("export proof fixture: %v", err)
+	}
+
+	// 4. Verify JSON round-trips
+	var fixture archivepoi.ProofFixture
+	if err := json.Unmarshal(jsonOut, &fixture); err != nil {
+		t.Fatalf("unmarshal fixture: %v", err)
+	}
+
+	if fixture.Commitment == "" {
+		t.Fatal("fixture commitment is empty")
+	}
+	if fixture.SealedRoot == "" {
+		t.Fatal("fixture sealed root is empty")
+	}
+	if fixture.ArchiveOriginalRoot == "" {
+		t.Fatal("fixture archive original root is empty")
+	}
+	for i, p := range fixture.SolidityProof {
+		if p == "" {
+			t.Fatalf("fixture solidity proof[%d] is empty", i)
+		}
+	}
+
+	jsonRoundTrip, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		t.Fatalf("re-marshal fixture: %v", err)
+	}
+	if string(jsonRoundTrip) != string(jsonOut) {
+		t.Fatal("fixture JSON round-trip mismatch")
+	}
+
+	fmt.Println("Fixture round-trip OK")
+}
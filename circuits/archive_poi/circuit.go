@@ -0,0 +1,184 @@
+package archivepoi
+
+import (
+	"math/big"
+
+	"github.com/MuriData/muri-zkproof/circuits/shared"
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/bits"
+	"github.com/consensys/gnark/std/math/cmp"
+)
+
+// SlotOpening proves that a sealed chunk index falls inside one member file
+// of the archive: the slot leaf (fileRoot, numChunks, cumulativeChunks) is a
+// member of the slot tree and cumulativeChunks <= chunk < cumulativeChunks +
+// numChunks.
+type SlotOpening struct {
+	FileRoot       frontend.Variable                `gnark:"fileRoot"`
+	FileNumChunks  frontend.Variable                `gnark:"fileNumChunks"`
+	FileFirstChunk frontend.Variable                `gnark:"fileFirstChunk"` // cumulativeChunks
+	SlotIndex      frontend.Variable                `gnark:"slotIndex"`
+	SlotPath       [SlotTreeDepth]frontend.Variable `gnark:"slotPath"`
+}
+
+// ArchivePoICircuit proves ongoing storage of an Archive MURI replica. It
+// mirrors PoICircuit, but openings are sealed leaves (ElementsPerChunk full
+// field elements) of the replica tree SealedRoot, and every opened chunk is
+// linked back to its member file through the slot tree committed to by
+// ArchiveOriginalRoot.
+//
+// The pairing of SealedRoot with (PublicKey, ArchiveOriginalRoot) is
+// established once by the Archive MURI proof and enforced on-chain.
+type ArchivePoICircuit struct {
+	// Public inputs (6): commitment, randomness, publicKey, sealedRoot,
+	// archiveOriginalRoot, totalRealChunks
+	Commitment          frontend.Variable `gnark:"commitment,public"`
+	Randomness          frontend.Variable `gnark:"randomness,public"`
+	PublicKey           frontend.Variable `gnark:"publicKey,public"`
+	SealedRoot          frontend.Variable `gnark:"sealedRoot,public"`
+	ArchiveOriginalRoot frontend.Variable `gnark:"archiveOriginalRoot,public"`
+	TotalRealChunks     frontend.Variable `gnark:"totalRealChunks,public"`
+
+	// Private inputs
+	SecretKey    frontend.Variable                                  `gnark:"secretKey"`
+	SlotTreeRoot frontend.Variable                                  `gnark:"slotTreeRoot"`
+	Elements     [OpeningsCount][ElementsPerChunk]frontend.Variable `gnark:"elements"`
+	ProofPaths   [OpeningsCount][MaxTreeDepth]frontend.Variable     `gnark:"proofPaths"`
+	Quotients    [OpeningsCount]frontend.Variable                   `gnark:"quotients"`
+	LeafIndices  [OpeningsCount]frontend.Variable                   `gnark:"leafIndices"`
+	Slots        [OpeningsCount]SlotOpening                         `gnark:"slots"`
+}
+
+func (circuit *ArchivePoICircuit) Define(api frontend.API) error {
+	sponge, err := shared.NewSpongeHasher(api)
+	if err != nil {
+		return err
+	}
+
+	// ---------------------------------------------------------------
+	// 1. Key ownership: publicKey == H(secretKey), both non-zero.
+	// ---------------------------------------------------------------
+	api.AssertIsEqual(api.IsZero(circuit.SecretKey), 0)
+	api.AssertIsEqual(api.IsZero(circuit.PublicKey), 0)
+
+	derivedPubKey, err := sponge.Hash(frontend.Variable(crypto.DomainTagPubKey), circuit.SecretKey)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(circuit.PublicKey, derivedPubKey)
+
+	// ---------------------------------------------------------------
+	// 2. Public input sanity: non-zero randomness,
+	//    totalRealChunks in [1, TotalLeaves].
+	// ---------------------------------------------------------------
+	api.AssertIsEqual(api.IsZero(circuit.Randomness), 0)
+	api.AssertIsEqual(api.IsZero(circuit.TotalRealChunks), 0)
+	api.AssertIsLessOrEqual(circuit.TotalRealChunks, TotalLeaves)
+
+	// ---------------------------------------------------------------
+	// 3. Archive root: archiveOriginalRoot == H(slotTreeRoot, totalRealChunks).
+	// ---------------------------------------------------------------
+	archiveRoot, err := sponge.Hash(
+		frontend.Variable(crypto.DomainTagArchiveRoot),
+		circuit.SlotTreeRoot, circuit.TotalRealChunks,
+	)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(circuit.ArchiveOriginalRoot, archiveRoot)
+
+	// ---------------------------------------------------------------
+	// 4. Bounded comparator for chunk-index range checks.
+	// ---------------------------------------------------------------
+	// Every compared operand is range-checked to MaxTreeDepth+1 bits, so
+	// max |a - b| is 2*TotalLeaves.
+	comparator := cmp.NewBoundedComparator(api, big.NewInt(2*int64(TotalLeaves)+1), false)
+
+	// ---------------------------------------------------------------
+	// 5. Per-opening: challenge index, sealed leaf, slot membership.
+	// ---------------------------------------------------------------
+	var leafHashes [OpeningsCount]frontend.Variable
+
+	for k := 0; k < OpeningsCount; k++ {
+		// 5a. Raw index: low ChallengeWindowBits of H(randomness, k).
+		challenge, err := sponge.Hash(frontend.Variable(crypto.DomainTagChallengeIdx), circuit.Randomness, k)
+		if err != nil {
+			return err
+		}
+		challengeBits := api.ToBinary(challenge, api.Compiler().FieldBitLen())
+		rawIndex := bits.FromBinary(api, challengeBits[:ChallengeWindowBits], bits.WithUnconstrainedInputs())
+
+		// 5b. Modular reduction: quotient * totalRealChunks + leafIndex == rawIndex.
+		// Range check: quotient fits in ChallengeWindowBits, leafIndex < totalRealChunks.
+		api.ToBinary(circuit.Quotients[k], ChallengeWindowBits)
+		product := api.Mul(circuit.Quotients[k], circuit.TotalRealChunks)
+		api.AssertIsEqual(api.Add(product, circuit.LeafIndices[k]), rawIndex)
+		leafBits := api.ToBinary(circuit.LeafIndices[k], MaxTreeDepth)
+		comparator.AssertIsLess(circuit.LeafIndices[k], circuit.TotalRealChunks)
+
+		// 5c. Sealed leaf hash: sponge(DomainTagReal, elements[k][0..528]).
+		leafHash, err := sponge.Hash(frontend.Variable(crypto.DomainTagReal), circuit.Elements[k][:]...)
+		if err != nil {
+			return err
+		}
+		leafHashes[k] = leafHash
+
+		// 5d. Sealed Merkle proof with directions from LeafIndex bits.
+		sealedRoot, err := computeMerkleRoot(api, sponge, leafHash, circuit.ProofPaths[k][:], leafBits)
+		if err != nil {
+			return err
+		}
+		api.AssertIsEqual(sealedRoot, circuit.SealedRoot)
+
+		// 5e. Slot membership of the opened chunk.
+		slot := &circuit.Slots[k]
+		slotLeaf, err := sponge.Hash(
+			frontend.Variable(crypto.DomainTagSlot),
+			slot.FileRoot, slot.FileNumChunks, slot.FileFirstChunk,
+		)
+		if err != nil {
+			return err
+		}
+		slotBits := api.ToBinary(slot.SlotIndex, SlotTreeDepth)
+		slotRoot, err := computeMerkleRoot(api, sponge, slotLeaf, slot.SlotPath[:], slotBits)
+		if err != nil {
+			return err
+		}
+		api.AssertIsEqual(slotRoot, circuit.SlotTreeRoot)
+
+		// 5f. Range check: fileFirstChunk <= leafIndex < fileFirstChunk + fileNumChunks.
+		api.ToBinary(slot.FileFirstChunk, MaxTreeDepth+1)
+		api.ToBinary(slot.FileNumChunks, MaxTreeDepth+1)
+		comparator.AssertIsLessEq(slot.FileFirstChunk, circuit.LeafIndices[k])
+		comparator.AssertIsLess(circuit.LeafIndices[k], api.Add(slot.FileFirstChunk, slot.FileNumChunks))
+	}
+
+	// ---------------------------------------------------------------
+	// 6. Aggregate message: aggMsg = H(leafHash[0], ..., leafHash[7], randomness).
+	// ---------------------------------------------------------------
+	aggInputs := make([]frontend.Variable, OpeningsCount+1)
+	for k := 0; k < OpeningsCount; k++ {
+		aggInputs[k] = leafHashes[k]
+	}
+	aggInputs[OpeningsCount] = circuit.Randomness
+	aggMsg, err := sponge.Hash(frontend.Variable(crypto.DomainTagAggMsg), aggInputs...)
+	if err != nil {
+		return err
+	}
+
+	// ---------------------------------------------------------------
+	// 7. VRF commitment: commitment = H(secretKey, aggMsg, randomness, publicKey).
+	// ---------------------------------------------------------------
+	derivedCommitment, err := sponge.Hash(
+		frontend.Variable(crypto.DomainTagCommitment),
+		circuit.SecretKey, aggMsg, circuit.Randomness, circuit.PublicKey,
+	)
+	if err != nil {
+		return err
+	}
+
+	api.AssertIsEqual(circuit.Commitment, derivedCommitment)
+
+	return nil
+}
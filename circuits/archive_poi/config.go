@@ -0,0 +1,17 @@
+package archivepoi
+
+import "github.com/MuriData/muri-zkproof/pkg/archive"
+
+const (
+	FileSize         = 16 * 1024                                       // 16 KB chunk size (must match Archive MURI)
+	ElementSize      = 31                                              // bytes per field element (must match Archive MURI)
+	ElementsPerChunk = int((FileSize + ElementSize - 1) / ElementSize) // 529 — sealed field elements per chunk
+
+	MaxTreeDepth = 20
+	TotalLeaves  = 1 << MaxTreeDepth // max real chunks per archive replica
+
+	SlotTreeDepth = archive.SlotTreeDepth
+
+	OpeningsCount       = 8  // number of parallel sealed-leaf openings per proof
+	ChallengeWindowBits = 64 // low bits of H(randomness, k) reduced mod totalRealChunks
+)
@@ -0,0 +1,181 @@
+package archivepoi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	archivemuri "github.com/MuriData/muri-zkproof/circuits/archive_muri"
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/MuriData/muri-zkproof/pkg/merkle"
+	"github.com/MuriData/muri-zkproof/pkg/setup"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/frontend"
+)
+
+// ProofFixture holds all values needed for Solidity tests.
+type ProofFixture struct {
+	SolidityProof       [8]string `json:"solidity_proof"`
+	Commitment          string    `json:"commitment"`
+	Randomness          string    `json:"randomness"`
+	PublicKey           string    `json:"public_key"`
+	SealedRoot          string    `json:"sealed_root"`
+	ArchiveOriginalRoot string    `json:"archive_original_root"`
+	TotalRealChunks     string    `json:"total_real_chunks"`
+}
+
+// ExportProofFixture generates a deterministic proof fixture for Solidity tests.
+// keysDir is the directory containing the proving and verifying keys.
+func ExportProofFixture(keysDir string) ([]byte, error) {
+	// 1. Compile the circuit
+	fmt.Println("Compiling circuit...")
+	ccs, err := setup.CompileCircuit(&ArchivePoICircuit{})
+	if err != nil {
+		return nil, fmt.Errorf("compile circuit: %w", err)
+	}
+
+	// 2. Load proving and verifying keys
+	fmt.Println("Loading keys...")
+	pk, vk, err := setup.LoadKeys(keysDir, "archive_poi")
+	if err != nil {
+		return nil, fmt.Errorf("load keys: %w", err)
+	}
+
+	// 3. Create a deterministic two-file archive (4 + 2 chunks), matching
+	//    the Archive MURI fixture.
+	zeroLeaf := crypto.ComputeZeroLeafHashFr(ElementSize, ElementsPerChunk)
+	var files []archivemuri.ArchiveFile
+	for f, numChunks := range []int{4, 2} {
+		fileData := make([]byte, numChunks*FileSize)
+		for i := range fileData {
+			fileData[i] = byte((i + f) % 256)
+		}
+		chunks := merkle.SplitIntoChunks(fileData, FileSize)
+		tree, err := merkle.GenerateSparseMerkleTree(chunks, MaxTreeDepth, archivemuri.HashChunk, zeroLeaf)
+		if err != nil {
+			return nil, fmt.Errorf("build file %d SMT: %w", f, err)
+		}
+		files = append(files, archivemuri.ArchiveFile{Chunks: chunks, Tree: tree})
+	}
+
+	// 4. Deterministic key and randomness, then seal the archive.
+	secretKey := new(big.Int).SetUint64(12345)
+	publicKey := crypto.DerivePublicKey(secretKey)
+	randomness := new(big.Int).SetUint64(42)
+
+	replica, err := archivemuri.SealArchive(publicKey, files)
+	if err != nil {
+		return nil, fmt.Errorf("seal archive: %w", err)
+	}
+	fmt.Printf("Archive original root: 0x%x\n", replica.Archive.OriginalRoot.Bytes())
+	fmt.Printf("Sealed root: 0x%x\n", replica.SealedTree.Root.Bytes())
+	fmt.Printf("Total real chunks: %d\n", replica.Archive.TotalRealChunks)
+
+	result, err := PrepareWitness(secretKey, randomness, replica)
+	if err != nil {
+		return nil, fmt.Errorf("prepare witness: %w", err)
+	}
+	fmt.Printf("Selected chunk indices: %v\n", result.ChunkIndices)
+	fmt.Printf("Slot indices: %v\n", result.SlotIndices)
+	fmt.Printf("Public key (H(sk)): 0x%064x\n", result.PublicKey)
+	fmt.Printf("Commitment: 0x%064x\n", result.Commitment)
+
+	// 5. Create witness and generate proof
+	witness, err := frontend.NewWitness(&result.Assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		return nil, fmt.Errorf("create witness: %w", err)
+	}
+
+	publicWitness, err := witness.Public()
+	if err != nil {
+		return nil, fmt.Errorf("extract public witness: %w", err)
+	}
+
+	fmt.Println("Generating proof...")
+	proof, err := groth16.Prove(ccs, pk, witness)
+	if err != nil {
+		return nil, fmt.Errorf("prove: %w", err)
+	}
+
+	// 6. Verify proof in Go
+	err = groth16.Verify(proof, vk, publicWitness)
+	if err != nil {
+		return nil, fmt.Errorf("verify: %w", err)
+	}
+	fmt.Println("Proof verified successfully in Go!")
+
+	// 7. Extract proof points for Solidity
+	bn254Proof := proof.(*groth16bn254.Proof)
+
+	aX := new(big.Int)
+	aY := new(big.Int)
+	bn254Proof.Ar.X.BigInt(aX)
+	bn254Proof.Ar.Y.BigInt(aY)
+
+	bX0 := new(big.Int)
+	bX1 := new(big.Int)
+	bY0 := new(big.Int)
+	bY1 := new(big.Int)
+	bn254Proof.Bs.X.A0.BigInt(bX0)
+	bn254Proof.Bs.X.A1.BigInt(bX1)
+	bn254Proof.Bs.Y.A0.BigInt(bY0)
+	bn254Proof.Bs.Y.A1.BigInt(bY1)
+
+	cX := new(big.Int)
+	cY := new(big.Int)
+	bn254Proof.Krs.X.BigInt(cX)
+	bn254Proof.Krs.Y.BigInt(cY)
+
+	// Solidity format: [A.x, A.y, B.x1, B.x0, B.y1, B.y0, C.x, C.y]
+	solidityProof := [8]*big.Int{aX, aY, bX1, bX0, bY1, bY0, cX, cY}
+
+	fixture := ProofFixture{
+		Commitment:          fmt.Sprintf("0x%064x", result.Commitment),
+		Randomness:          fmt.Sprintf("0x%064x", randomness),
+		PublicKey:           fmt.Sprintf("0x%064x", result.PublicKey),
+		SealedRoot:          fmt.Sprintf("0x%064x", replica.SealedTree.RootBigInt()),
+		ArchiveOriginalRoot: fmt.Sprintf("0x%064x", replica.Archive.OriginalRootBigInt()),
+		TotalRealChunks:     fmt.Sprintf("%d", result.TotalRealChunks),
+	}
+	for i := 0; i < 8; i++ {
+		fixture.SolidityProof[i] = fmt.Sprintf("0x%064x", solidityProof[i])
+	}
+
+	jsonOut, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal fixture: %w", err)
+	}
+
+	// Print diagnostic info
+	fmt.Println("\n=== PROOF FIXTURE (JSON) ===")
+	fmt.Println(string(jsonOut))
+
+	fmt.Println("\n=== SOLIDITY CONSTANTS ===")
+	fmt.Printf("    // Public inputs\n")
+	fmt.Printf("    bytes32 constant ARCHIVE_POI_COMMITMENT = bytes32(%s);\n", fixture.Commitment)
+	fmt.Printf("    uint256 constant ARCHIVE_POI_RANDOMNESS = %s;\n", fixture.Randomness)
+	fmt.Printf("    uint256 constant ARCHIVE_POI_PUB_KEY = %s;\n", fixture.PublicKey)
+	fmt.Printf("    uint256 constant ARCHIVE_POI_SEALED_ROOT = %s;\n", fixture.SealedRoot)
+	fmt.Printf("    uint256 constant ARCHIVE_POI_ARCHIVE_ROOT = %s;\n", fixture.ArchiveOriginalRoot)
+	fmt.Printf("    uint32 constant ARCHIVE_POI_TOTAL_CHUNKS = %s;\n", fixture.TotalRealChunks)
+	fmt.Println()
+	fmt.Printf("    // Proof (uint256[8])\n")
+	for i := 0; i < 8; i++ {
+		fmt.Printf("    uint256 constant ARCHIVE_POI_PROOF_%d = %s;\n", i, fixture.SolidityProof[i])
+	}
+
+	// Public witness info
+	fmt.Println("\n=== PUBLIC WITNESS ORDER ===")
+	fmt.Println("In gnark circuit (= Solidity order): [commitment, randomness, publicKey, sealedRoot, archiveOriginalRoot, totalRealChunks]")
+	var pubWitBuf bytes.Buffer
+	_, err = publicWitness.WriteTo(&pubWitBuf)
+	if err != nil {
+		return nil, fmt.Errorf("write public witness: %w", err)
+	}
+	fmt.Printf("Public witness size: %d bytes\n", pubWitBuf.Len())
+
+	return jsonOut, nil
+}
@@ -0,0 +1,140 @@
+package archivepoi
+
+import (
+	"fmt"
+	"math/big"
+
+	archivemuri "github.com/MuriData/muri-zkproof/circuits/archive_muri"
+	"github.com/MuriData/muri-zkproof/pkg/archive"
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/frontend"
+)
+
+// WitnessResult holds the fully populated circuit assignment and derived
+// public values that callers typically need for logging or fixture export.
+type WitnessResult struct {
+	Assignment      ArchivePoICircuit
+	ChunkIndices    [OpeningsCount]int // sealed chunk index per opening
+	SlotIndices     [OpeningsCount]int // member file slot per opening
+	TotalRealChunks int
+	PublicKey       *big.Int
+	Commitment      *big.Int
+	AggMsg          *big.Int
+}
+
+// PrepareWitness derives all public and private witness values for a proof
+// of storage over replica and returns a ready-to-use circuit assignment.
+//
+// For each of the OpeningsCount openings, a raw index is taken from the low
+// ChallengeWindowBits of DeriveChallengeIdx(randomness, k), then reduced
+// modulo totalRealChunks to select a sealed chunk.
+func PrepareWitness(secretKey, randomness *big.Int, replica *archivemuri.Replica) (*WitnessResult, error) {
+	arc := replica.Archive
+	total := arc.TotalRealChunks
+	if total == 0 {
+		return nil, fmt.Errorf("replica has no chunks")
+	}
+	if total > TotalLeaves {
+		return nil, fmt.Errorf("totalRealChunks %d exceeds circuit capacity %d", total, TotalLeaves)
+	}
+	if replica.SealedTree.NumLeaves != total {
+		return nil, fmt.Errorf("sealed tree numLeaves %d does not match totalRealChunks %d", replica.SealedTree.NumLeaves, total)
+	}
+
+	publicKey := crypto.DerivePublicKey(secretKey)
+	if publicKey.Cmp(replica.PublicKey) != 0 {
+		return nil, fmt.Errorf("secret key does not match replica public key")
+	}
+
+	var assignment ArchivePoICircuit
+	assignment.SecretKey = secretKey
+	assignment.Randomness = randomness
+	assignment.PublicKey = publicKey
+	assignment.SealedRoot = replica.SealedTree.Root
+	assignment.ArchiveOriginalRoot = arc.OriginalRoot
+	assignment.TotalRealChunks = total
+	assignment.SlotTreeRoot = arc.SlotTree.Root
+
+	var chunkIndices, slotIndices [OpeningsCount]int
+	leafHashesBig := make([]*big.Int, OpeningsCount)
+
+	for k := 0; k < OpeningsCount; k++ {
+		quotient, leafIndex := challengeIndex(randomness, k, total)
+		chunkIndices[k] = leafIndex
+		assignment.Quotients[k] = quotient
+		assignment.LeafIndices[k] = leafIndex
+
+		// Sealed chunk elements and Merkle proof.
+		elems := replica.Sealed[leafIndex*ElementsPerChunk : (leafIndex+1)*ElementsPerChunk]
+		for i := 0; i < ElementsPerChunk; i++ {
+			assignment.Elements[k][i] = elems[i]
+		}
+		siblings, _ := replica.SealedTree.GetProof(leafIndex)
+		for i := 0; i < MaxTreeDepth; i++ {
+			assignment.ProofPaths[k][i] = siblings[i]
+		}
+
+		leafHash := replica.SealedTree.GetLeafHash(leafIndex)
+		leafHashesBig[k] = new(big.Int)
+		leafHash.BigInt(leafHashesBig[k])
+
+		// Member file slot containing the chunk.
+		slot, err := arc.SlotOf(leafIndex)
+		if err != nil {
+			return nil, err
+		}
+		proof, err := arc.SlotProof(slot)
+		if err != nil {
+			return nil, err
+		}
+		slotIndices[k] = slot
+		assignment.Slots[k] = slotOpening(proof)
+	}
+
+	aggMsg := crypto.DeriveAggMsg(leafHashesBig, randomness)
+	commitment := crypto.DeriveCommitment(secretKey, aggMsg, randomness, publicKey)
+	assignment.Commitment = commitment
+
+	return &WitnessResult{
+		Assignment:      assignment,
+		ChunkIndices:    chunkIndices,
+		SlotIndices:     slotIndices,
+		TotalRealChunks: total,
+		PublicKey:       publicKey,
+		Commitment:      commitment,
+		AggMsg:          aggMsg,
+	}, nil
+}
+
+// challengeIndex reduces the low ChallengeWindowBits of
+// H(randomness, k) modulo total, returning (quotient, leafIndex).
+func challengeIndex(randomness *big.Int, k, total int) (*big.Int, int) {
+	var h fr.Element
+	h.SetBigInt(crypto.DeriveChallengeIdx(randomness, big.NewInt(int64(k))))
+	hBig := new(big.Int)
+	h.BigInt(hBig)
+
+	mask := new(big.Int).Lsh(big.NewInt(1), ChallengeWindowBits)
+	mask.Sub(mask, big.NewInt(1))
+	rawIndex := new(big.Int).And(hBig, mask)
+
+	quotient, leafIndex := new(big.Int).DivMod(rawIndex, big.NewInt(int64(total)), new(big.Int))
+	return quotient, int(leafIndex.Int64())
+}
+
+// slotOpening converts a native slot proof into its circuit assignment.
+func slotOpening(proof *archive.SlotProof) SlotOpening {
+	var slotPath [SlotTreeDepth]frontend.Variable
+	for i := 0; i < SlotTreeDepth; i++ {
+		slotPath[i] = proof.Siblings[i]
+	}
+
+	return SlotOpening{
+		FileRoot:       proof.File.Root,
+		FileNumChunks:  proof.File.NumChunks,
+		FileFirstChunk: proof.FirstChunk,
+		SlotIndex:      proof.Slot,
+		SlotPath:       slotPath,
+	}
+}
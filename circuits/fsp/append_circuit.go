@@ -0,0 +1,139 @@
+package fsp
+
+import (
+	"fmt"
+
+	"github.com/MuriData/muri-zkproof/circuits/shared"
+	"github.com/MuriData/muri-zkproof/pkg/merkle"
+	"github.com/consensys/gnark/frontend"
+)
+
+// FSPAppendCircuit proves that newRoot extends oldRoot by appending chunks
+// oldNumChunks..newNumChunks-1, so an appended file does not need a new FSP
+// proof from scratch. It opens leaf oldNumChunks (the first appended leaf) in
+// both trees:
+//   - In the old tree the leaf is padding and every sibling on its right
+//     (levels where oldNumChunks is a left child) is a zero subtree hash, so
+//     the old tree holds nothing at or beyond oldNumChunks.
+//   - In the new tree every sibling on its left (levels where oldNumChunks is
+//     a right child) equals the old one. These subtrees cover exactly leaves
+//     0..oldNumChunks-1, which are therefore unchanged.
+//   - The new leaf at oldNumChunks is real data.
+//   - A boundary proof of leaf newNumChunks-1 certifies newNumChunks for
+//     newRoot exactly as FSPCircuit does.
+//
+// oldNumChunks must be in [1, newNumChunks). The old chunk count itself is
+// not re-certified; it is the value previously certified for oldRoot.
+//
+// Proof paths are sized from Depth, so instances must be built with
+// NewFSPAppendCircuit (or PrepareAppendWitness).
+type FSPAppendCircuit struct {
+	// Public inputs (4)
+	OldRoot      frontend.Variable `gnark:"oldRoot,public"`
+	OldNumChunks frontend.Variable `gnark:"oldNumChunks,public"`
+	NewRoot      frontend.Variable `gnark:"newRoot,public"`
+	NewNumChunks frontend.Variable `gnark:"newNumChunks,public"`
+
+	// Private inputs: siblings of leaf oldNumChunks in the old and new trees,
+	// the new leaf hash at oldNumChunks, and the boundary proof of the new tree.
+	OldPath  []frontend.Variable `gnark:"oldPath"`
+	NewPath  []frontend.Variable `gnark:"newPath"`
+	NewLeaf  frontend.Variable   `gnark:"newLeaf"`
+	Boundary BoundaryMerkleProof `gnark:"boundary"`
+
+	// Depth fixes the circuit shape; it is not part of the witness.
+	Depth int `gnark:"-"`
+}
+
+// NewFSPAppendCircuit allocates an FSPAppendCircuit for a tree of the given
+// depth.
+func NewFSPAppendCircuit(depth int) *FSPAppendCircuit {
+	return &FSPAppendCircuit{
+		OldPath: make([]frontend.Variable, depth),
+		NewPath: make([]frontend.Variable, depth),
+		Boundary: BoundaryMerkleProof{
+			ProofPath:  make([]frontend.Variable, depth),
+			Directions: make([]frontend.Variable, depth),
+		},
+		Depth: depth,
+	}
+}
+
+func (circuit *FSPAppendCircuit) Define(api frontend.API) error {
+	depth := circuit.Depth
+	if err := validateDepth(depth); err != nil {
+		return fmt.Errorf("%w (use NewFSPAppendCircuit)", err)
+	}
+	if len(circuit.OldPath) != depth || len(circuit.NewPath) != depth ||
+		len(circuit.Boundary.ProofPath) != depth || len(circuit.Boundary.Directions) != depth {
+		return fmt.Errorf("append proof does not match tree depth %d", depth)
+	}
+
+	sponge, err := shared.NewSpongeHasher(api)
+	if err != nil {
+		return err
+	}
+
+	// ---------------------------------------------------------------
+	// 1. New boundary: newNumChunks in [1, 2^Depth] is certified for
+	//    newRoot.
+	// ---------------------------------------------------------------
+	if err := assertBoundary(api, sponge, depth, circuit.NewRoot, circuit.NewNumChunks, &circuit.Boundary); err != nil {
+		return err
+	}
+
+	// ---------------------------------------------------------------
+	// 2. Range check: 1 <= oldNumChunks < newNumChunks. Together with
+	//    newNumChunks <= 2^Depth this gives oldNumChunks < 2^Depth, so
+	//    its Depth-bit decomposition is the path of leaf oldNumChunks.
+	// ---------------------------------------------------------------
+	api.AssertIsEqual(api.IsZero(circuit.OldNumChunks), 0)
+	api.ToBinary(api.Sub(circuit.NewNumChunks, circuit.OldNumChunks, 1), depth)
+	oldBits := api.ToBinary(circuit.OldNumChunks, depth)
+
+	// ---------------------------------------------------------------
+	// 3. Old tree: leaf oldNumChunks is padding and every right sibling
+	//    is a zero subtree.
+	// ---------------------------------------------------------------
+	zeroSubtreeHashes := merkle.PrecomputeZeroHashes(depth, zeroLeafHash)
+	for j := 0; j < depth; j++ {
+		isLeftChild := api.Sub(1, oldBits[j])
+		diff := api.Sub(circuit.OldPath[j], frontend.Variable(zeroSubtreeHashes[j]))
+		api.AssertIsEqual(api.Mul(isLeftChild, diff), 0)
+	}
+
+	oldRoot, err := computeAppendRoot(api, sponge, frontend.Variable(zeroLeafHash), circuit.OldPath, oldBits)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(oldRoot, circuit.OldRoot)
+
+	// ---------------------------------------------------------------
+	// 4. New tree: left siblings are unchanged and leaf oldNumChunks is
+	//    real data.
+	// ---------------------------------------------------------------
+	for j := 0; j < depth; j++ {
+		diff := api.Sub(circuit.NewPath[j], circuit.OldPath[j])
+		api.AssertIsEqual(api.Mul(oldBits[j], diff), 0)
+	}
+	api.AssertIsEqual(api.IsZero(api.Sub(circuit.NewLeaf, frontend.Variable(zeroLeafHash))), 0)
+
+	newRoot, err := computeAppendRoot(api, sponge, circuit.NewLeaf, circuit.NewPath, oldBits)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(newRoot, circuit.NewRoot)
+
+	return nil
+}
+
+// computeAppendRoot hashes leaf up through siblings, taking direction bits
+// from the index decomposition.
+func computeAppendRoot(api frontend.API, sponge *shared.SpongeHasher, leaf frontend.Variable, siblings, bits []frontend.Variable) (frontend.Variable, error) {
+	proof := BoundaryMerkleProof{
+		LeafHash:   leaf,
+		ProofPath:  siblings,
+		Directions: bits,
+	}
+	return proof.ComputeRoot(api, sponge)
+}
@@ -0,0 +1,171 @@
+package fsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/MuriData/muri-zkproof/pkg/merkle"
+	"github.com/MuriData/muri-zkproof/pkg/setup"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/frontend"
+)
+
+// AppendProofFixture holds all values needed for Solidity tests of the
+// FSPAppendCircuit.
+type AppendProofFixture struct {
+	SolidityProof [8]string `json:"solidity_proof"`
+	OldRoot       string    `json:"old_root"`
+	OldNumChunks  string    `json:"old_num_chunks"`
+	NewRoot       string    `json:"new_root"`
+	NewNumChunks  string    `json:"new_num_chunks"`
+}
+
+// ExportAppendProofFixture generates a deterministic FSPAppendCircuit proof
+// fixture for the given tree depth, loading keys stored under
+// AppendCircuitName(depth) in keysDir.
+func ExportAppendProofFixture(keysDir string, depth int) ([]byte, error) {
+	if err := validateDepth(depth); err != nil {
+		return nil, err
+	}
+
+	// 1. Compile the circuit
+	fmt.Printf("Compiling circuit (depth %d)...\n", depth)
+	ccs, err := setup.CompileCircuit(NewFSPAppendCircuit(depth))
+	if err != nil {
+		return nil, fmt.Errorf("compile circuit: %w", err)
+	}
+
+	// 2. Load proving and verifying keys
+	fmt.Println("Loading keys...")
+	pk, vk, err := setup.LoadKeys(keysDir, AppendCircuitName(depth))
+	if err != nil {
+		return nil, fmt.Errorf("load keys: %w", err)
+	}
+
+	// 3. Create a deterministic 5-chunk file and append 3 more chunks to it
+	//    in place.
+	testFileData := make([]byte, 8*FileSize)
+	for i := range testFileData {
+		testFileData[i] = byte(i % 256)
+	}
+	chunks := merkle.SplitIntoChunks(testFileData, FileSize)
+	oldNumChunks := 5
+
+	zeroLeaf := crypto.ComputeZeroLeafHashFr(ElementSize, NumChunks)
+	smt, err := merkle.GenerateSparseMerkleTree(chunks[:oldNumChunks], depth, HashChunk, zeroLeaf)
+	if err != nil {
+		return nil, fmt.Errorf("build SMT: %w", err)
+	}
+	fmt.Printf("Old root: 0x%x (%d chunks)\n", smt.Root.Bytes(), smt.NumLeaves)
+
+	// 4. Append and prepare the witness
+	if err := smt.Append(chunks[oldNumChunks:], HashChunk); err != nil {
+		return nil, fmt.Errorf("append chunks: %w", err)
+	}
+	fmt.Printf("New root: 0x%x (%d chunks)\n", smt.Root.Bytes(), smt.NumLeaves)
+
+	result, err := PrepareAppendWitness(smt, oldNumChunks)
+	if err != nil {
+		return nil, fmt.Errorf("prepare witness: %w", err)
+	}
+
+	// 5. Create witness and generate proof
+	witness, err := frontend.NewWitness(&result.Assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		return nil, fmt.Errorf("create witness: %w", err)
+	}
+
+	publicWitness, err := witness.Public()
+	if err != nil {
+		return nil, fmt.Errorf("extract public witness: %w", err)
+	}
+
+	fmt.Println("Generating proof...")
+	proof, err := groth16.Prove(ccs, pk, witness)
+	if err != nil {
+		return nil, fmt.Errorf("prove: %w", err)
+	}
+
+	// 6. Verify proof in Go
+	err = groth16.Verify(proof, vk, publicWitness)
+	if err != nil {
+		return nil, fmt.Errorf("verify: %w", err)
+	}
+	fmt.Println("Proof verified successfully in Go!")
+
+	// 7. Extract proof points for Solidity
+	bn254Proof := proof.(*groth16bn254.Proof)
+
+	aX := new(big.Int)
+	aY := new(big.Int)
+	bn254Proof.Ar.X.BigInt(aX)
+	bn254Proof.Ar.Y.BigInt(aY)
+
+	bX0 := new(big.Int)
+	bX1 := new(big.Int)
+	bY0 := new(big.Int)
+	bY1 := new(big.Int)
+	bn254Proof.Bs.X.A0.BigInt(bX0)
+	bn254Proof.Bs.X.A1.BigInt(bX1)
+	bn254Proof.Bs.Y.A0.BigInt(bY0)
+	bn254Proof.Bs.Y.A1.BigInt(bY1)
+
+	cX := new(big.Int)
+	cY := new(big.Int)
+	bn254Proof.Krs.X.BigInt(cX)
+	bn254Proof.Krs.Y.BigInt(cY)
+
+	// Solidity format: [A.x, A.y, B.x1, B.x0, B.y1, B.y0, C.x, C.y]
+	solidityProof := [8]*big.Int{aX, aY, bX1, bX0, bY1, bY0, cX, cY}
+
+	oldRoot := new(big.Int)
+	result.OldRoot.BigInt(oldRoot)
+
+	fixture := AppendProofFixture{
+		OldRoot:      fmt.Sprintf("0x%064x", oldRoot),
+		OldNumChunks: fmt.Sprintf("%d", result.OldNumChunks),
+		NewRoot:      fmt.Sprintf("0x%064x", smt.RootBigInt()),
+		NewNumChunks: fmt.Sprintf("%d", result.NewNumChunks),
+	}
+	for i := 0; i < 8; i++ {
+		fixture.SolidityProof[i] = fmt.Sprintf("0x%064x", solidityProof[i])
+	}
+
+	jsonOut, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal fixture: %w", err)
+	}
+
+	// Print diagnostic info
+	fmt.Println("\n=== PROOF FIXTURE (JSON) ===")
+	fmt.Println(string(jsonOut))
+
+	fmt.Println("\n=== SOLIDITY CONSTANTS ===")
+	fmt.Printf("    // Public inputs\n")
+	fmt.Printf("    uint256 constant FSP_APPEND_OLD_ROOT = %s;\n", fixture.OldRoot)
+	fmt.Printf("    uint32 constant FSP_APPEND_OLD_NUM_CHUNKS = %s;\n", fixture.OldNumChunks)
+	fmt.Printf("    uint256 constant FSP_APPEND_NEW_ROOT = %s;\n", fixture.NewRoot)
+	fmt.Printf("    uint32 constant FSP_APPEND_NEW_NUM_CHUNKS = %s;\n", fixture.NewNumChunks)
+	fmt.Println()
+	fmt.Printf("    // Proof (uint256[8])\n")
+	for i := 0; i < 8; i++ {
+		fmt.Printf("    uint256 constant FSP_APPEND_PROOF_%d = %s;\n", i, fixture.SolidityProof[i])
+	}
+
+	// Public witness info
+	fmt.Println("\n=== PUBLIC WITNESS ORDER ===")
+	fmt.Println("In gnark circuit (= Solidity order): [oldRoot, oldNumChunks, newRoot, newNumChunks]")
+	var pubWitBuf bytes.Buffer
+	_, err = publicWitness.WriteTo(&pubWitBuf)
+	if err != nil {
+		return nil, fmt.Errorf("write public witness: %w", err)
+	}
+	fmt.Printf("Public witness size: %d bytes\n", pubWitBuf.Len())
+
+	return jsonOut, nil
+}
@@ -0,0 +1,150 @@
+package fsp_test
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/MuriData/muri-zkproof/circuits/fsp"
+	"github.com/MuriData/muri-zkproof/pkg/setup"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+)
+
+// TestFSPAppendCircuit appends chunks to a tree in place and solves the
+// append circuit for several old/new chunk counts, then checks that a
+// modified prefix or a wrong old count is rejected.
+func TestFSPAppendCircuit(t *testing.T) {
+	ccs, err := setup.CompileCircuit(fsp.NewFSPAppendCircuit(fsp.MaxTreeDepth))
+	if err != nil {
+		t.Fatalf("compile circuit: %v", err)
+	}
+	t.Logf("Constraints: %d", ccs.GetNbConstraints())
+
+	solved := func(assignment *fsp.FSPAppendCircuit) bool {
+		t.Helper()
+		witness, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+		if err != nil {
+			t.Fatalf("create witness: %v", err)
+		}
+		return ccs.IsSolved(witness) == nil
+	}
+
+	for _, tc := range [][2]int{{1, 2}, {1, 8}, {3, 4}, {4, 7}, {5, 13}} {
+		oldN, newN := tc[0], tc[1]
+		t.Run(fmt.Sprintf("%d_to_%d", oldN, newN), func(t *testing.T) {
+			data := make([]byte, newN*fsp.FileSize)
+			if _, err := rand.Read(data); err != nil {
+				t.Fatalf("generate random data: %v", err)
+			}
+			full, chunks := buildSMT(t, data)
+			smt, _ := buildSMT(t, data[:oldN*fsp.FileSize])
+			oldRoot := smt.Root
+
+			if err := smt.Append(chunks[oldN:], fsp.HashChunk); err != nil {
+				t.Fatalf("append: %v", err)
+			}
+			if smt.Root != full.Root {
+				t.Fatal("appended root differs from a fresh build")
+			}
+
+			result, err := fsp.PrepareAppendWitness(smt, oldN)
+			if err != nil {
+				t.Fatalf("prepare witness: %v", err)
+			}
+			if result.OldRoot != oldRoot {
+				t.Fatal("recomputed old root mismatch")
+			}
+			if !solved(&result.Assignment) {
+				t.Fatal("circuit not solved for a valid append")
+			}
+
+			// A different old root (here: a modified first chunk) is not a
+			// prefix of the new tree.
+			other := make([]byte, oldN*fsp.FileSize)
+			copy(other, data)
+			other[0] ^= 1
+			otherTree, _ := buildSMT(t, other)
+			tampered := result.Assignment
+			tampered.OldRoot = otherTree.Root
+			if solved(&tampered) {
+				t.Fatal("expected modified prefix to be rejected")
+			}
+
+			// Claiming fewer old chunks than the old tree holds leaves real
+			// data to the right of the old boundary.
+			if oldN > 1 {
+				shorter, err := fsp.PrepareAppendWitness(smt, oldN-1)
+				if err != nil {
+					t.Fatalf("prepare witness: %v", err)
+				}
+				tampered = shorter.Assignment
+				tampered.OldRoot = oldRoot
+				if solved(&tampered) {
+					t.Fatal("expected wrong old chunk count to be rejected")
+				}
+			}
+
+			// The new count must exceed the old one.
+			tampered = result.Assignment
+			tampered.OldNumChunks = newN
+			if solved(&tampered) {
+				t.Fatal("expected oldNumChunks >= newNumChunks to be rejected")
+			}
+		})
+	}
+}
+
+// TestPrepareAppendWitnessRejectsBadCounts checks the old chunk count range.
+func TestPrepareAppendWitnessRejectsBadCounts(t *testing.T) {
+	smt, _ := buildSMT(t, make([]byte, 3*fsp.FileSize))
+	for _, oldN := range []int{0, 3, 4} {
+		if _, err := fsp.PrepareAppendWitness(smt, oldN); err == nil {
+			t.Fatalf("expected error for old chunk count %d", oldN)
+		}
+	}
+}
+
+// TestFSPAppendExportFixture generates a deterministic append fixture and
+// verifies that it round-trips through JSON.
+func TestFSPAppendExportFixture(t *testing.T) {
+	ccs, err := setup.CompileCircuit(fsp.NewFSPAppendCircuit(fsp.MaxTreeDepth))
+	if err != nil {
+		t.Fatalf("compile circuit: %v", err)
+	}
+	pk, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		t.Fatalf("groth16 setup: %v", err)
+	}
+
+	tmpDir := t.TempDir()
+	if err := setup.ExportKeys(pk, vk, tmpDir, fsp.AppendCircuitName(fsp.MaxTreeDepth)); err != nil {
+		t.Fatalf("export keys: %v", err)
+	}
+
+	jsonOut, err := fsp.ExportAppendProofFixture(tmpDir, fsp.MaxTreeDepth)
+	if err != nil {
+		t.Fatalf("export proof fixture: %v", err)
+	}
+
+	var fixture fsp.AppendProofFixture
+	if err := json.Unmarshal(jsonOut, &fixture); err != nil {
+		t.Fatalf("unmarshal fixture: %v", err)
+	}
+	if fixture.OldRoot == "" || fixture.NewRoot == "" || fixture.OldRoot == fixture.NewRoot {
+		t.Fatal("fixture roots are empty or equal")
+	}
+	if fixture.OldNumChunks != "5" || fixture.NewNumChunks != "8" {
+		t.Fatalf("unexpected chunk counts %s -> %s", fixture.OldNumChunks, fixture.NewNumChunks)
+	}
+
+	jsonRoundTrip, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		t.Fatalf("re-marshal fixture: %v", err)
+	}
+	if string(jsonRoundTrip) != string(jsonOut) {
+		t.Fatal("fixture JSON round-trip mismatch")
+	}
+}
@@ -0,0 +1,65 @@
+package fsp
+
+import (
+	"fmt"
+
+	"github.com/MuriData/muri-zkproof/pkg/merkle"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// AppendWitnessResult holds the fully populated FSPAppendCircuit assignment.
+type AppendWitnessResult struct {
+	Assignment   FSPAppendCircuit
+	OldRoot      fr.Element
+	OldNumChunks int
+	NewNumChunks int
+}
+
+// PrepareAppendWitness builds an FSPAppendCircuit assignment proving that smt
+// extends the tree of its first oldNumChunks leaves. Only the new tree is
+// needed: the old tree shares its left siblings along the path of leaf
+// oldNumChunks and has zero subtrees everywhere to the right, so the old root
+// is recomputed here and returned in OldRoot. This makes it suitable after
+// SparseMerkleTree.Append has updated the tree in place.
+func PrepareAppendWitness(smt *merkle.SparseMerkleTree, oldNumChunks int) (*AppendWitnessResult, error) {
+	if oldNumChunks < 1 || oldNumChunks >= smt.NumLeaves {
+		return nil, fmt.Errorf("old chunk count %d must be in [1, %d)", oldNumChunks, smt.NumLeaves)
+	}
+
+	base, err := PrepareWitness(smt)
+	if err != nil {
+		return nil, err
+	}
+
+	siblings, directions := smt.GetProof(oldNumChunks)
+	newLeaf := smt.GetLeafHash(oldNumChunks)
+
+	assignment := *NewFSPAppendCircuit(smt.Depth)
+	oldRoot := smt.ZeroHashes[0]
+	for j := 0; j < smt.Depth; j++ {
+		oldSibling := siblings[j]
+		if directions[j] == 0 {
+			// Right sibling: empty in the old tree.
+			oldSibling = smt.ZeroHashes[j]
+			oldRoot = merkle.HashNodesFr(oldRoot, oldSibling)
+		} else {
+			oldRoot = merkle.HashNodesFr(oldSibling, oldRoot)
+		}
+		assignment.OldPath[j] = oldSibling
+		assignment.NewPath[j] = siblings[j]
+	}
+
+	assignment.OldRoot = oldRoot
+	assignment.OldNumChunks = oldNumChunks
+	assignment.NewRoot = smt.Root
+	assignment.NewNumChunks = smt.NumLeaves
+	assignment.NewLeaf = newLeaf
+	assignment.Boundary = base.Assignment.Proof
+
+	return &AppendWitnessResult{
+		Assignment:   assignment,
+		OldRoot:      oldRoot,
+		OldNumChunks: oldNumChunks,
+		NewNumChunks: smt.NumLeaves,
+	}, nil
+}
@@ -0,0 +1,140 @@
+package fsp
+
+import (
+	"fmt"
+	"math/bits"
+
+	"github.com/MuriData/muri-zkproof/circuits/shared"
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/consensys/gnark/frontend"
+)
+
+// chunkSizeBits is log2(FileSize). The last chunk's length is range-checked
+// with a chunkSizeBits-bit decomposition, so FileSize must be a power of two.
+var chunkSizeBits = bits.TrailingZeros(uint(FileSize))
+
+// FSPBytesCircuit extends FSPCircuit to the exact file length in bytes. On
+// top of the boundary checks of FSPCircuit it opens the last leaf's NumChunks
+// field elements and checks:
+//   - (numChunks - 1) * FileSize < byteLength <= numChunks * FileSize
+//   - sponge(DomainTagReal, bytes...) equals the boundary leaf hash
+//   - every byte of the last chunk at offset >= byteLength mod FileSize
+//     (or none, if the chunk is full) is zero
+//
+// Elements entirely past the end must be zero. The single element that
+// straddles the end is decomposed into its 31 big-endian bytes and only its
+// trailing bytes are constrained.
+//
+// Instances must be built with NewFSPBytesCircuit (or PrepareBytesWitness).
+type FSPBytesCircuit struct {
+	// Public inputs (3)
+	RootHash   frontend.Variable `gnark:"rootHash,public"`
+	NumChunks  frontend.Variable `gnark:"numChunks,public"`
+	ByteLength frontend.Variable `gnark:"byteLength,public"`
+
+	// Private inputs: Merkle proof of leaf numChunks-1 and its contents.
+	Proof BoundaryMerkleProof `gnark:"proof"`
+	Bytes []frontend.Variable `gnark:"bytes"`
+
+	// EndElement is the index of the element holding the last real byte and
+	// EndOffset that byte's offset within the element, so that
+	// lastChunkLength - 1 == EndElement*ElementSize + EndOffset.
+	EndElement frontend.Variable `gnark:"endElement"`
+	EndOffset  frontend.Variable `gnark:"endOffset"`
+
+	// Depth fixes the circuit shape; it is not part of the witness.
+	Depth int `gnark:"-"`
+}
+
+// NewFSPBytesCircuit allocates an FSPBytesCircuit for a tree of the given depth.
+func NewFSPBytesCircuit(depth int) *FSPBytesCircuit {
+	return &FSPBytesCircuit{
+		Proof: BoundaryMerkleProof{
+			ProofPath:  make([]frontend.Variable, depth),
+			Directions: make([]frontend.Variable, depth),
+		},
+		Bytes: make([]frontend.Variable, NumChunks),
+		Depth: depth,
+	}
+}
+
+func (circuit *FSPBytesCircuit) Define(api frontend.API) error {
+	depth := circuit.Depth
+	if err := validateDepth(depth); err != nil {
+		return fmt.Errorf("%w (use NewFSPBytesCircuit)", err)
+	}
+	if len(circuit.Proof.ProofPath) != depth || len(circuit.Proof.Directions) != depth {
+		return fmt.Errorf("boundary proof does not match tree depth %d", depth)
+	}
+	if len(circuit.Bytes) != NumChunks {
+		return fmt.Errorf("%d byte elements, expected %d", len(circuit.Bytes), NumChunks)
+	}
+
+	sponge, err := shared.NewSpongeHasher(api)
+	if err != nil {
+		return err
+	}
+
+	if err := assertBoundary(api, sponge, depth, circuit.RootHash, circuit.NumChunks, &circuit.Proof); err != nil {
+		return err
+	}
+
+	// ---------------------------------------------------------------
+	// 1. Length binding: lastLen = byteLength - (numChunks-1)*FileSize
+	//    must lie in [1, FileSize].
+	// ---------------------------------------------------------------
+	lastLen := api.Sub(circuit.ByteLength, api.Mul(api.Sub(circuit.NumChunks, 1), FileSize))
+	api.ToBinary(api.Sub(lastLen, 1), chunkSizeBits)
+
+	// ---------------------------------------------------------------
+	// 2. The opened elements are the contents of the boundary leaf.
+	// ---------------------------------------------------------------
+	leafHash, err := sponge.Hash(frontend.Variable(crypto.DomainTagReal), circuit.Bytes...)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(leafHash, circuit.Proof.LeafHash)
+
+	// ---------------------------------------------------------------
+	// 3. Locate the end: lastLen - 1 == endElement*ElementSize + endOffset.
+	//    One-hot selectors over the element and offset ranges force
+	//    endElement in [0, NumChunks) and endOffset in [0, ElementSize).
+	// ---------------------------------------------------------------
+	api.AssertIsEqual(api.Sub(lastLen, 1),
+		api.Add(api.Mul(circuit.EndElement, ElementSize), circuit.EndOffset))
+
+	// ---------------------------------------------------------------
+	// 4. Elements after endElement must be zero; pick out the end element.
+	//    pastEnd is the running sum of the one-hot selector, i.e. 1 for
+	//    every element strictly after endElement.
+	// ---------------------------------------------------------------
+	var selSum, pastEnd, endElem frontend.Variable = 0, 0, 0
+	for i := 0; i < NumChunks; i++ {
+		api.AssertIsEqual(api.Mul(pastEnd, circuit.Bytes[i]), 0)
+
+		sel := api.IsZero(api.Sub(circuit.EndElement, i))
+		endElem = api.Add(endElem, api.Mul(sel, circuit.Bytes[i]))
+		selSum = api.Add(selSum, sel)
+		pastEnd = api.Add(pastEnd, sel)
+	}
+	api.AssertIsEqual(selSum, 1)
+
+	// ---------------------------------------------------------------
+	// 5. Within the end element, bytes after endOffset must be zero.
+	//    Byte j (big-endian) occupies bits [8*(ElementSize-1-j), +8).
+	// ---------------------------------------------------------------
+	elemBits := api.ToBinary(endElem, 8*ElementSize)
+	selSum, pastEnd = 0, 0
+	for j := 0; j < ElementSize; j++ {
+		lo := 8 * (ElementSize - 1 - j)
+		byteVal := api.FromBinary(elemBits[lo : lo+8]...)
+		api.AssertIsEqual(api.Mul(pastEnd, byteVal), 0)
+
+		sel := api.IsZero(api.Sub(circuit.EndOffset, j))
+		selSum = api.Add(selSum, sel)
+		pastEnd = api.Add(pastEnd, sel)
+	}
+	api.AssertIsEqual(selSum, 1)
+
+	return nil
+}
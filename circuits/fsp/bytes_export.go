@@ -0,0 +1,160 @@
+package fsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/MuriData/muri-zkproof/pkg/merkle"
+	"github.com/MuriData/muri-zkproof/pkg/setup"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/frontend"
+)
+
+// BytesProofFixture holds all values needed for Solidity tests of the
+// FSPBytesCircuit.
+type BytesProofFixture struct {
+	SolidityProof [8]string `json:"solidity_proof"`
+	RootHash      string    `json:"root_hash"`
+	NumChunks     string    `json:"num_chunks"`
+	ByteLength    string    `json:"byte_length"`
+}
+
+// ExportBytesProofFixture generates a deterministic FSPBytesCircuit proof
+// fixture for the given tree depth, loading keys stored under
+// BytesCircuitName(depth) in keysDir.
+func ExportBytesProofFixture(keysDir string, depth int) ([]byte, error) {
+	if err := validateDepth(depth); err != nil {
+		return nil, err
+	}
+
+	// 1. Compile the circuit
+	fmt.Printf("Compiling circuit (depth %d)...\n", depth)
+	ccs, err := setup.CompileCircuit(NewFSPBytesCircuit(depth))
+	if err != nil {
+		return nil, fmt.Errorf("compile circuit: %w", err)
+	}
+
+	// 2. Load proving and verifying keys
+	fmt.Println("Loading keys...")
+	pk, vk, err := setup.LoadKeys(keysDir, BytesCircuitName(depth))
+	if err != nil {
+		return nil, fmt.Errorf("load keys: %w", err)
+	}
+
+	// 3. Create a deterministic test file that ends mid-chunk and mid-element
+	//    (7 full chunks + 1000 bytes).
+	testFileData := make([]byte, 7*FileSize+1000)
+	for i := range testFileData {
+		testFileData[i] = byte(i%255 + 1)
+	}
+	chunks := merkle.SplitIntoChunks(testFileData, FileSize)
+	fmt.Printf("Chunks: %d, Bytes: %d\n", len(chunks), len(testFileData))
+
+	// 4. Build sparse Merkle tree and prepare the witness
+	zeroLeaf := crypto.ComputeZeroLeafHashFr(ElementSize, NumChunks)
+	smt, err := merkle.GenerateSparseMerkleTree(chunks, depth, HashChunk, zeroLeaf)
+	if err != nil {
+		return nil, fmt.Errorf("build SMT: %w", err)
+	}
+	fmt.Printf("Merkle root: 0x%x\n", smt.Root.Bytes())
+
+	result, err := PrepareBytesWitness(smt, chunks[len(chunks)-1], int64(len(testFileData)))
+	if err != nil {
+		return nil, fmt.Errorf("prepare witness: %w", err)
+	}
+
+	// 5. Create witness and generate proof
+	witness, err := frontend.NewWitness(&result.Assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		return nil, fmt.Errorf("create witness: %w", err)
+	}
+
+	publicWitness, err := witness.Public()
+	if err != nil {
+		return nil, fmt.Errorf("extract public witness: %w", err)
+	}
+
+	fmt.Println("Generating proof...")
+	proof, err := groth16.Prove(ccs, pk, witness)
+	if err != nil {
+		return nil, fmt.Errorf("prove: %w", err)
+	}
+
+	// 6. Verify proof in Go
+	err = groth16.Verify(proof, vk, publicWitness)
+	if err != nil {
+		return nil, fmt.Errorf("verify: %w", err)
+	}
+	fmt.Println("Proof verified successfully in Go!")
+
+	// 7. Extract proof points for Solidity
+	bn254Proof := proof.(*groth16bn254.Proof)
+
+	aX := new(big.Int)
+	aY := new(big.Int)
+	bn254Proof.Ar.X.BigInt(aX)
+	bn254Proof.Ar.Y.BigInt(aY)
+
+	bX0 := new(big.Int)
+	bX1 := new(big.Int)
+	bY0 := new(big.Int)
+	bY1 := new(big.Int)
+	bn254Proof.Bs.X.A0.BigInt(bX0)
+	bn254Proof.Bs.X.A1.BigInt(bX1)
+	bn254Proof.Bs.Y.A0.BigInt(bY0)
+	bn254Proof.Bs.Y.A1.BigInt(bY1)
+
+	cX := new(big.Int)
+	cY := new(big.Int)
+	bn254Proof.Krs.X.BigInt(cX)
+	bn254Proof.Krs.Y.BigInt(cY)
+
+	// Solidity format: [A.x, A.y, B.x1, B.x0, B.y1, B.y0, C.x, C.y]
+	solidityProof := [8]*big.Int{aX, aY, bX1, bX0, bY1, bY0, cX, cY}
+
+	fixture := BytesProofFixture{
+		RootHash:   fmt.Sprintf("0x%064x", smt.RootBigInt()),
+		NumChunks:  fmt.Sprintf("%d", result.NumLeaves),
+		ByteLength: fmt.Sprintf("%d", result.ByteLength),
+	}
+	for i := 0; i < 8; i++ {
+		fixture.SolidityProof[i] = fmt.Sprintf("0x%064x", solidityProof[i])
+	}
+
+	jsonOut, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal fixture: %w", err)
+	}
+
+	// Print diagnostic info
+	fmt.Println("\n=== PROOF FIXTURE (JSON) ===")
+	fmt.Println(string(jsonOut))
+
+	fmt.Println("\n=== SOLIDITY CONSTANTS ===")
+	fmt.Printf("    // Public inputs\n")
+	fmt.Printf("    uint256 constant FSP_BYTES_FILE_ROOT = %s;\n", fixture.RootHash)
+	fmt.Printf("    uint32 constant FSP_BYTES_NUM_CHUNKS = %s;\n", fixture.NumChunks)
+	fmt.Printf("    uint64 constant FSP_BYTES_BYTE_LENGTH = %s;\n", fixture.ByteLength)
+	fmt.Println()
+	fmt.Printf("    // Proof (uint256[8])\n")
+	for i := 0; i < 8; i++ {
+		fmt.Printf("    uint256 constant FSP_BYTES_PROOF_%d = %s;\n", i, fixture.SolidityProof[i])
+	}
+
+	// Public witness info
+	fmt.Println("\n=== PUBLIC WITNESS ORDER ===")
+	fmt.Println("In gnark circuit (= Solidity order): [rootHash, numChunks, byteLength]")
+	var pubWitBuf bytes.Buffer
+	_, err = publicWitness.WriteTo(&pubWitBuf)
+	if err != nil {
+		return nil, fmt.Errorf("write public witness: %w", err)
+	}
+	fmt.Printf("Public witness size: %d bytes\n", pubWitBuf.Len())
+
+	return jsonOut, nil
+}
@@ -0,0 +1,126 @@
+package fsp_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/MuriData/muri-zkproof/circuits/fsp"
+	"github.com/MuriData/muri-zkproof/pkg/setup"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/constraint"
+	"github.com/consensys/gnark/frontend"
+)
+
+// nonZeroData returns n bytes with no zero byte, so that every byte of the
+// file is distinguishable from padding.
+func nonZeroData(n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i%255 + 1)
+	}
+	return data
+}
+
+// solved reports whether the assignment satisfies the compiled circuit.
+func solved(t *testing.T, ccs constraint.ConstraintSystem, assignment *fsp.FSPBytesCircuit) bool {
+	t.Helper()
+	witness, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatalf("create witness: %v", err)
+	}
+	return ccs.IsSolved(witness) == nil
+}
+
+// TestFSPBytesEndToEnd proves and verifies the exact length of a file that
+// ends in the middle of a chunk and of a field element.
+func TestFSPBytesEndToEnd(t *testing.T) {
+	ccs, err := setup.CompileCircuit(fsp.NewFSPBytesCircuit(fsp.MaxTreeDepth))
+	if err != nil {
+		t.Fatalf("compile circuit: %v", err)
+	}
+	pk, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		t.Fatalf("groth16 setup: %v", err)
+	}
+
+	data := nonZeroData(3*fsp.FileSize + 1000)
+	smt, chunks := buildSMT(t, data)
+	result, err := fsp.PrepareBytesWitness(smt, chunks[len(chunks)-1], int64(len(data)))
+	if err != nil {
+		t.Fatalf("prepare witness: %v", err)
+	}
+
+	witness, err := frontend.NewWitness(&result.Assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatalf("create witness: %v", err)
+	}
+	publicWitness, err := witness.Public()
+	if err != nil {
+		t.Fatalf("extract public witness: %v", err)
+	}
+	proof, err := groth16.Prove(ccs, pk, witness)
+	if err != nil {
+		t.Fatalf("prove: %v", err)
+	}
+	if err := groth16.Verify(proof, vk, publicWitness); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+}
+
+// TestFSPBytesLengths solves the circuit for lengths at chunk and element
+// boundaries and checks that misreported lengths are rejected.
+func TestFSPBytesLengths(t *testing.T) {
+	ccs, err := setup.CompileCircuit(fsp.NewFSPBytesCircuit(fsp.MaxTreeDepth))
+	if err != nil {
+		t.Fatalf("compile circuit: %v", err)
+	}
+
+	lengths := []int{
+		1,
+		fsp.ElementSize,
+		fsp.ElementSize + 1,
+		fsp.FileSize - 1,
+		fsp.FileSize,
+		fsp.FileSize + 1,
+		2*fsp.FileSize + 1000,
+	}
+	for _, n := range lengths {
+		t.Run(fmt.Sprintf("len_%d", n), func(t *testing.T) {
+			data := nonZeroData(n)
+			smt, chunks := buildSMT(t, data)
+			lastChunk := chunks[len(chunks)-1]
+
+			result, err := fsp.PrepareBytesWitness(smt, lastChunk, int64(n))
+			if err != nil {
+				t.Fatalf("prepare witness: %v", err)
+			}
+			if !solved(t, ccs, &result.Assignment) {
+				t.Fatal("circuit not solved for the true length")
+			}
+
+			// Under-reporting by one byte within the same chunk, with a
+			// consistent end position, leaves a non-zero byte past the end.
+			if lastLen := (n-1)%fsp.FileSize + 1; lastLen > 1 {
+				short := result.Assignment
+				short.ByteLength = n - 1
+				short.EndElement = (lastLen - 2) / fsp.ElementSize
+				short.EndOffset = (lastLen - 2) % fsp.ElementSize
+				if solved(t, ccs, &short) {
+					t.Fatal("expected under-reported length to be rejected")
+				}
+			}
+
+			// Over-reporting past the last chunk contradicts numChunks.
+			long := result.Assignment
+			long.ByteLength = len(chunks)*fsp.FileSize + 1
+			if solved(t, ccs, &long) {
+				t.Fatal("expected length beyond numChunks to be rejected")
+			}
+
+			if _, err := fsp.PrepareBytesWitness(smt, lastChunk, int64(n-1)); n > 1 && err == nil {
+				t.Fatal("expected PrepareBytesWitness to reject a non-zero byte past the end")
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package fsp
+
+import (
+	"fmt"
+
+	"github.com/MuriData/muri-zkproof/pkg/field"
+	"github.com/MuriData/muri-zkproof/pkg/merkle"
+)
+
+// BytesWitnessResult holds the fully populated FSPBytesCircuit assignment.
+type BytesWitnessResult struct {
+	Assignment FSPBytesCircuit
+	NumLeaves  int
+	ByteLength int64
+}
+
+// PrepareBytesWitness builds an FSPBytesCircuit assignment for a file of
+// byteLength bytes committed to by smt. lastChunk is the file's last chunk
+// (chunk NumLeaves-1) as stored in the tree: the final byteLength mod
+// FileSize bytes of the file, optionally zero-padded to FileSize.
+func PrepareBytesWitness(smt *merkle.SparseMerkleTree, lastChunk []byte, byteLength int64) (*BytesWitnessResult, error) {
+	if byteLength <= 0 {
+		return nil, fmt.Errorf("byte length must be positive, got %d", byteLength)
+	}
+	numLeaves := int((byteLength + FileSize - 1) / FileSize)
+	if smt.NumLeaves != numLeaves {
+		return nil, fmt.Errorf("byte length %d needs %d chunks, tree has %d", byteLength, numLeaves, smt.NumLeaves)
+	}
+	if len(lastChunk) > FileSize {
+		return nil, fmt.Errorf("last chunk is %d bytes, exceeds chunk size %d", len(lastChunk), FileSize)
+	}
+
+	lastLen := int(byteLength - int64(numLeaves-1)*FileSize)
+	for i := lastLen; i < len(lastChunk); i++ {
+		if lastChunk[i] != 0 {
+			return nil, fmt.Errorf("last chunk has non-zero byte at offset %d beyond length %d", i, lastLen)
+		}
+	}
+	leafHash := HashChunk(lastChunk)
+	if smt.GetLeafHash(numLeaves-1) != leafHash {
+		return nil, fmt.Errorf("last chunk does not match leaf %d of the tree", numLeaves-1)
+	}
+
+	base, err := PrepareWitness(smt)
+	if err != nil {
+		return nil, err
+	}
+
+	assignment := *NewFSPBytesCircuit(smt.Depth)
+	assignment.RootHash = base.Assignment.RootHash
+	assignment.NumChunks = base.Assignment.NumChunks
+	assignment.ByteLength = byteLength
+	assignment.Proof = base.Assignment.Proof
+	assignment.Bytes = field.Bytes2Field(lastChunk, NumChunks, ElementSize)
+	assignment.EndElement = (lastLen - 1) / ElementSize
+	assignment.EndOffset = (lastLen - 1) % ElementSize
+
+	return &BytesWitnessResult{
+		Assignment: assignment,
+		NumLeaves:  numLeaves,
+		ByteLength: byteLength,
+	}, nil
+}
@@ -1,6 +1,8 @@
 package fsp
 
 import (
+	"fmt"
+
 	"github.com/MuriData/muri-zkproof/circuits/shared"
 	"github.com/MuriData/muri-zkproof/pkg/crypto"
 	"github.com/MuriData/muri-zkproof/pkg/merkle"
@@ -12,16 +14,8 @@ import (
 // at package init. It is used as a circuit constant.
 var zeroLeafHash fr.Element
 
-// zeroSubtreeHashes[j] is the hash of an all-zero subtree of depth j.
-// zeroSubtreeHashes[0] = zeroLeafHash, zeroSubtreeHashes[j] = H(zh[j-1], zh[j-1]).
-var zeroSubtreeHashes [MaxTreeDepth]fr.Element
-
 func init() {
 	zeroLeafHash = crypto.ComputeZeroLeafHashFr(ElementSize, NumChunks)
-	zh := merkle.PrecomputeZeroHashes(MaxTreeDepth, zeroLeafHash)
-	for i := 0; i < MaxTreeDepth; i++ {
-		zeroSubtreeHashes[i] = zh[i]
-	}
 }
 
 // FSPCircuit proves the exact file boundary in a Sparse Merkle Tree using a
@@ -31,8 +25,11 @@ func init() {
 //     precomputed zero subtree hash (no real data to the right)
 //   - The proof path reconstructs the claimed root
 //
-// When numChunks == TotalLeaves (full tree), last = 2^20 - 1 has all bits set,
+// When numChunks == 2^Depth (full tree), last = 2^Depth - 1 has all bits set,
 // so it is a right child at every level and no zero-sibling checks are enforced.
+//
+// The proof path is sized from Depth, so instances must be built with
+// NewFSPCircuit (or PrepareWitness).
 type FSPCircuit struct {
 	// Public inputs (2)
 	RootHash  frontend.Variable `gnark:"rootHash,public"`
@@ -40,57 +37,89 @@ type FSPCircuit struct {
 
 	// Private inputs: single Merkle proof of leaf at numChunks-1
 	Proof BoundaryMerkleProof `gnark:"proof"`
+
+	// Depth fixes the circuit shape; it is not part of the witness.
+	Depth int `gnark:"-"`
+}
+
+// NewFSPCircuit allocates an FSPCircuit for a tree of the given depth.
+func NewFSPCircuit(depth int) *FSPCircuit {
+	return &FSPCircuit{
+		Proof: BoundaryMerkleProof{
+			ProofPath:  make([]frontend.Variable, depth),
+			Directions: make([]frontend.Variable, depth),
+		},
+		Depth: depth,
+	}
 }
 
 func (circuit *FSPCircuit) Define(api frontend.API) error {
+	depth := circuit.Depth
+	if err := validateDepth(depth); err != nil {
+		return fmt.Errorf("%w (use NewFSPCircuit)", err)
+	}
+	if len(circuit.Proof.ProofPath) != depth || len(circuit.Proof.Directions) != depth {
+		return fmt.Errorf("boundary proof does not match tree depth %d", depth)
+	}
+
 	sponge, err := shared.NewSpongeHasher(api)
 	if err != nil {
 		return err
 	}
 
+	return assertBoundary(api, sponge, depth, circuit.RootHash, circuit.NumChunks, &circuit.Proof)
+}
+
+// assertBoundary constrains proof to be the Merkle path of the last real leaf
+// (numChunks - 1) of a depth-deep tree with the given root, with only padding
+// to its right. It is shared by FSPCircuit and FSPBytesCircuit.
+func assertBoundary(api frontend.API, sponge *shared.SpongeHasher, depth int, rootHash, numChunks frontend.Variable, proof *BoundaryMerkleProof) error {
+	// zeroSubtreeHashes[j] is the hash of an all-zero subtree of depth j.
+	zeroSubtreeHashes := merkle.PrecomputeZeroHashes(depth, zeroLeafHash)
+
 	// ---------------------------------------------------------------
-	// 1. Range check: numChunks in [1, TotalLeaves].
-	//    ToBinary(numChunks - 1, MaxTreeDepth) constrains
-	//    numChunks - 1 in [0, 2^20 - 1], i.e. numChunks in [1, 2^20].
+	// 1. Range check: numChunks in [1, 2^Depth].
+	//    ToBinary(numChunks - 1, Depth) constrains
+	//    numChunks - 1 in [0, 2^Depth - 1], i.e. numChunks in [1, 2^Depth].
 	// ---------------------------------------------------------------
-	api.AssertIsEqual(api.IsZero(circuit.NumChunks), 0)
+	api.AssertIsEqual(api.IsZero(numChunks), 0)
 
-	lastIdx := api.Sub(circuit.NumChunks, 1)
-	lastBits := api.ToBinary(lastIdx, MaxTreeDepth)
+	lastIdx := api.Sub(numChunks, 1)
+	lastBits := api.ToBinary(lastIdx, depth)
 
 	// ---------------------------------------------------------------
 	// 2. Direction bits must match the binary decomposition of lastIdx.
 	// ---------------------------------------------------------------
-	for j := 0; j < MaxTreeDepth; j++ {
-		api.AssertIsEqual(circuit.Proof.Directions[j], lastBits[j])
+	for j := 0; j < depth; j++ {
+		api.AssertIsEqual(proof.Directions[j], lastBits[j])
 	}
 
 	// ---------------------------------------------------------------
 	// 3. Leaf must be non-zero (real data, not padding).
 	// ---------------------------------------------------------------
 	zeroLeafConst := frontend.Variable(zeroLeafHash)
-	api.AssertIsEqual(api.IsZero(api.Sub(circuit.Proof.LeafHash, zeroLeafConst)), 0)
+	api.AssertIsEqual(api.IsZero(api.Sub(proof.LeafHash, zeroLeafConst)), 0)
 
 	// ---------------------------------------------------------------
 	// 4. Zero-sibling check: at each level where last is a left child
 	//    (bit = 0), the sibling must equal the zero subtree hash for
 	//    that level. This proves no real chunk exists beyond lastIdx.
 	// ---------------------------------------------------------------
-	for j := 0; j < MaxTreeDepth; j++ {
+	for j := 0; j < depth; j++ {
 		zhConst := frontend.Variable(zeroSubtreeHashes[j])
 		isLeftChild := api.Sub(1, lastBits[j]) // 1 if left child, 0 if right
-		diff := api.Sub(circuit.Proof.ProofPath[j], zhConst)
+		diff := api.Sub(proof.ProofPath[j], zhConst)
 		api.AssertIsEqual(api.Mul(isLeftChild, diff), 0)
 	}
 
 	// ---------------------------------------------------------------
 	// 5. Verify the proof path reconstructs the claimed root.
 	// ---------------------------------------------------------------
-	root, err := circuit.Proof.ComputeRoot(api, sponge)
+	root, err := proof.ComputeRoot(api, sponge)
 	if err != nil {
 		return err
 	}
-	api.AssertIsEqual(root, circuit.RootHash)
+	api.AssertIsEqual(root, rootHash)
 
 	return nil
 }
@@ -1,10 +1,66 @@
 package fsp
 
+import "fmt"
+
 const (
-	FileSize    = 16 * 1024                                   // 16 KB chunk size (must match PoI)
-	ElementSize = 31                                          // bytes per field element (must match PoI)
+	FileSize    = 16 * 1024                                       // 16 KB chunk size (must match PoI)
+	ElementSize = 31                                              // bytes per field element (must match PoI)
 	NumChunks   = int((FileSize + ElementSize - 1) / ElementSize) // 529 — field elements per leaf hash (must match PoI)
 
 	MaxTreeDepth = 20
 	TotalLeaves  = 1 << MaxTreeDepth // 1,048,576 leaf slots in the sparse Merkle tree
 )
+
+// SupportedDepths lists the tree depths with a registered FSP circuit, in
+// ascending order. Depth 20 caps files at 16 GiB, 24 at 256 GiB and 28 at
+// 4 TiB.
+var SupportedDepths = []int{MaxTreeDepth, 24, 28}
+
+// DepthForChunks returns the smallest supported depth whose tree holds
+// numChunks leaves. Files that fit in a depth-20 tree keep depth 20, so their
+// roots are unchanged.
+func DepthForChunks(numChunks int) (int, error) {
+	for _, d := range SupportedDepths {
+		if numChunks <= 1<<d {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("%d chunks exceed the largest supported tree depth %d",
+		numChunks, SupportedDepths[len(SupportedDepths)-1])
+}
+
+// CircuitName returns the registry name of the FSP circuit for depth:
+// "fsp" for MaxTreeDepth and "fsp-d<depth>" otherwise.
+func CircuitName(depth int) string {
+	if depth == MaxTreeDepth {
+		return "fsp"
+	}
+	return fmt.Sprintf("fsp-d%d", depth)
+}
+
+// BytesCircuitName returns the registry name of the FSPBytesCircuit for
+// depth: "fsp_bytes" for MaxTreeDepth and "fsp_bytes-d<depth>" otherwise.
+func BytesCircuitName(depth int) string {
+	if depth == MaxTreeDepth {
+		return "fsp_bytes"
+	}
+	return fmt.Sprintf("fsp_bytes-d%d", depth)
+}
+
+// AppendCircuitName returns the registry name of the FSPAppendCircuit for
+// depth: "fsp_append" for MaxTreeDepth and "fsp_append-d<depth>" otherwise.
+func AppendCircuitName(depth int) string {
+	if depth == MaxTreeDepth {
+		return "fsp_append"
+	}
+	return fmt.Sprintf("fsp_append-d%d", depth)
+}
+
+func validateDepth(depth int) error {
+	for _, d := range SupportedDepths {
+		if d == depth {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported FSP tree depth %d (supported: %v)", depth, SupportedDepths)
+}
@@ -25,16 +25,27 @@ type ProofFixture struct {
 // ExportProofFixture generates a deterministic proof fixture for Solidity tests.
 // keysDir is the directory containing the proving and verifying keys.
 func ExportProofFixture(keysDir string) ([]byte, error) {
+	return ExportProofFixtureForDepth(keysDir, MaxTreeDepth)
+}
+
+// ExportProofFixtureForDepth generates a deterministic proof fixture for the
+// FSP circuit of the given tree depth, loading keys stored under
+// CircuitName(depth) in keysDir.
+func ExportProofFixtureForDepth(keysDir string, depth int) ([]byte, error) {
+	if err := validateDepth(depth); err != nil {
+		return nil, err
+	}
+
 	// 1. Compile the circuit
-	fmt.Println("Compiling circuit...")
-	ccs, err := setup.CompileCircuit(&FSPCircuit{})
+	fmt.Printf("Compiling circuit (depth %d)...\n", depth)
+	ccs, err := setup.CompileCircuit(NewFSPCircuit(depth))
 	if err != nil {
 		return nil, fmt.Errorf("compile circuit: %w", err)
 	}
 
 	// 2. Load proving and verifying keys
 	fmt.Println("Loading keys...")
-	pk, vk, err := setup.LoadKeys(keysDir, "fsp")
+	pk, vk, err := setup.LoadKeys(keysDir, CircuitName(depth))
 	if err != nil {
 		return nil, fmt.Errorf("load keys: %w", err)
 	}
@@ -49,7 +60,7 @@ func ExportProofFixture(keysDir string) ([]byte, error) {
 
 	// 4. Build sparse Merkle tree and prepare the witness
 	zeroLeaf := crypto.ComputeZeroLeafHashFr(ElementSize, NumChunks)
-	smt, err := merkle.GenerateSparseMerkleTree(chunks, MaxTreeDepth, HashChunk, zeroLeaf)
+	smt, err := merkle.GenerateSparseMerkleTree(chunks, depth, HashChunk, zeroLeaf)
 	if err != nil {
 		return nil, fmt.Errorf("build SMT: %w", err)
 	}
@@ -59,7 +59,7 @@ func proveAndVerify(t *testing.T, ccs constraint.ConstraintSystem, pk groth16.Pr
 // generates a proof, and verifies it.
 func TestFSPCircuitEndToEnd(t *testing.T) {
 	// 1. Compile
-	ccs, err := setup.CompileCircuit(&fsp.FSPCircuit{})
+	ccs, err := setup.CompileCircuit(fsp.NewFSPCircuit(fsp.MaxTreeDepth))
 	if err != nil {
 		t.Fatalf("compile circuit: %v", err)
 	}
@@ -95,7 +95,7 @@ func TestFSPCircuitEndToEnd(t *testing.T) {
 
 // TestFSPMultipleFileSizes verifies the circuit works for various file sizes.
 func TestFSPMultipleFileSizes(t *testing.T) {
-	ccs, err := setup.CompileCircuit(&fsp.FSPCircuit{})
+	ccs, err := setup.CompileCircuit(fsp.NewFSPCircuit(fsp.MaxTreeDepth))
 	if err != nil {
 		t.Fatalf("compile circuit: %v", err)
 	}
@@ -141,7 +141,7 @@ func TestFSPMultipleFileSizes(t *testing.T) {
 // that it round-trips through JSON.
 func TestFSPExportFixture(t *testing.T) {
 	// 1. Compile and dev setup
-	ccs, err := setup.CompileCircuit(&fsp.FSPCircuit{})
+	ccs, err := setup.CompileCircuit(fsp.NewFSPCircuit(fsp.MaxTreeDepth))
 	if err != nil {
 		t.Fatalf("compile circuit: %v", err)
 	}
@@ -192,3 +192,72 @@ func TestFSPExportFixture(t *testing.T) {
 
 	fmt.Println("Fixture round-trip OK")
 }
+
+// TestFSPDeepTrees checks depth selection and solves the depth-24 and
+// depth-28 circuits for a small file.
+func TestFSPDeepTrees(t *testing.T) {
+	depthCases := []struct {
+		numChunks int
+		depth     int
+	}{
+		{1, 20},
+		{fsp.TotalLeaves, 20},
+		{fsp.TotalLeaves + 1, 24},
+		{1 << 24, 24},
+		{1<<24 + 1, 28},
+	}
+	for _, tc := range depthCases {
+		d, err := fsp.DepthForChunks(tc.numChunks)
+		if err != nil {
+			t.Fatalf("DepthForChunks(%d): %v", tc.numChunks, err)
+		}
+		if d != tc.depth {
+			t.Fatalf("DepthForChunks(%d) = %d, expected %d", tc.numChunks, d, tc.depth)
+		}
+	}
+	if _, err := fsp.DepthForChunks(1<<28 + 1); err == nil {
+		t.Fatal("expected error above the largest supported depth")
+	}
+
+	data := make([]byte, 3*fsp.FileSize)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatalf("generate random data: %v", err)
+	}
+	chunks := merkle.SplitIntoChunks(data, fsp.FileSize)
+	zeroLeaf := crypto.ComputeZeroLeafHashFr(fsp.ElementSize, fsp.NumChunks)
+
+	for _, depth := range []int{24, 28} {
+		t.Run(fmt.Sprintf("depth_%d", depth), func(t *testing.T) {
+			smt, err := merkle.GenerateSparseMerkleTree(chunks, depth, fsp.HashChunk, zeroLeaf)
+			if err != nil {
+				t.Fatalf("build SMT: %v", err)
+			}
+			result, err := fsp.PrepareWitness(smt)
+			if err != nil {
+				t.Fatalf("prepare witness: %v", err)
+			}
+
+			ccs, err := setup.CompileCircuit(fsp.NewFSPCircuit(depth))
+			if err != nil {
+				t.Fatalf("compile circuit: %v", err)
+			}
+			witness, err := frontend.NewWitness(&result.Assignment, ecc.BN254.ScalarField())
+			if err != nil {
+				t.Fatalf("create witness: %v", err)
+			}
+			if err := ccs.IsSolved(witness); err != nil {
+				t.Fatalf("circuit not solved: %v", err)
+			}
+
+			// Claiming fewer chunks than the tree holds must fail.
+			result.Assignment.NumChunks = 2
+			short, err := frontend.NewWitness(&result.Assignment, ecc.BN254.ScalarField())
+			if err != nil {
+				t.Fatalf("create witness: %v", err)
+			}
+			if err := ccs.IsSolved(short); err == nil {
+				t.Fatal("expected wrong numChunks to be rejected")
+			}
+		})
+	}
+}
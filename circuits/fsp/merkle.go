@@ -7,21 +7,21 @@ import (
 )
 
 // BoundaryMerkleProof is a lightweight sub-circuit for boundary validation.
-// It takes a pre-computed LeafHash (no byte array) and verifies a depth-20
-// Merkle path, returning the computed root for the caller to check.
+// It takes a pre-computed LeafHash (no byte array) and verifies a Merkle path
+// of depth len(ProofPath), returning the computed root for the caller to check.
 type BoundaryMerkleProof struct {
-	LeafHash   frontend.Variable                `gnark:"leafHash"`
-	ProofPath  [MaxTreeDepth]frontend.Variable   `gnark:"proofPath"`
-	Directions [MaxTreeDepth]frontend.Variable   `gnark:"directions"`
+	LeafHash   frontend.Variable   `gnark:"leafHash"`
+	ProofPath  []frontend.Variable `gnark:"proofPath"`
+	Directions []frontend.Variable `gnark:"directions"`
 }
 
-// ComputeRoot hashes through all MaxTreeDepth levels and returns the computed
-// root. The caller is responsible for comparing it to the expected root (with
-// optional guarding for the isFull edge case).
+// ComputeRoot hashes through all levels and returns the computed root. The
+// caller is responsible for comparing it to the expected root (with optional
+// guarding for the isFull edge case).
 func (bp *BoundaryMerkleProof) ComputeRoot(api frontend.API, sponge *shared.SpongeHasher) (frontend.Variable, error) {
 	currentHash := bp.LeafHash
 
-	for i := 0; i < MaxTreeDepth; i++ {
+	for i := range bp.ProofPath {
 		sibling := bp.ProofPath[i]
 		direction := bp.Directions[i]
 
@@ -16,15 +16,19 @@ type WitnessResult struct {
 }
 
 // PrepareWitness derives all public and private witness values from a sparse
-// Merkle tree and returns a ready-to-use circuit assignment.
+// Merkle tree and returns a ready-to-use circuit assignment. The circuit depth
+// is taken from smt.Depth, which must be one of SupportedDepths.
 func PrepareWitness(smt *merkle.SparseMerkleTree) (*WitnessResult, error) {
 	if smt.NumLeaves == 0 {
 		return nil, fmt.Errorf("sparse merkle tree has no leaves")
 	}
+	if err := validateDepth(smt.Depth); err != nil {
+		return nil, err
+	}
 
 	numLeaves := smt.NumLeaves
 
-	var assignment FSPCircuit
+	assignment := *NewFSPCircuit(smt.Depth)
 	assignment.RootHash = smt.Root
 	assignment.NumChunks = numLeaves
 
@@ -42,9 +46,9 @@ func prepareBoundaryProof(smt *merkle.SparseMerkleTree, leafIndex int) BoundaryM
 	siblings, directions := smt.GetProof(leafIndex)
 	leafHash := smt.GetLeafHash(leafIndex)
 
-	var proofPath [MaxTreeDepth]frontend.Variable
-	var proofDirections [MaxTreeDepth]frontend.Variable
-	for i := 0; i < MaxTreeDepth; i++ {
+	proofPath := make([]frontend.Variable, smt.Depth)
+	proofDirections := make([]frontend.Variable, smt.Depth)
+	for i := 0; i < smt.Depth; i++ {
 		proofPath[i] = siblings[i]
 		proofDirections[i] = directions[i]
 	}
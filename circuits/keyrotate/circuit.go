@@ -0,0 +1,62 @@
+package keyrotate
+
+import (
+	"github.com/MuriData/muri-zkproof/circuits/shared"
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/consensys/gnark/frontend"
+)
+
+// KeyRotateCircuit proves knowledge of the secret keys behind both a
+// registered public key and its replacement, without revealing either secret
+// key on-chain. Proving knowledge of SecretKeyNew stops a rotation to a
+// public key nobody controls (or someone else's). The contract moves stake
+// and storage obligations from PublicKeyOld to PublicKeyNew in a single
+// transaction.
+type KeyRotateCircuit struct {
+	// Public inputs
+	PublicKeyOld frontend.Variable `gnark:"publicKeyOld,public"`
+	PublicKeyNew frontend.Variable `gnark:"publicKeyNew,public"`
+	Nonce        frontend.Variable `gnark:"nonce,public"`
+
+	// Private witness
+	SecretKeyOld frontend.Variable `gnark:"secretKeyOld"`
+	SecretKeyNew frontend.Variable `gnark:"secretKeyNew"`
+}
+
+func (circuit *KeyRotateCircuit) Define(api frontend.API) error {
+	sponge, err := shared.NewSpongeHasher(api)
+	if err != nil {
+		return err
+	}
+
+	// 1. Both secret keys must be non-zero (a zero key is trivially known).
+	api.AssertIsEqual(api.IsZero(circuit.SecretKeyOld), 0)
+	api.AssertIsEqual(api.IsZero(circuit.SecretKeyNew), 0)
+
+	// 2. Both public keys must be non-zero (a zero public key bypasses
+	//    identity checks) and distinct (rotating to the same key is a no-op).
+	api.AssertIsEqual(api.IsZero(circuit.PublicKeyOld), 0)
+	api.AssertIsEqual(api.IsZero(circuit.PublicKeyNew), 0)
+	api.AssertIsEqual(api.IsZero(api.Sub(circuit.PublicKeyNew, circuit.PublicKeyOld)), 0)
+
+	// 3. Key ownership: publicKeyOld == H(secretKeyOld) and
+	//    publicKeyNew == H(secretKeyNew).
+	derivedPubKeyOld, err := sponge.Hash(frontend.Variable(crypto.DomainTagPubKey), circuit.SecretKeyOld)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(circuit.PublicKeyOld, derivedPubKeyOld)
+
+	derivedPubKeyNew, err := sponge.Hash(frontend.Variable(crypto.DomainTagPubKey), circuit.SecretKeyNew)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(circuit.PublicKeyNew, derivedPubKeyNew)
+
+	// Nonce is a public input with no constraint — the contract checks it
+	// against the rotation counter of publicKeyOld, so a proof cannot be
+	// replayed to rotate back to an earlier key.
+	_ = circuit.Nonce
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package keyrotate_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/MuriData/muri-zkproof/circuits/keyrotate"
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/MuriData/muri-zkproof/pkg/setup"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/plonk"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/test/unsafekzg"
+)
+
+// TestKeyRotateCircuitEndToEnd compiles the circuit with SCS, performs an
+// unsafe PLONK setup, generates a proof, and verifies it.
+func TestKeyRotateCircuitEndToEnd(t *testing.T) {
+	// 1. Compile (SCS for PLONK)
+	ccs, err := setup.CompileCircuitForBackend(&keyrotate.KeyRotateCircuit{}, setup.PlonkBackend)
+	if err != nil {
+		t.Fatalf("compile circuit: %v", err)
+	}
+
+	// 2. Generate unsafe KZG SRS and run PLONK setup
+	srs, srsLagrange, err := unsafekzg.NewSRS(ccs)
+	if err != nil {
+		t.Fatalf("generate SRS: %v", err)
+	}
+
+	pk, vk, err := plonk.Setup(ccs, srs, srsLagrange)
+	if err != nil {
+		t.Fatalf("plonk setup: %v", err)
+	}
+
+	// 3. Generate old and new key pairs
+	secretKeyOld, err := crypto.GenerateSecretKey()
+	if err != nil {
+		t.Fatalf("generate secret key: %v", err)
+	}
+	secretKeyNew, err := crypto.GenerateSecretKey()
+	if err != nil {
+		t.Fatalf("generate secret key: %v", err)
+	}
+	publicKeyOld := crypto.DerivePublicKey(secretKeyOld)
+	publicKeyNew := crypto.DerivePublicKey(secretKeyNew)
+	nonce := big.NewInt(7)
+
+	t.Logf("Old public key: 0x%064x", publicKeyOld)
+	t.Logf("New public key: 0x%064x", publicKeyNew)
+
+	// 4. Build witness
+	assignment := keyrotate.KeyRotateCircuit{
+		PublicKeyOld: publicKeyOld,
+		PublicKeyNew: publicKeyNew,
+		Nonce:        nonce,
+		SecretKeyOld: secretKeyOld,
+		SecretKeyNew: secretKeyNew,
+	}
+
+	witness, err := frontend.NewWitness(&assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatalf("create witness: %v", err)
+	}
+
+	publicWitness, err := witness.Public()
+	if err != nil {
+		t.Fatalf("extract public witness: %v", err)
+	}
+
+	// 5. Prove
+	proof, err := plonk.Prove(ccs, pk, witness)
+	if err != nil {
+		t.Fatalf("prove: %v", err)
+	}
+
+	// 6. Verify
+	err = plonk.Verify(proof, vk, publicWitness)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	// 7. The proof must not verify for a different new key or nonce.
+	for name, tampered := range map[string]keyrotate.KeyRotateCircuit{
+		"publicKeyNew": {PublicKeyOld: publicKeyOld, PublicKeyNew: crypto.DerivePublicKey(big.NewInt(1)), Nonce: nonce},
+		"nonce":        {PublicKeyOld: publicKeyOld, PublicKeyNew: publicKeyNew, Nonce: big.NewInt(8)},
+	} {
+		tamperedPublic, err := frontend.NewWitness(&tampered, ecc.BN254.ScalarField(), frontend.PublicOnly())
+		if err != nil {
+			t.Fatalf("create public witness: %v", err)
+		}
+		if err := plonk.Verify(proof, vk, tamperedPublic); err == nil {
+			t.Fatalf("expected proof to be rejected with a different %s", name)
+		}
+	}
+
+	t.Log("PLONK keyrotate proof verified successfully!")
+}
+
+// TestKeyRotateRejectsInvalidWitness checks the constraints on the old and
+// new keys.
+func TestKeyRotateRejectsInvalidWitness(t *testing.T) {
+	ccs, err := setup.CompileCircuitForBackend(&keyrotate.KeyRotateCircuit{}, setup.PlonkBackend)
+	if err != nil {
+		t.Fatalf("compile circuit: %v", err)
+	}
+
+	secretKeyOld := big.NewInt(12345)
+	publicKeyOld := crypto.DerivePublicKey(secretKeyOld)
+	secretKeyNew := big.NewInt(67890)
+	publicKeyNew := crypto.DerivePublicKey(secretKeyNew)
+
+	valid := keyrotate.KeyRotateCircuit{PublicKeyOld: publicKeyOld, PublicKeyNew: publicKeyNew, Nonce: 1, SecretKeyOld: secretKeyOld, SecretKeyNew: secretKeyNew}
+	witness, err := frontend.NewWitness(&valid, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatalf("create witness: %v", err)
+	}
+	if err := ccs.IsSolved(witness); err != nil {
+		t.Fatalf("valid witness rejected: %v", err)
+	}
+
+	cases := map[string]keyrotate.KeyRotateCircuit{
+		"wrong secret key":     {PublicKeyOld: publicKeyOld, PublicKeyNew: publicKeyNew, Nonce: 1, SecretKeyOld: big.NewInt(12346), SecretKeyNew: secretKeyNew},
+		"zero new key":         {PublicKeyOld: publicKeyOld, PublicKeyNew: 0, Nonce: 1, SecretKeyOld: secretKeyOld, SecretKeyNew: secretKeyNew},
+		"unchanged key":        {PublicKeyOld: publicKeyOld, PublicKeyNew: publicKeyOld, Nonce: 1, SecretKeyOld: secretKeyOld, SecretKeyNew: secretKeyOld},
+		"zero secret key":      {PublicKeyOld: crypto.DerivePublicKey(big.NewInt(0)), PublicKeyNew: publicKeyNew, Nonce: 1, SecretKeyOld: 0, SecretKeyNew: secretKeyNew},
+		"wrong new secret key": {PublicKeyOld: publicKeyOld, PublicKeyNew: publicKeyNew, Nonce: 1, SecretKeyOld: secretKeyOld, SecretKeyNew: big.NewInt(67891)},
+		"unowned new key":      {PublicKeyOld: publicKeyOld, PublicKeyNew: crypto.DerivePublicKey(big.NewInt(1)), Nonce: 1, SecretKeyOld: secretKeyOld, SecretKeyNew: secretKeyNew},
+		"zero new secret key":  {PublicKeyOld: publicKeyOld, PublicKeyNew: crypto.DerivePublicKey(big.NewInt(0)), Nonce: 1, SecretKeyOld: secretKeyOld, SecretKeyNew: 0},
+	}
+	for name, assignment := range cases {
+		witness, err := frontend.NewWitness(&assignment, ecc.BN254.ScalarField())
+		if err != nil {
+			t.Fatalf("%s: create witness: %v", name, err)
+		}
+		if err := ccs.IsSolved(witness); err == nil {
+			t.Fatalf("%s: expected witness to be rejected", name)
+		}
+	}
+}
+
+// TestKeyRotateExportFixture generates a deterministic fixture and verifies
+// that it round-trips through JSON.
+func TestKeyRotateExportFixture(t *testing.T) {
+	// 1. Compile and dev setup
+	ccs, err := setup.CompileCircuitForBackend(&keyrotate.KeyRotateCircuit{}, setup.PlonkBackend)
+	if err != nil {
+		t.Fatalf("compile circuit: %v", err)
+	}
+
+	srs, srsLagrange, err := unsafekzg.NewSRS(ccs)
+	if err != nil {
+		t.Fatalf("generate SRS: %v", err)
+	}
+
+	pk, vk, err := plonk.Setup(ccs, srs, srsLagrange)
+	if err != nil {
+		t.Fatalf("plonk setup: %v", err)
+	}
+
+	// 2. Write keys to temp directory
+	tmpDir := t.TempDir()
+	if err := setup.ExportPlonkKeys(pk, vk, tmpDir, "keyrotate"); err != nil {
+		t.Fatalf("export keys: %v", err)
+	}
+
+	// 3. Generate fixture
+	jsonOut, err := keyrotate.ExportProofFixture(tmpDir)
+	if err != nil {
+		t.Fatalf("export proof fixture: %v", err)
+	}
+
+	// 4. Verify JSON round-trips
+	var fixture keyrotate.ProofFixture
+	if err := json.Unmarshal(jsonOut, &fixture); err != nil {
+		t.Fatalf("unmarshal fixture: %v", err)
+	}
+
+	if fixture.SolidityProof == "" {
+		t.Fatal("fixture solidity_proof is empty")
+	}
+	if fixture.PublicKeyOld == "" || fixture.PublicKeyNew == "" {
+		t.Fatal("fixture public keys are empty")
+	}
+	if fixture.Nonce == "" {
+		t.Fatal("fixture nonce is empty")
+	}
+
+	jsonRoundTrip, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		t.Fatalf("re-marshal fixture: %v", err)
+	}
+	if string(jsonRoundTrip) != string(jsonOut) {
+		t.Fatal("fixture JSON round-trip mismatch")
+	}
+
+	fmt.Println("Keyrotate fixture round-trip OK")
+}
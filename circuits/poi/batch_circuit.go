@@ -0,0 +1,195 @@
+package poi
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/MuriData/muri-zkproof/circuits/shared"
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/bits"
+	"github.com/consensys/gnark/std/math/cmp"
+)
+
+// BatchPoICircuit proves storage of BatchFileCount files in a single proof.
+// Opening k of Params.OpeningsCount targets file k mod BatchFileCount, so
+// every file in the batch is challenged OpeningsCount/BatchFileCount times.
+// Because windows of one randomness value cannot cover that many openings,
+// the raw index of opening k is always the low ChallengeWindowBits of
+// H(DomainTagChallengeIdx, randomness, k), reduced modulo that file's
+// numLeaves; the window is wide enough for every supported tree depth.
+//
+// All files share Params.MaxTreeDepth. A node with fewer than BatchFileCount
+// files may repeat a file. The private arrays are slices sized from Params,
+// so instances must be built with NewBatchPoICircuit.
+type BatchPoICircuit struct {
+	// Public inputs (3 + 2*BatchFileCount): commitment, randomness,
+	// publicKey, rootHashes[], numLeaves[]
+	Commitment frontend.Variable                 `gnark:"commitment,public"`
+	Randomness frontend.Variable                 `gnark:"randomness,public"`
+	PublicKey  frontend.Variable                 `gnark:"publicKey,public"`
+	RootHashes [BatchFileCount]frontend.Variable `gnark:"rootHashes,public"`
+	NumLeaves  [BatchFileCount]frontend.Variable `gnark:"numLeaves,public"`
+
+	// Private inputs
+	SecretKey    frontend.Variable     `gnark:"secretKey"`
+	Bytes        [][]frontend.Variable `gnark:"bytes"`
+	MerkleProofs []MerkleProofCircuit  `gnark:"merkleProofs"`
+	Quotients    []frontend.Variable   `gnark:"quotients"`
+	LeafIndices  []frontend.Variable   `gnark:"leafIndices"`
+
+	// Params fixes the circuit shape; it is not part of the witness.
+	Params Params `gnark:"-"`
+}
+
+// NewBatchPoICircuit allocates a BatchPoICircuit whose slices are sized
+// from p. p.OpeningsCount is the total over all BatchFileCount files.
+func NewBatchPoICircuit(p Params) *BatchPoICircuit {
+	c := &BatchPoICircuit{
+		Bytes:        make([][]frontend.Variable, p.OpeningsCount),
+		MerkleProofs: make([]MerkleProofCircuit, p.OpeningsCount),
+		Quotients:    make([]frontend.Variable, p.OpeningsCount),
+		LeafIndices:  make([]frontend.Variable, p.OpeningsCount),
+		Params:       p,
+	}
+	for k := 0; k < p.OpeningsCount; k++ {
+		c.Bytes[k] = make([]frontend.Variable, p.NumChunks())
+		c.MerkleProofs[k] = NewMerkleProofCircuit(p.MaxTreeDepth)
+	}
+	return c
+}
+
+// checkShape verifies that the circuit slices match Params.
+func (circuit *BatchPoICircuit) checkShape() error {
+	p := circuit.Params
+	if err := p.ValidateBatch(); err != nil {
+		return fmt.Errorf("invalid batch PoI params (use NewBatchPoICircuit): %w", err)
+	}
+	if len(circuit.Bytes) != p.OpeningsCount || len(circuit.MerkleProofs) != p.OpeningsCount ||
+		len(circuit.Quotients) != p.OpeningsCount || len(circuit.LeafIndices) != p.OpeningsCount {
+		return fmt.Errorf("circuit slices do not match %d openings", p.OpeningsCount)
+	}
+	for k := 0; k < p.OpeningsCount; k++ {
+		if len(circuit.Bytes[k]) != p.NumChunks() {
+			return fmt.Errorf("opening %d: %d byte elements, expected %d", k, len(circuit.Bytes[k]), p.NumChunks())
+		}
+		if len(circuit.MerkleProofs[k].ProofPath) != p.MaxTreeDepth || len(circuit.MerkleProofs[k].Directions) != p.MaxTreeDepth {
+			return fmt.Errorf("opening %d: merkle proof depth does not match %d", k, p.MaxTreeDepth)
+		}
+	}
+	return nil
+}
+
+func (circuit *BatchPoICircuit) Define(api frontend.API) error {
+	if err := circuit.checkShape(); err != nil {
+		return err
+	}
+	p := circuit.Params
+
+	sponge, err := shared.NewSpongeHasher(api)
+	if err != nil {
+		return err
+	}
+
+	// ---------------------------------------------------------------
+	// 1. Key ownership: publicKey == H(secretKey), both non-zero.
+	// ---------------------------------------------------------------
+	api.AssertIsEqual(api.IsZero(circuit.SecretKey), 0)
+	api.AssertIsEqual(api.IsZero(circuit.PublicKey), 0)
+
+	derivedPubKey, err := sponge.Hash(frontend.Variable(crypto.DomainTagPubKey), circuit.SecretKey)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(circuit.PublicKey, derivedPubKey)
+
+	api.AssertIsEqual(api.IsZero(circuit.Randomness), 0)
+
+	// ---------------------------------------------------------------
+	// 2. NumLeaves validation per file: numLeaves in [1, TotalLeaves].
+	// ---------------------------------------------------------------
+	for f := 0; f < BatchFileCount; f++ {
+		api.AssertIsEqual(api.IsZero(circuit.NumLeaves[f]), 0)
+		api.AssertIsLessOrEqual(circuit.NumLeaves[f], p.TotalLeaves())
+	}
+
+	// ---------------------------------------------------------------
+	// 3. Bounded comparator for leafIndex < numLeaves checks.
+	// ---------------------------------------------------------------
+	comparator := cmp.NewBoundedComparator(api, new(big.Int).SetInt64(int64(p.TotalLeaves())+1), false)
+
+	// ---------------------------------------------------------------
+	// 4. Per-opening: challenge index, leaf hash, Merkle proof.
+	// ---------------------------------------------------------------
+	leafHashes := make([]frontend.Variable, p.OpeningsCount)
+
+	for k := 0; k < p.OpeningsCount; k++ {
+		f := k % BatchFileCount
+
+		// 4a. Raw index: low ChallengeWindowBits of H(randomness, k).
+		challenge, err := sponge.Hash(frontend.Variable(crypto.DomainTagChallengeIdx), circuit.Randomness, k)
+		if err != nil {
+			return err
+		}
+		challengeBits := api.ToBinary(challenge, api.Compiler().FieldBitLen())
+		rawIndex := bits.FromBinary(api, challengeBits[:ChallengeWindowBits], bits.WithUnconstrainedInputs())
+
+		// 4b. Modular reduction: quotient * numLeaves[f] + leafIndex == rawIndex.
+		// Range check: quotient fits in ChallengeWindowBits.
+		api.ToBinary(circuit.Quotients[k], ChallengeWindowBits)
+		product := api.Mul(circuit.Quotients[k], circuit.NumLeaves[f])
+		sum := api.Add(product, circuit.LeafIndices[k])
+		api.AssertIsEqual(sum, rawIndex)
+
+		// Range check: leafIndex < numLeaves[f].
+		comparator.AssertIsLess(circuit.LeafIndices[k], circuit.NumLeaves[f])
+
+		// 4c. Compute domain-tagged leaf hash: sponge(DomainTagReal, bytes[k][0..528]).
+		leafHash, err := sponge.Hash(frontend.Variable(crypto.DomainTagReal), circuit.Bytes[k][:]...)
+		if err != nil {
+			return err
+		}
+		leafHashes[k] = leafHash
+
+		// 4d. Link leaf hash and file root hash to sub-circuit.
+		api.AssertIsEqual(circuit.MerkleProofs[k].LeafValue, leafHashes[k])
+		api.AssertIsEqual(circuit.MerkleProofs[k].RootHash, circuit.RootHashes[f])
+
+		// 4e. Direction enforcement from LeafIndex bits.
+		leafBits := api.ToBinary(circuit.LeafIndices[k], p.MaxTreeDepth)
+		for j := 0; j < p.MaxTreeDepth; j++ {
+			api.AssertIsEqual(circuit.MerkleProofs[k].Directions[j], leafBits[j])
+		}
+
+		// 4f. Verify Merkle proof (all MaxTreeDepth levels, no skip).
+		if err := circuit.MerkleProofs[k].Define(api, sponge); err != nil {
+			return err
+		}
+	}
+
+	// ---------------------------------------------------------------
+	// 5. Aggregate message: aggMsg = H(leafHash[0], ..., leafHash[K-1], randomness).
+	// ---------------------------------------------------------------
+	aggInputs := make([]frontend.Variable, p.OpeningsCount+1)
+	copy(aggInputs, leafHashes)
+	aggInputs[p.OpeningsCount] = circuit.Randomness
+	aggMsg, err := sponge.Hash(frontend.Variable(crypto.DomainTagAggMsg), aggInputs...)
+	if err != nil {
+		return err
+	}
+
+	// ---------------------------------------------------------------
+	// 6. VRF commitment: commitment = H(secretKey, aggMsg, randomness, publicKey).
+	// ---------------------------------------------------------------
+	derivedCommitment, err := sponge.Hash(
+		frontend.Variable(crypto.DomainTagCommitment),
+		circuit.SecretKey, aggMsg, circuit.Randomness, circuit.PublicKey,
+	)
+	if err != nil {
+		return err
+	}
+
+	api.AssertIsEqual(circuit.Commitment, derivedCommitment)
+
+	return nil
+}
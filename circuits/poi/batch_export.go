@@ -0,0 +1,178 @@
+package poi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/MuriData/muri-zkproof/pkg/merkle"
+	"github.com/MuriData/muri-zkproof/pkg/setup"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/frontend"
+)
+
+// BatchProofFixture holds all values needed for Solidity batch PoI tests.
+type BatchProofFixture struct {
+	SolidityProof [8]string              `json:"solidity_proof"`
+	Randomness    string                 `json:"randomness"`
+	Commitment    string                 `json:"commitment"`
+	PublicKey     string                 `json:"public_key"`
+	RootHashes    [BatchFileCount]string `json:"root_hashes"`
+	NumLeaves     [BatchFileCount]string `json:"num_leaves"`
+}
+
+// ExportBatchProofFixture generates a deterministic batch proof fixture for
+// Solidity tests. keysDir is the directory containing the proving and
+// verifying keys.
+func ExportBatchProofFixture(keysDir string) ([]byte, error) {
+	return ExportBatchProofFixtureWithParams(keysDir, "poi_batch", DefaultBatchParams)
+}
+
+// ExportBatchProofFixtureWithParams generates a deterministic batch proof
+// fixture for the parameter set p, loading keys stored under circuitName in
+// keysDir.
+func ExportBatchProofFixtureWithParams(keysDir, circuitName string, p Params) ([]byte, error) {
+	// 1. Compile the circuit
+	fmt.Printf("Compiling circuit (%s)...\n", p.BatchName())
+	ccs, err := setup.CompileCircuit(NewBatchPoICircuit(p))
+	if err != nil {
+		return nil, fmt.Errorf("compile circuit: %w", err)
+	}
+
+	// 2. Load proving and verifying keys
+	fmt.Println("Loading keys...")
+	pk, vk, err := setup.LoadKeys(keysDir, circuitName)
+	if err != nil {
+		return nil, fmt.Errorf("load keys: %w", err)
+	}
+
+	// 3. Create BatchFileCount deterministic files of 1, 2, 4, ... chunks.
+	files := make([]BatchFile, BatchFileCount)
+	for f := range files {
+		fileData := make([]byte, (1<<f)*p.FileSize)
+		for i := range fileData {
+			fileData[i] = byte((i + f) % 256)
+		}
+		chunks := merkle.SplitIntoChunks(fileData, p.FileSize)
+		smt, err := merkle.GenerateSparseMerkleTree(chunks, p.MaxTreeDepth, p.HashChunk, p.ZeroLeafHash())
+		if err != nil {
+			return nil, fmt.Errorf("build file %d SMT: %w", f, err)
+		}
+		files[f] = BatchFile{Chunks: chunks, Tree: smt}
+		fmt.Printf("File %d: %d chunks, root 0x%x\n", f, smt.NumLeaves, smt.Root.Bytes())
+	}
+
+	// 4. Deterministic randomness and secret key
+	randomness := new(big.Int).SetUint64(42)
+	secretKey := new(big.Int).SetUint64(12345)
+
+	result, err := PrepareBatchWitnessWithParams(p, secretKey, randomness, files)
+	if err != nil {
+		return nil, fmt.Errorf("prepare witness: %w", err)
+	}
+	fmt.Printf("Selected chunk indices: %v\n", result.ChunkIndices)
+	fmt.Printf("Public key (H(sk)): 0x%064x\n", result.PublicKey)
+	fmt.Printf("Commitment: 0x%064x\n", result.Commitment)
+
+	// 5. Create witness and generate proof
+	witness, err := frontend.NewWitness(&result.Assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		return nil, fmt.Errorf("create witness: %w", err)
+	}
+
+	publicWitness, err := witness.Public()
+	if err != nil {
+		return nil, fmt.Errorf("extract public witness: %w", err)
+	}
+
+	fmt.Println("Generating proof...")
+	proof, err := groth16.Prove(ccs, pk, witness)
+	if err != nil {
+		return nil, fmt.Errorf("prove: %w", err)
+	}
+
+	// 6. Verify proof in Go
+	err = groth16.Verify(proof, vk, publicWitness)
+	if err != nil {
+		return nil, fmt.Errorf("verify: %w", err)
+	}
+	fmt.Println("Proof verified successfully in Go!")
+
+	// 7. Extract proof points for Solidity
+	bn254Proof := proof.(*groth16bn254.Proof)
+
+	aX := new(big.Int)
+	aY := new(big.Int)
+	bn254Proof.Ar.X.BigInt(aX)
+	bn254Proof.Ar.Y.BigInt(aY)
+
+	bX0 := new(big.Int)
+	bX1 := new(big.Int)
+	bY0 := new(big.Int)
+	bY1 := new(big.Int)
+	bn254Proof.Bs.X.A0.BigInt(bX0)
+	bn254Proof.Bs.X.A1.BigInt(bX1)
+	bn254Proof.Bs.Y.A0.BigInt(bY0)
+	bn254Proof.Bs.Y.A1.BigInt(bY1)
+
+	cX := new(big.Int)
+	cY := new(big.Int)
+	bn254Proof.Krs.X.BigInt(cX)
+	bn254Proof.Krs.Y.BigInt(cY)
+
+	// Solidity format: [A.x, A.y, B.x1, B.x0, B.y1, B.y0, C.x, C.y]
+	solidityProof := [8]*big.Int{aX, aY, bX1, bX0, bY1, bY0, cX, cY}
+
+	fixture := BatchProofFixture{
+		Randomness: fmt.Sprintf("0x%064x", randomness),
+		Commitment: fmt.Sprintf("0x%064x", result.Commitment),
+		PublicKey:  fmt.Sprintf("0x%064x", result.PublicKey),
+	}
+	for f := range files {
+		fixture.RootHashes[f] = fmt.Sprintf("0x%064x", files[f].Tree.RootBigInt())
+		fixture.NumLeaves[f] = fmt.Sprintf("0x%064x", big.NewInt(int64(result.NumLeaves[f])))
+	}
+	for i := 0; i < 8; i++ {
+		fixture.SolidityProof[i] = fmt.Sprintf("0x%064x", solidityProof[i])
+	}
+
+	jsonOut, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal fixture: %w", err)
+	}
+
+	// Print diagnostic info
+	fmt.Println("\n=== PROOF FIXTURE (JSON) ===")
+	fmt.Println(string(jsonOut))
+
+	fmt.Println("\n=== SOLIDITY CONSTANTS ===")
+	fmt.Printf("    // Public inputs\n")
+	fmt.Printf("    uint256 constant BATCH_RANDOMNESS = %s;\n", fixture.Randomness)
+	fmt.Printf("    bytes32 constant BATCH_COMMITMENT = bytes32(%s);\n", fixture.Commitment)
+	fmt.Printf("    uint256 constant BATCH_PUB_KEY = %s;\n", fixture.PublicKey)
+	for f := 0; f < BatchFileCount; f++ {
+		fmt.Printf("    uint256 constant BATCH_FILE_ROOT_%d = %s;\n", f, fixture.RootHashes[f])
+		fmt.Printf("    uint256 constant BATCH_NUM_LEAVES_%d = %s;\n", f, fixture.NumLeaves[f])
+	}
+	fmt.Println()
+	fmt.Printf("    // Proof (uint256[8])\n")
+	for i := 0; i < 8; i++ {
+		fmt.Printf("    uint256 constant BATCH_PROOF_%d = %s;\n", i, fixture.SolidityProof[i])
+	}
+
+	// Public witness info
+	fmt.Println("\n=== PUBLIC WITNESS ORDER ===")
+	fmt.Printf("In gnark circuit (= Solidity order): [commitment, randomness, publicKey, rootHashes[0..%d], numLeaves[0..%d]]\n",
+		BatchFileCount-1, BatchFileCount-1)
+	var pubWitBuf bytes.Buffer
+	_, err = publicWitness.WriteTo(&pubWitBuf)
+	if err != nil {
+		return nil, fmt.Errorf("write public witness: %w", err)
+	}
+	fmt.Printf("Public witness size: %d bytes\n", pubWitBuf.Len())
+
+	return jsonOut, nil
+}
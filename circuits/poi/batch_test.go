@@ -0,0 +1,242 @@
+package poi_test
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/MuriData/muri-zkproof/circuits/poi"
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/MuriData/muri-zkproof/pkg/merkle"
+	"github.com/MuriData/muri-zkproof/pkg/setup"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+)
+
+// buildBatch is a test helper that generates BatchFileCount random files
+// with the given chunk counts.
+func buildBatch(t *testing.T, chunkCounts [poi.BatchFileCount]int) []poi.BatchFile {
+	t.Helper()
+	files := make([]poi.BatchFile, poi.BatchFileCount)
+	for f, n := range chunkCounts {
+		data := make([]byte, n*poi.FileSize)
+		if _, err := rand.Read(data); err != nil {
+			t.Fatalf("generate random data: %v", err)
+		}
+		smt, chunks := buildSMT(t, data)
+		files[f] = poi.BatchFile{Chunks: chunks, Tree: smt}
+	}
+	return files
+}
+
+// TestBatchPoICircuitEndToEnd proves storage of BatchFileCount files of
+// different sizes with a single Groth16 proof.
+func TestBatchPoICircuitEndToEnd(t *testing.T) {
+	// 1. Compile
+	ccs, err := setup.CompileCircuit(poi.NewBatchPoICircuit(poi.DefaultBatchParams))
+	if err != nil {
+		t.Fatalf("compile circuit: %v", err)
+	}
+	t.Logf("Constraints: %d", ccs.GetNbConstraints())
+
+	// 2. Dev setup (single-party, not for production)
+	pk, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		t.Fatalf("groth16 setup: %v", err)
+	}
+
+	// 3. Random files, randomness and secret key
+	files := buildBatch(t, [poi.BatchFileCount]int{1, 3, 8, 5})
+	randomness, err := rand.Int(rand.Reader, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatalf("generate randomness: %v", err)
+	}
+	secretKey, err := crypto.GenerateSecretKey()
+	if err != nil {
+		t.Fatalf("generate secret key: %v", err)
+	}
+
+	// 4. Prepare witness
+	result, err := poi.PrepareBatchWitness(secretKey, randomness, files)
+	if err != nil {
+		t.Fatalf("prepare witness: %v", err)
+	}
+	t.Logf("Selected chunk indices: %v", result.ChunkIndices)
+	for k, idx := range result.ChunkIndices {
+		if idx >= result.NumLeaves[k%poi.BatchFileCount] {
+			t.Fatalf("opening %d: chunk index %d out of range", k, idx)
+		}
+	}
+
+	// 5. Prove and verify
+	witness, err := frontend.NewWitness(&result.Assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatalf("create witness: %v", err)
+	}
+	publicWitness, err := witness.Public()
+	if err != nil {
+		t.Fatalf("extract public witness: %v", err)
+	}
+	proof, err := groth16.Prove(ccs, pk, witness)
+	if err != nil {
+		t.Fatalf("prove: %v", err)
+	}
+	if err := groth16.Verify(proof, vk, publicWitness); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	// 6. Swapping two files' roots must invalidate the proof.
+	swapped := result.Assignment
+	swapped.RootHashes[0], swapped.RootHashes[1] = swapped.RootHashes[1], swapped.RootHashes[0]
+	swappedWitness, err := frontend.NewWitness(&swapped, ecc.BN254.ScalarField(), frontend.PublicOnly())
+	if err != nil {
+		t.Fatalf("create swapped witness: %v", err)
+	}
+	if err := groth16.Verify(proof, vk, swappedWitness); err == nil {
+		t.Fatal("expected verification to fail with swapped root hashes")
+	}
+	t.Log("ZK proof verified successfully!")
+}
+
+// TestBatchPoIRejectsWrongFileCount checks PrepareBatchWitness input
+// validation, including trees whose depth does not match the circuit.
+func TestBatchPoIRejectsWrongFileCount(t *testing.T) {
+	files := buildBatch(t, [poi.BatchFileCount]int{1, 1, 1, 1})
+	if _, err := poi.PrepareBatchWitness(big.NewInt(1), big.NewInt(1), files[:poi.BatchFileCount-1]); err == nil {
+		t.Fatal("expected error for short batch")
+	}
+	if _, err := poi.PrepareBatchWitnessWithParams(poi.Params{FileSize: poi.FileSize, MaxTreeDepth: poi.MaxTreeDepth, OpeningsCount: 6}, big.NewInt(1), big.NewInt(1), files); err == nil {
+		t.Fatal("expected error for openings not a multiple of the file count")
+	}
+	// Depth-20 trees must not be accepted by a deeper parameter set.
+	if _, err := poi.PrepareBatchWitnessWithParams(poi.BatchParamSets["poi_batch-d24-o16"], big.NewInt(1), big.NewInt(1), files); err == nil {
+		t.Fatal("expected depth mismatch error")
+	}
+	files[2].Chunks = files[2].Chunks[:0]
+	if _, err := poi.PrepareBatchWitness(big.NewInt(1), big.NewInt(1), files); err == nil {
+		t.Fatal("expected error for chunk/tree mismatch")
+	}
+}
+
+// TestBatchPoIExportFixture generates a deterministic batch fixture and
+// verifies that it round-trips through JSON.
+func TestBatchPoIExportFixture(t *testing.T) {
+	ccs, err := setup.CompileCircuit(poi.NewBatchPoICircuit(poi.DefaultBatchParams))
+	if err != nil {
+		t.Fatalf("compile circuit: %v", err)
+	}
+	pk, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		t.Fatalf("groth16 setup: %v", err)
+	}
+
+	tmpDir := t.TempDir()
+	if err := setup.ExportKeys(pk, vk, tmpDir, "poi_batch"); err != nil {
+		t.Fatalf("export keys: %v", err)
+	}
+
+	jsonOut, err := poi.ExportBatchProofFixture(tmpDir)
+	if err != nil {
+		t.Fatalf("export proof fixture: %v", err)
+	}
+
+	var fixture poi.BatchProofFixture
+	if err := json.Unmarshal(jsonOut, &fixture); err != nil {
+		t.Fatalf("unmarshal fixture: %v", err)
+	}
+	for f := 0; f < poi.BatchFileCount; f++ {
+		if fixture.RootHashes[f] == "" || fixture.NumLeaves[f] == "" {
+			t.Fatalf("fixture file %d is empty", f)
+		}
+	}
+	if fixture.Commitment == "" {
+		t.Fatal("fixture commitment is empty")
+	}
+
+	jsonRoundTrip, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		t.Fatalf("re-marshal fixture: %v", err)
+	}
+	if string(jsonRoundTrip) != string(jsonOut) {
+		t.Fatal("fixture JSON round-trip mismatch")
+	}
+}
+
+func TestBatchParamSets(t *testing.T) {
+	for name, p := range poi.BatchParamSets {
+		if err := p.ValidateBatch(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if p.BatchName() != name {
+			t.Fatalf("param set registered as %q but named %q", name, p.BatchName())
+		}
+	}
+	if poi.DefaultBatchParams.BatchName() != "poi_batch-d20-o16" {
+		t.Fatalf("unexpected default params name %q", poi.DefaultBatchParams.BatchName())
+	}
+	if _, err := setup.CompileCircuit(&poi.BatchPoICircuit{}); err == nil {
+		t.Fatal("expected compile error for unallocated circuit")
+	}
+}
+
+// TestBatchPoICustomParams solves a small non-default batch parameter set,
+// with files of several sizes at a non-default depth.
+func TestBatchPoICustomParams(t *testing.T) {
+	p := poi.Params{FileSize: 1024, MaxTreeDepth: 8, OpeningsCount: 8}
+	files := make([]poi.BatchFile, poi.BatchFileCount)
+	for f := range files {
+		data := make([]byte, (2*f+1)*p.FileSize)
+		if _, err := rand.Read(data); err != nil {
+			t.Fatalf("generate random data: %v", err)
+		}
+		chunks := merkle.SplitIntoChunks(data, p.FileSize)
+		smt, err := merkle.GenerateSparseMerkleTree(chunks, p.MaxTreeDepth, p.HashChunk, p.ZeroLeafHash())
+		if err != nil {
+			t.Fatalf("build SMT: %v", err)
+		}
+		files[f] = poi.BatchFile{Chunks: chunks, Tree: smt}
+	}
+	secretKey, err := crypto.GenerateSecretKey()
+	if err != nil {
+		t.Fatalf("generate secret key: %v", err)
+	}
+
+	result, err := poi.PrepareBatchWitnessWithParams(p, secretKey, big.NewInt(77), files)
+	if err != nil {
+		t.Fatalf("prepare witness: %v", err)
+	}
+	if len(result.ChunkIndices) != p.OpeningsCount {
+		t.Fatalf("got %d chunk indices, expected %d", len(result.ChunkIndices), p.OpeningsCount)
+	}
+
+	ccs, err := setup.CompileCircuit(poi.NewBatchPoICircuit(p))
+	if err != nil {
+		t.Fatalf("compile circuit: %v", err)
+	}
+	witness, err := frontend.NewWitness(&result.Assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatalf("create witness: %v", err)
+	}
+	if err := ccs.IsSolved(witness); err != nil {
+		t.Fatalf("circuit not solved: %v", err)
+	}
+
+	// Trees over the same chunks at another depth must be rejected.
+	for f := range files {
+		deeper, err := merkle.GenerateSparseMerkleTree(files[f].Chunks, p.MaxTreeDepth+1, p.HashChunk, p.ZeroLeafHash())
+		if err != nil {
+			t.Fatalf("build deeper SMT: %v", err)
+		}
+		files[f].Tree = deeper
+	}
+	_, err = poi.PrepareBatchWitnessWithParams(p, secretKey, big.NewInt(77), files)
+	if err == nil {
+		t.Fatal("expected depth mismatch error")
+	}
+	if !strings.Contains(err.Error(), "depth") {
+		t.Fatalf("expected depth mismatch error, got %v", err)
+	}
+}
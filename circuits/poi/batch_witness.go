@@ -0,0 +1,139 @@
+package poi
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/MuriData/muri-zkproof/pkg/field"
+	"github.com/MuriData/muri-zkproof/pkg/merkle"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/frontend"
+)
+
+// BatchFile is one file of a batch proof: its chunks and sparse Merkle tree.
+type BatchFile struct {
+	Chunks [][]byte
+	Tree   *merkle.SparseMerkleTree
+}
+
+// BatchWitnessResult holds the fully populated batch circuit assignment and
+// derived public values.
+type BatchWitnessResult struct {
+	Assignment   BatchPoICircuit
+	ChunkIndices []int // leafIndex within file k % BatchFileCount
+	NumLeaves    [BatchFileCount]int
+	PublicKey    *big.Int
+	Commitment   *big.Int
+	AggMsg       *big.Int
+}
+
+// PrepareBatchWitness derives all public and private witness values for a
+// batch proof over exactly BatchFileCount files, for DefaultBatchParams.
+//
+// Opening k targets files[k % BatchFileCount]; its raw index is the low
+// ChallengeWindowBits of DeriveChallengeIdx(randomness, k), reduced modulo
+// that file's numLeaves.
+func PrepareBatchWitness(secretKey, randomness *big.Int, files []BatchFile) (*BatchWitnessResult, error) {
+	return PrepareBatchWitnessWithParams(DefaultBatchParams, secretKey, randomness, files)
+}
+
+// PrepareBatchWitnessWithParams is PrepareBatchWitness for an arbitrary
+// batch parameter set. Every file tree must have depth p.MaxTreeDepth;
+// files too large for it need a deeper set such as "poi_batch-d24-o16".
+func PrepareBatchWitnessWithParams(p Params, secretKey, randomness *big.Int, files []BatchFile) (*BatchWitnessResult, error) {
+	if err := p.ValidateBatch(); err != nil {
+		return nil, err
+	}
+	if len(files) != BatchFileCount {
+		return nil, fmt.Errorf("batch has %d files, expected %d", len(files), BatchFileCount)
+	}
+	for f, file := range files {
+		if file.Tree == nil || file.Tree.NumLeaves == 0 {
+			return nil, fmt.Errorf("file %d: sparse merkle tree has no leaves", f)
+		}
+		if file.Tree.Depth != p.MaxTreeDepth {
+			return nil, fmt.Errorf("file %d: tree depth %d does not match circuit depth %d", f, file.Tree.Depth, p.MaxTreeDepth)
+		}
+		if file.Tree.NumLeaves > p.TotalLeaves() {
+			return nil, fmt.Errorf("file %d: numLeaves %d exceeds circuit capacity %d", f, file.Tree.NumLeaves, p.TotalLeaves())
+		}
+		if len(file.Chunks) != file.Tree.NumLeaves {
+			return nil, fmt.Errorf("file %d: chunk count %d does not match tree numLeaves %d", f, len(file.Chunks), file.Tree.NumLeaves)
+		}
+	}
+
+	publicKey := crypto.DerivePublicKey(secretKey)
+
+	assignment := NewBatchPoICircuit(p)
+	assignment.SecretKey = secretKey
+	assignment.Randomness = randomness
+	assignment.PublicKey = publicKey
+
+	var numLeaves [BatchFileCount]int
+	for f, file := range files {
+		numLeaves[f] = file.Tree.NumLeaves
+		assignment.RootHashes[f] = file.Tree.Root
+		assignment.NumLeaves[f] = file.Tree.NumLeaves
+	}
+
+	chunkIndices := make([]int, p.OpeningsCount)
+	leafHashesBig := make([]*big.Int, p.OpeningsCount)
+
+	mask := new(big.Int).Lsh(big.NewInt(1), ChallengeWindowBits)
+	mask.Sub(mask, big.NewInt(1))
+
+	for k := 0; k < p.OpeningsCount; k++ {
+		file := files[k%BatchFileCount]
+		smt := file.Tree
+
+		// Raw index from the low ChallengeWindowBits of H(randomness, k).
+		var challenge fr.Element
+		challenge.SetBigInt(crypto.DeriveChallengeIdx(randomness, big.NewInt(int64(k))))
+		rawIndex := new(big.Int)
+		challenge.BigInt(rawIndex)
+		rawIndex.And(rawIndex, mask)
+
+		// Modular reduction: leafIndex = rawIndex % numLeaves.
+		quotientBig, leafIndexBig := new(big.Int).DivMod(rawIndex, big.NewInt(int64(smt.NumLeaves)), new(big.Int))
+		leafIndex := int(leafIndexBig.Int64())
+		chunkIndices[k] = leafIndex
+
+		siblings, directions := smt.GetProof(leafIndex)
+		proofPath := make([]frontend.Variable, p.MaxTreeDepth)
+		proofDirections := make([]frontend.Variable, p.MaxTreeDepth)
+		for i := 0; i < p.MaxTreeDepth; i++ {
+			proofPath[i] = siblings[i]
+			proofDirections[i] = directions[i]
+		}
+
+		fieldSlice := field.Bytes2Field(file.Chunks[leafIndex], p.NumChunks(), ElementSize)
+		copy(assignment.Bytes[k], fieldSlice)
+
+		leafHash := smt.GetLeafHash(leafIndex)
+		assignment.Quotients[k] = quotientBig
+		assignment.LeafIndices[k] = leafIndexBig
+		assignment.MerkleProofs[k] = MerkleProofCircuit{
+			RootHash:   smt.Root,
+			LeafValue:  leafHash,
+			ProofPath:  proofPath,
+			Directions: proofDirections,
+		}
+
+		leafHashesBig[k] = new(big.Int)
+		leafHash.BigInt(leafHashesBig[k])
+	}
+
+	aggMsg := crypto.DeriveAggMsg(leafHashesBig, randomness)
+	commitment := crypto.DeriveCommitment(secretKey, aggMsg, randomness, publicKey)
+	assignment.Commitment = commitment
+
+	return &BatchWitnessResult{
+		Assignment:   *assignment,
+		ChunkIndices: chunkIndices,
+		NumLeaves:    numLeaves,
+		PublicKey:    publicKey,
+		Commitment:   commitment,
+		AggMsg:       aggMsg,
+	}, nil
+}
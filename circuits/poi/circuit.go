@@ -1,24 +1,19 @@
 package poi
 
 import (
+	"fmt"
 	"math/big"
 
 	"github.com/MuriData/muri-zkproof/circuits/shared"
 	"github.com/MuriData/muri-zkproof/pkg/crypto"
-	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
 	"github.com/consensys/gnark/frontend"
 	"github.com/consensys/gnark/std/math/bits"
 	"github.com/consensys/gnark/std/math/cmp"
 )
 
-// zeroLeafHash is the domain-separated hash for padding leaves, computed once
-// at package init. It is used as a circuit constant.
-var zeroLeafHash fr.Element
-
-func init() {
-	zeroLeafHash = crypto.ComputeZeroLeafHashFr(ElementSize, NumChunks)
-}
-
+// PoICircuit proves Params.OpeningsCount Merkle openings against a single
+// root. Its private arrays are slices sized from Params, so instances must
+// be built with NewPoICircuit (or PrepareWitness) rather than a zero value.
 type PoICircuit struct {
 	// Public inputs (5): commitment, randomness, publicKey, rootHash, numLeaves
 	Commitment frontend.Variable `gnark:"commitment,public"`
@@ -28,14 +23,59 @@ type PoICircuit struct {
 	NumLeaves  frontend.Variable `gnark:"numLeaves,public"`
 
 	// Private inputs
-	SecretKey    frontend.Variable                           `gnark:"secretKey"`
-	Bytes        [OpeningsCount][NumChunks]frontend.Variable `gnark:"bytes"`
-	MerkleProofs [OpeningsCount]MerkleProofCircuit           `gnark:"merkleProofs"`
-	Quotients    [OpeningsCount]frontend.Variable            `gnark:"quotients"`
-	LeafIndices  [OpeningsCount]frontend.Variable            `gnark:"leafIndices"`
+	SecretKey    frontend.Variable     `gnark:"secretKey"`
+	Bytes        [][]frontend.Variable `gnark:"bytes"`
+	MerkleProofs []MerkleProofCircuit  `gnark:"merkleProofs"`
+	Quotients    []frontend.Variable   `gnark:"quotients"`
+	LeafIndices  []frontend.Variable   `gnark:"leafIndices"`
+
+	// Params fixes the circuit shape; it is not part of the witness.
+	Params Params `gnark:"-"`
+}
+
+// NewPoICircuit allocates a PoICircuit whose slices are sized from p.
+func NewPoICircuit(p Params) *PoICircuit {
+	c := &PoICircuit{
+		Bytes:        make([][]frontend.Variable, p.OpeningsCount),
+		MerkleProofs: make([]MerkleProofCircuit, p.OpeningsCount),
+		Quotients:    make([]frontend.Variable, p.OpeningsCount),
+		LeafIndices:  make([]frontend.Variable, p.OpeningsCount),
+		Params:       p,
+	}
+	for k := 0; k < p.OpeningsCount; k++ {
+		c.Bytes[k] = make([]frontend.Variable, p.NumChunks())
+		c.MerkleProofs[k] = NewMerkleProofCircuit(p.MaxTreeDepth)
+	}
+	return c
+}
+
+// checkShape verifies that the circuit slices match Params.
+func (circuit *PoICircuit) checkShape() error {
+	p := circuit.Params
+	if err := p.Validate(); err != nil {
+		return fmt.Errorf("invalid PoI params (use NewPoICircuit): %w", err)
+	}
+	if len(circuit.Bytes) != p.OpeningsCount || len(circuit.MerkleProofs) != p.OpeningsCount ||
+		len(circuit.Quotients) != p.OpeningsCount || len(circuit.LeafIndices) != p.OpeningsCount {
+		return fmt.Errorf("circuit slices do not match %d openings", p.OpeningsCount)
+	}
+	for k := 0; k < p.OpeningsCount; k++ {
+		if len(circuit.Bytes[k]) != p.NumChunks() {
+			return fmt.Errorf("opening %d: %d byte elements, expected %d", k, len(circuit.Bytes[k]), p.NumChunks())
+		}
+		if len(circuit.MerkleProofs[k].ProofPath) != p.MaxTreeDepth || len(circuit.MerkleProofs[k].Directions) != p.MaxTreeDepth {
+			return fmt.Errorf("opening %d: merkle proof depth does not match %d", k, p.MaxTreeDepth)
+		}
+	}
+	return nil
 }
 
 func (circuit *PoICircuit) Define(api frontend.API) error {
+	if err := circuit.checkShape(); err != nil {
+		return err
+	}
+	p := circuit.Params
+
 	sponge, err := shared.NewSpongeHasher(api)
 	if err != nil {
 		return err
@@ -54,41 +94,52 @@ func (circuit *PoICircuit) Define(api frontend.API) error {
 	api.AssertIsEqual(circuit.PublicKey, derivedPubKey)
 
 	// ---------------------------------------------------------------
-	// 2. Randomness decomposition (once for all openings).
+	// 2. Randomness decomposition (once for all openings). Expanded
+	//    parameter sets decompose one challenge hash per opening instead.
 	// ---------------------------------------------------------------
 	api.AssertIsEqual(api.IsZero(circuit.Randomness), 0)
-	randBitsFull := api.ToBinary(circuit.Randomness, api.Compiler().FieldBitLen())
+	var randBitsFull []frontend.Variable
+	if !p.ExpandsRandomness() {
+		randBitsFull = api.ToBinary(circuit.Randomness, api.Compiler().FieldBitLen())
+	}
 
 	// ---------------------------------------------------------------
 	// 3. NumLeaves validation (public input, verified on-chain via FSP).
 	//    Range check: numLeaves in [1, TotalLeaves].
 	// ---------------------------------------------------------------
 	api.AssertIsEqual(api.IsZero(circuit.NumLeaves), 0)
-	api.AssertIsLessOrEqual(circuit.NumLeaves, TotalLeaves)
+	api.AssertIsLessOrEqual(circuit.NumLeaves, p.TotalLeaves())
 
 	// ---------------------------------------------------------------
 	// 4. Bounded comparator for leafIndex < numLeaves checks.
 	// ---------------------------------------------------------------
 	// Max |a - b| is TotalLeaves (when leafIndex=0, numLeaves=TotalLeaves).
-	comparator := cmp.NewBoundedComparator(api, new(big.Int).SetInt64(int64(TotalLeaves)+1), false)
+	comparator := cmp.NewBoundedComparator(api, new(big.Int).SetInt64(int64(p.TotalLeaves())+1), false)
 
 	// ---------------------------------------------------------------
 	// 5. Per-opening: modular reduction, leaf hash, Merkle proof.
 	// ---------------------------------------------------------------
-	var leafHashes [OpeningsCount]frontend.Variable
-
-	for k := 0; k < OpeningsCount; k++ {
-		// 5a. Reconstruct rawIndex from 20-bit window of randomness.
-		bitOffset := k * MaxTreeDepth
-		randWindow := make([]frontend.Variable, MaxTreeDepth)
-		for j := 0; j < MaxTreeDepth; j++ {
-			randWindow[j] = randBitsFull[bitOffset+j]
+	leafHashes := make([]frontend.Variable, p.OpeningsCount)
+
+	for k := 0; k < p.OpeningsCount; k++ {
+		// 5a. Reconstruct rawIndex from the MaxTreeDepth-bit window of
+		// randomness, or from the low MaxTreeDepth bits of H(randomness, k).
+		var randWindow []frontend.Variable
+		if p.ExpandsRandomness() {
+			challenge, err := sponge.Hash(frontend.Variable(crypto.DomainTagChallengeIdx), circuit.Randomness, k)
+			if err != nil {
+				return err
+			}
+			randWindow = api.ToBinary(challenge, api.Compiler().FieldBitLen())[:p.MaxTreeDepth]
+		} else {
+			bitOffset := k * p.MaxTreeDepth
+			randWindow = randBitsFull[bitOffset : bitOffset+p.MaxTreeDepth]
 		}
 		rawIndex := bits.FromBinary(api, randWindow, bits.WithUnconstrainedInputs())
 
 		// 5b. Modular reduction: quotient * numLeaves + leafIndex == rawIndex.
-		// Range check: quotient fits in 20 bits (< TotalLeaves).
-		api.ToBinary(circuit.Quotients[k], MaxTreeDepth)
+		// Range check: quotient fits in MaxTreeDepth bits (< TotalLeaves).
+		api.ToBinary(circuit.Quotients[k], p.MaxTreeDepth)
 		product := api.Mul(circuit.Quotients[k], circuit.NumLeaves)
 		sum := api.Add(product, circuit.LeafIndices[k])
 		api.AssertIsEqual(sum, rawIndex)
@@ -96,7 +147,7 @@ func (circuit *PoICircuit) Define(api frontend.API) error {
 		// Range check: leafIndex < numLeaves.
 		comparator.AssertIsLess(circuit.LeafIndices[k], circuit.NumLeaves)
 
-		// 5c. Compute domain-tagged leaf hash: sponge(DomainTagReal, bytes[k][0..528]).
+		// 5c. Compute domain-tagged leaf hash: sponge(DomainTagReal, bytes[k][0..NumChunks-1]).
 		leafHash, err := sponge.Hash(frontend.Variable(crypto.DomainTagReal), circuit.Bytes[k][:]...)
 		if err != nil {
 			return err
@@ -108,25 +159,23 @@ func (circuit *PoICircuit) Define(api frontend.API) error {
 		api.AssertIsEqual(circuit.MerkleProofs[k].RootHash, circuit.RootHash)
 
 		// 5e. Direction enforcement from LeafIndex bits.
-		leafBits := api.ToBinary(circuit.LeafIndices[k], MaxTreeDepth)
-		for j := 0; j < MaxTreeDepth; j++ {
+		leafBits := api.ToBinary(circuit.LeafIndices[k], p.MaxTreeDepth)
+		for j := 0; j < p.MaxTreeDepth; j++ {
 			api.AssertIsEqual(circuit.MerkleProofs[k].Directions[j], leafBits[j])
 		}
 
-		// 5f. Verify Merkle proof (all 20 levels, no skip).
+		// 5f. Verify Merkle proof (all levels, no skip).
 		if err := circuit.MerkleProofs[k].Define(api, sponge); err != nil {
 			return err
 		}
 	}
 
 	// ---------------------------------------------------------------
-	// 6. Aggregate message: aggMsg = H(leafHash[0], ..., leafHash[7], randomness).
+	// 6. Aggregate message: aggMsg = H(leafHash[0], ..., leafHash[K-1], randomness).
 	// ---------------------------------------------------------------
-	aggInputs := make([]frontend.Variable, OpeningsCount+1)
-	for k := 0; k < OpeningsCount; k++ {
-		aggInputs[k] = leafHashes[k]
-	}
-	aggInputs[OpeningsCount] = circuit.Randomness
+	aggInputs := make([]frontend.Variable, p.OpeningsCount+1)
+	copy(aggInputs, leafHashes)
+	aggInputs[p.OpeningsCount] = circuit.Randomness
 	aggMsg, err := sponge.Hash(frontend.Variable(crypto.DomainTagAggMsg), aggInputs...)
 	if err != nil {
 		return err
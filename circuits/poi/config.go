@@ -8,4 +8,14 @@ const (
 	MaxTreeDepth  = 20
 	TotalLeaves   = 1 << MaxTreeDepth // 1,048,576 leaf slots in the sparse Merkle tree
 	OpeningsCount = 8                 // number of parallel Merkle openings per proof
+
+	// Batch PoI: one proof over BatchFileCount files, with openings assigned
+	// round-robin so each file receives BatchOpeningsPerFile of them.
+	BatchFileCount       = 4
+	BatchOpeningsPerFile = 4
+	BatchOpeningsCount   = BatchFileCount * BatchOpeningsPerFile
+	ChallengeWindowBits  = 64 // low bits of H(randomness, k) reduced mod numLeaves
+
+	// Encrypted-storage PoI: plaintext/ciphertext leaf pairs opened per proof.
+	EncryptedOpeningsCount = 4
 )
@@ -0,0 +1,194 @@
+package poi
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/MuriData/muri-zkproof/circuits/shared"
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/consensys/gnark/frontend"
+	"github.com/consensys/gnark/std/math/bits"
+	"github.com/consensys/gnark/std/math/cmp"
+)
+
+// EncryptedPoICircuit binds a stored ciphertext tree to the plaintext tree of
+// the same file. Ciphertext element j of leaf i is
+//
+//	c[i][j] = p[i][j] + H(DomainTagKeystream, key, i, j)  (mod p)
+//
+// and ciphertext leaves are hashed exactly like plaintext ones,
+// H(DomainTagReal, c[i][0..528]), so the ciphertext tree is an ordinary file
+// tree for storage proofs. The data owner proves, for Params.OpeningsCount
+// leaves selected by the public randomness, that the ciphertext leaf in
+// cipherRoot encrypts the plaintext leaf at the same index in plaintextRoot
+// under the key behind keyCommitment = H(DomainTagEncKey, key). The key and
+// plaintext stay private.
+//
+// The raw index of opening k is the low ChallengeWindowBits of
+// H(DomainTagChallengeIdx, randomness, k), reduced modulo numLeaves. Like
+// PoICircuit, the private arrays are slices sized from Params, so instances
+// must be built with NewEncryptedPoICircuit.
+type EncryptedPoICircuit struct {
+	// Public inputs (5)
+	Randomness    frontend.Variable `gnark:"randomness,public"`
+	KeyCommitment frontend.Variable `gnark:"keyCommitment,public"`
+	PlaintextRoot frontend.Variable `gnark:"plaintextRoot,public"`
+	CipherRoot    frontend.Variable `gnark:"cipherRoot,public"`
+	NumLeaves     frontend.Variable `gnark:"numLeaves,public"`
+
+	// Private inputs
+	Key             frontend.Variable     `gnark:"key"`
+	Bytes           [][]frontend.Variable `gnark:"bytes"` // plaintext elements
+	PlaintextProofs []MerkleProofCircuit  `gnark:"plaintextProofs"`
+	CipherProofs    []MerkleProofCircuit  `gnark:"cipherProofs"`
+	Quotients       []frontend.Variable   `gnark:"quotients"`
+	LeafIndices     []frontend.Variable   `gnark:"leafIndices"`
+
+	// Params fixes the circuit shape; it is not part of the witness.
+	Params Params `gnark:"-"`
+}
+
+// NewEncryptedPoICircuit allocates an EncryptedPoICircuit whose slices are
+// sized from p. p.OpeningsCount is the number of leaf pairs opened.
+func NewEncryptedPoICircuit(p Params) *EncryptedPoICircuit {
+	c := &EncryptedPoICircuit{
+		Bytes:           make([][]frontend.Variable, p.OpeningsCount),
+		PlaintextProofs: make([]MerkleProofCircuit, p.OpeningsCount),
+		CipherProofs:    make([]MerkleProofCircuit, p.OpeningsCount),
+		Quotients:       make([]frontend.Variable, p.OpeningsCount),
+		LeafIndices:     make([]frontend.Variable, p.OpeningsCount),
+		Params:          p,
+	}
+	for k := 0; k < p.OpeningsCount; k++ {
+		c.Bytes[k] = make([]frontend.Variable, p.NumChunks())
+		c.PlaintextProofs[k] = NewMerkleProofCircuit(p.MaxTreeDepth)
+		c.CipherProofs[k] = NewMerkleProofCircuit(p.MaxTreeDepth)
+	}
+	return c
+}
+
+// checkShape verifies that the circuit slices match Params.
+func (circuit *EncryptedPoICircuit) checkShape() error {
+	p := circuit.Params
+	if err := p.Validate(); err != nil {
+		return fmt.Errorf("invalid encrypted PoI params (use NewEncryptedPoICircuit): %w", err)
+	}
+	if len(circuit.Bytes) != p.OpeningsCount || len(circuit.PlaintextProofs) != p.OpeningsCount ||
+		len(circuit.CipherProofs) != p.OpeningsCount || len(circuit.Quotients) != p.OpeningsCount ||
+		len(circuit.LeafIndices) != p.OpeningsCount {
+		return fmt.Errorf("circuit slices do not match %d openings", p.OpeningsCount)
+	}
+	for k := 0; k < p.OpeningsCount; k++ {
+		if len(circuit.Bytes[k]) != p.NumChunks() {
+			return fmt.Errorf("opening %d: %d byte elements, expected %d", k, len(circuit.Bytes[k]), p.NumChunks())
+		}
+		for _, proof := range []MerkleProofCircuit{circuit.PlaintextProofs[k], circuit.CipherProofs[k]} {
+			if len(proof.ProofPath) != p.MaxTreeDepth || len(proof.Directions) != p.MaxTreeDepth {
+				return fmt.Errorf("opening %d: merkle proof depth does not match %d", k, p.MaxTreeDepth)
+			}
+		}
+	}
+	return nil
+}
+
+func (circuit *EncryptedPoICircuit) Define(api frontend.API) error {
+	if err := circuit.checkShape(); err != nil {
+		return err
+	}
+	p := circuit.Params
+
+	sponge, err := shared.NewSpongeHasher(api)
+	if err != nil {
+		return err
+	}
+
+	// ---------------------------------------------------------------
+	// 1. Key commitment: keyCommitment == H(key), key non-zero.
+	// ---------------------------------------------------------------
+	api.AssertIsEqual(api.IsZero(circuit.Key), 0)
+
+	derivedCommitment, err := sponge.Hash(frontend.Variable(crypto.DomainTagEncKey), circuit.Key)
+	if err != nil {
+		return err
+	}
+	api.AssertIsEqual(circuit.KeyCommitment, derivedCommitment)
+
+	api.AssertIsEqual(api.IsZero(circuit.Randomness), 0)
+
+	// ---------------------------------------------------------------
+	// 2. NumLeaves validation: numLeaves in [1, TotalLeaves].
+	// ---------------------------------------------------------------
+	api.AssertIsEqual(api.IsZero(circuit.NumLeaves), 0)
+	api.AssertIsLessOrEqual(circuit.NumLeaves, p.TotalLeaves())
+
+	// ---------------------------------------------------------------
+	// 3. Bounded comparator for leafIndex < numLeaves checks.
+	// ---------------------------------------------------------------
+	comparator := cmp.NewBoundedComparator(api, new(big.Int).SetInt64(int64(p.TotalLeaves())+1), false)
+
+	// ---------------------------------------------------------------
+	// 4. Per-opening: challenge index, plaintext leaf, ciphertext leaf.
+	// ---------------------------------------------------------------
+	for k := 0; k < p.OpeningsCount; k++ {
+		// 4a. Raw index: low ChallengeWindowBits of H(randomness, k).
+		challenge, err := sponge.Hash(frontend.Variable(crypto.DomainTagChallengeIdx), circuit.Randomness, k)
+		if err != nil {
+			return err
+		}
+		challengeBits := api.ToBinary(challenge, api.Compiler().FieldBitLen())
+		rawIndex := bits.FromBinary(api, challengeBits[:ChallengeWindowBits], bits.WithUnconstrainedInputs())
+
+		// 4b. Modular reduction: quotient * numLeaves + leafIndex == rawIndex.
+		// Range check: quotient fits in ChallengeWindowBits.
+		api.ToBinary(circuit.Quotients[k], ChallengeWindowBits)
+		product := api.Mul(circuit.Quotients[k], circuit.NumLeaves)
+		sum := api.Add(product, circuit.LeafIndices[k])
+		api.AssertIsEqual(sum, rawIndex)
+
+		// Range check: leafIndex < numLeaves.
+		comparator.AssertIsLess(circuit.LeafIndices[k], circuit.NumLeaves)
+		leafBits := api.ToBinary(circuit.LeafIndices[k], p.MaxTreeDepth)
+
+		// 4c. Plaintext leaf hash: sponge(DomainTagReal, bytes[k][0..528]).
+		plainHash, err := sponge.Hash(frontend.Variable(crypto.DomainTagReal), circuit.Bytes[k][:]...)
+		if err != nil {
+			return err
+		}
+
+		// 4d. Encrypt under the keystream of this leaf and hash the
+		// ciphertext with the same leaf domain tag.
+		cipher := make([]frontend.Variable, p.NumChunks())
+		for j := range cipher {
+			ks, err := sponge.Hash(frontend.Variable(crypto.DomainTagKeystream), circuit.Key, circuit.LeafIndices[k], j)
+			if err != nil {
+				return err
+			}
+			cipher[j] = api.Add(circuit.Bytes[k][j], ks)
+		}
+		cipherHash, err := sponge.Hash(frontend.Variable(crypto.DomainTagReal), cipher...)
+		if err != nil {
+			return err
+		}
+
+		// 4e. Both leaves sit at leafIndex of their respective trees.
+		for _, opening := range []struct {
+			proof *MerkleProofCircuit
+			leaf  frontend.Variable
+			root  frontend.Variable
+		}{
+			{&circuit.PlaintextProofs[k], plainHash, circuit.PlaintextRoot},
+			{&circuit.CipherProofs[k], cipherHash, circuit.CipherRoot},
+		} {
+			api.AssertIsEqual(opening.proof.LeafValue, opening.leaf)
+			api.AssertIsEqual(opening.proof.RootHash, opening.root)
+			for j := 0; j < p.MaxTreeDepth; j++ {
+				api.AssertIsEqual(opening.proof.Directions[j], leafBits[j])
+			}
+			if err := opening.proof.Define(api, sponge); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package poi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/MuriData/muri-zkproof/pkg/merkle"
+	"github.com/MuriData/muri-zkproof/pkg/setup"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	groth16bn254 "github.com/consensys/gnark/backend/groth16/bn254"
+	"github.com/consensys/gnark/frontend"
+)
+
+// EncryptedProofFixture holds all values needed for Solidity encrypted-storage
+// PoI tests.
+type EncryptedProofFixture struct {
+	SolidityProof [8]string `json:"solidity_proof"`
+	Randomness    string    `json:"randomness"`
+	KeyCommitment string    `json:"key_commitment"`
+	PlaintextRoot string    `json:"plaintext_root"`
+	CipherRoot    string    `json:"cipher_root"`
+	NumLeaves     string    `json:"num_leaves"`
+}
+
+// ExportEncryptedProofFixture generates a deterministic encrypted-storage
+// proof fixture for Solidity tests. keysDir is the directory containing the
+// proving and verifying keys.
+func ExportEncryptedProofFixture(keysDir string) ([]byte, error) {
+	return ExportEncryptedProofFixtureWithParams(keysDir, "poi_encrypted", DefaultEncryptedParams)
+}
+
+// ExportEncryptedProofFixtureWithParams generates a deterministic
+// encrypted-storage proof fixture for the parameter set p, loading keys
+// stored under circuitName in keysDir.
+func ExportEncryptedProofFixtureWithParams(keysDir, circuitName string, p Params) ([]byte, error) {
+	// 1. Compile the circuit
+	fmt.Printf("Compiling circuit (%s)...\n", p.EncryptedName())
+	ccs, err := setup.CompileCircuit(NewEncryptedPoICircuit(p))
+	if err != nil {
+		return nil, fmt.Errorf("compile circuit: %w", err)
+	}
+
+	// 2. Load proving and verifying keys
+	fmt.Println("Loading keys...")
+	pk, vk, err := setup.LoadKeys(keysDir, circuitName)
+	if err != nil {
+		return nil, fmt.Errorf("load keys: %w", err)
+	}
+
+	// 3. Create a deterministic test file (128 KB = 8 chunks) and encrypt it.
+	testFileData := make([]byte, 8*p.FileSize)
+	for i := range testFileData {
+		testFileData[i] = byte(i % 256)
+	}
+	chunks := merkle.SplitIntoChunks(testFileData, p.FileSize)
+
+	plainTree, err := merkle.GenerateSparseMerkleTree(chunks, p.MaxTreeDepth, p.HashChunk, p.ZeroLeafHash())
+	if err != nil {
+		return nil, fmt.Errorf("build plaintext SMT: %w", err)
+	}
+
+	key := new(big.Int).SetUint64(98765)
+	cipherTree, err := p.BuildCipherTree(key, chunks)
+	if err != nil {
+		return nil, fmt.Errorf("build ciphertext SMT: %w", err)
+	}
+	fmt.Printf("Plaintext root: 0x%x\n", plainTree.Root.Bytes())
+	fmt.Printf("Ciphertext root: 0x%x\n", cipherTree.Root.Bytes())
+
+	// 4. Deterministic randomness
+	randomness := new(big.Int).SetUint64(42)
+
+	result, err := PrepareEncryptedWitnessWithParams(p, key, randomness, EncryptedFile{
+		Chunks:        chunks,
+		PlaintextTree: plainTree,
+		CipherTree:    cipherTree,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("prepare witness: %w", err)
+	}
+	fmt.Printf("Selected chunk indices: %v\n", result.ChunkIndices)
+	fmt.Printf("Key commitment: 0x%064x\n", result.KeyCommitment)
+
+	// 5. Create witness and generate proof
+	witness, err := frontend.NewWitness(&result.Assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		return nil, fmt.Errorf("create witness: %w", err)
+	}
+
+	publicWitness, err := witness.Public()
+	if err != nil {
+		return nil, fmt.Errorf("extract public witness: %w", err)
+	}
+
+	fmt.Println("Generating proof...")
+	proof, err := groth16.Prove(ccs, pk, witness)
+	if err != nil {
+		return nil, fmt.Errorf("prove: %w", err)
+	}
+
+	// 6. Verify proof in Go
+	err = groth16.Verify(proof, vk, publicWitness)
+	if err != nil {
+		return nil, fmt.Errorf("verify: %w", err)
+	}
+	fmt.Println("Proof verified successfully in Go!")
+
+	// 7. Extract proof points for Solidity
+	bn254Proof := proof.(*groth16bn254.Proof)
+
+	aX := new(big.Int)
+	aY := new(big.Int)
+	bn254Proof.Ar.X.BigInt(aX)
+	bn254Proof.Ar.Y.BigInt(aY)
+
+	bX0 := new(big.Int)
+	bX1 := new(big.Int)
+	bY0 := new(big.Int)
+	bY1 := new(big.Int)
+	bn254Proof.Bs.X.A0.BigInt(bX0)
+	bn254Proof.Bs.X.A1.BigInt(bX1)
+	bn254Proof.Bs.Y.A0.BigInt(bY0)
+	bn254Proof.Bs.Y.A1.BigInt(bY1)
+
+	cX := new(big.Int)
+	cY := new(big.Int)
+	bn254Proof.Krs.X.BigInt(cX)
+	bn254Proof.Krs.Y.BigInt(cY)
+
+	// Solidity format: [A.x, A.y, B.x1, B.x0, B.y1, B.y0, C.x, C.y]
+	solidityProof := [8]*big.Int{aX, aY, bX1, bX0, bY1, bY0, cX, cY}
+
+	fixture := EncryptedProofFixture{
+		Randomness:    fmt.Sprintf("0x%064x", randomness),
+		KeyCommitment: fmt.Sprintf("0x%064x", result.KeyCommitment),
+		PlaintextRoot: fmt.Sprintf("0x%064x", plainTree.RootBigInt()),
+		CipherRoot:    fmt.Sprintf("0x%064x", cipherTree.RootBigInt()),
+		NumLeaves:     fmt.Sprintf("0x%064x", big.NewInt(int64(plainTree.NumLeaves))),
+	}
+	for i := 0; i < 8; i++ {
+		fixture.SolidityProof[i] = fmt.Sprintf("0x%064x", solidityProof[i])
+	}
+
+	jsonOut, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal fixture: %w", err)
+	}
+
+	// Print diagnostic info
+	fmt.Println("\n=== PROOF FIXTURE (JSON) ===")
+	fmt.Println(string(jsonOut))
+
+	fmt.Println("\n=== SOLIDITY CONSTANTS ===")
+	fmt.Printf("    // Public inputs\n")
+	fmt.Printf("    uint256 constant ENC_RANDOMNESS = %s;\n", fixture.Randomness)
+	fmt.Printf("    uint256 constant ENC_KEY_COMMITMENT = %s;\n", fixture.KeyCommitment)
+	fmt.Printf("    uint256 constant ENC_PLAINTEXT_ROOT = %s;\n", fixture.PlaintextRoot)
+	fmt.Printf("    uint256 constant ENC_CIPHER_ROOT = %s;\n", fixture.CipherRoot)
+	fmt.Printf("    uint256 constant ENC_NUM_LEAVES = %s;\n", fixture.NumLeaves)
+	fmt.Println()
+	fmt.Printf("    // Proof (uint256[8])\n")
+	for i := 0; i < 8; i++ {
+		fmt.Printf("    uint256 constant ENC_PROOF_%d = %s;\n", i, fixture.SolidityProof[i])
+	}
+
+	// Public witness info
+	fmt.Println("\n=== PUBLIC WITNESS ORDER ===")
+	fmt.Println("In gnark circuit (= Solidity order): [randomness, keyCommitment, plaintextRoot, cipherRoot, numLeaves]")
+	var pubWitBuf bytes.Buffer
+	_, err = publicWitness.WriteTo(&pubWitBuf)
+	if err != nil {
+		return nil, fmt.Errorf("write public witness: %w", err)
+	}
+	fmt.Printf("Public witness size: %d bytes\n", pubWitBuf.Len())
+
+	return jsonOut, nil
+}
@@ -0,0 +1,172 @@
+package poi_test
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/MuriData/muri-zkproof/circuits/poi"
+	"github.com/MuriData/muri-zkproof/pkg/merkle"
+	"github.com/MuriData/muri-zkproof/pkg/setup"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/frontend"
+)
+
+// buildEncryptedFile is a test helper that generates a random file of n
+// chunks and builds its plaintext and ciphertext trees under key.
+func buildEncryptedFile(t *testing.T, key *big.Int, n int) poi.EncryptedFile {
+	t.Helper()
+	data := make([]byte, n*poi.FileSize)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatalf("generate random data: %v", err)
+	}
+	plain, chunks := buildSMT(t, data)
+	cipher, err := poi.BuildCipherTree(key, chunks)
+	if err != nil {
+		t.Fatalf("build ciphertext SMT: %v", err)
+	}
+	return poi.EncryptedFile{Chunks: chunks, PlaintextTree: plain, CipherTree: cipher}
+}
+
+// TestEncryptedPoICircuit solves the encrypted-storage circuit for a valid
+// witness and checks that a wrong key or mismatched ciphertext is rejected.
+func TestEncryptedPoICircuit(t *testing.T) {
+	ccs, err := setup.CompileCircuit(poi.NewEncryptedPoICircuit(poi.DefaultEncryptedParams))
+	if err != nil {
+		t.Fatalf("compile circuit: %v", err)
+	}
+	t.Logf("Constraints: %d", ccs.GetNbConstraints())
+
+	key := big.NewInt(424242)
+	file := buildEncryptedFile(t, key, 5)
+	result, err := poi.PrepareEncryptedWitness(key, big.NewInt(99), file)
+	if err != nil {
+		t.Fatalf("prepare witness: %v", err)
+	}
+
+	solved := func(assignment *poi.EncryptedPoICircuit) bool {
+		t.Helper()
+		witness, err := frontend.NewWitness(assignment, ecc.BN254.ScalarField())
+		if err != nil {
+			t.Fatalf("create witness: %v", err)
+		}
+		return ccs.IsSolved(witness) == nil
+	}
+
+	if !solved(&result.Assignment) {
+		t.Fatal("circuit not solved for a valid witness")
+	}
+
+	// A different key no longer opens the key commitment.
+	wrongKey := result.Assignment
+	wrongKey.Key = big.NewInt(424243)
+	if solved(&wrongKey) {
+		t.Fatal("expected wrong key to be rejected")
+	}
+
+	// The ciphertext root must commit to this plaintext under this key.
+	otherCipher := buildEncryptedFile(t, key, 5).CipherTree
+	wrongRoot := result.Assignment
+	wrongRoot.CipherRoot = otherCipher.Root
+	if solved(&wrongRoot) {
+		t.Fatal("expected unrelated ciphertext root to be rejected")
+	}
+}
+
+// TestPrepareEncryptedWitnessRejectsMismatch checks that the witness builder
+// refuses a ciphertext tree built under a different key.
+func TestPrepareEncryptedWitnessRejectsMismatch(t *testing.T) {
+	key := big.NewInt(7)
+	file := buildEncryptedFile(t, key, 3)
+
+	cipher, err := poi.BuildCipherTree(big.NewInt(8), file.Chunks)
+	if err != nil {
+		t.Fatalf("build ciphertext SMT: %v", err)
+	}
+	file.CipherTree = cipher
+	if _, err := poi.PrepareEncryptedWitness(key, big.NewInt(1), file); err == nil {
+		t.Fatal("expected error for ciphertext under a different key")
+	}
+
+	file.Chunks = file.Chunks[:2]
+	if _, err := poi.PrepareEncryptedWitness(key, big.NewInt(1), file); err == nil {
+		t.Fatal("expected error for chunk/tree mismatch")
+	}
+}
+
+func TestEncryptedParamSets(t *testing.T) {
+	for name, p := range poi.EncryptedParamSets {
+		if err := p.Validate(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if p.EncryptedName() != name {
+			t.Fatalf("param set registered as %q but named %q", name, p.EncryptedName())
+		}
+	}
+	if poi.DefaultEncryptedParams.EncryptedName() != "poi_encrypted-d20-o4" {
+		t.Fatalf("unexpected default params name %q", poi.DefaultEncryptedParams.EncryptedName())
+	}
+	if _, err := setup.CompileCircuit(&poi.EncryptedPoICircuit{}); err == nil {
+		t.Fatal("expected compile error for unallocated circuit")
+	}
+}
+
+// TestEncryptedPoICustomParams solves a small non-default encrypted-storage
+// parameter set and checks that trees of another depth are rejected.
+func TestEncryptedPoICustomParams(t *testing.T) {
+	p := poi.Params{FileSize: 1024, MaxTreeDepth: 8, OpeningsCount: 2}
+	data := make([]byte, 5*p.FileSize)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatalf("generate random data: %v", err)
+	}
+	chunks := merkle.SplitIntoChunks(data, p.FileSize)
+	plain, err := merkle.GenerateSparseMerkleTree(chunks, p.MaxTreeDepth, p.HashChunk, p.ZeroLeafHash())
+	if err != nil {
+		t.Fatalf("build plaintext SMT: %v", err)
+	}
+	key := big.NewInt(31337)
+	cipher, err := p.BuildCipherTree(key, chunks)
+	if err != nil {
+		t.Fatalf("build ciphertext SMT: %v", err)
+	}
+	file := poi.EncryptedFile{Chunks: chunks, PlaintextTree: plain, CipherTree: cipher}
+
+	result, err := poi.PrepareEncryptedWitnessWithParams(p, key, big.NewInt(5), file)
+	if err != nil {
+		t.Fatalf("prepare witness: %v", err)
+	}
+	if len(result.ChunkIndices) != p.OpeningsCount {
+		t.Fatalf("got %d chunk indices, expected %d", len(result.ChunkIndices), p.OpeningsCount)
+	}
+
+	ccs, err := setup.CompileCircuit(poi.NewEncryptedPoICircuit(p))
+	if err != nil {
+		t.Fatalf("compile circuit: %v", err)
+	}
+	witness, err := frontend.NewWitness(&result.Assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatalf("create witness: %v", err)
+	}
+	if err := ccs.IsSolved(witness); err != nil {
+		t.Fatalf("circuit not solved: %v", err)
+	}
+
+	// Trees over the same chunks at another depth must be rejected.
+	deeper := poi.Params{FileSize: p.FileSize, MaxTreeDepth: p.MaxTreeDepth + 1, OpeningsCount: p.OpeningsCount}
+	deeperPlain, err := merkle.GenerateSparseMerkleTree(chunks, deeper.MaxTreeDepth, deeper.HashChunk, deeper.ZeroLeafHash())
+	if err != nil {
+		t.Fatalf("build deeper plaintext SMT: %v", err)
+	}
+	deeperCipher, err := deeper.BuildCipherTree(key, chunks)
+	if err != nil {
+		t.Fatalf("build deeper ciphertext SMT: %v", err)
+	}
+	_, err = poi.PrepareEncryptedWitnessWithParams(p, key, big.NewInt(5), poi.EncryptedFile{Chunks: chunks, PlaintextTree: deeperPlain, CipherTree: deeperCipher})
+	if err == nil {
+		t.Fatal("expected depth mismatch error")
+	}
+	if !strings.Contains(err.Error(), "depth") {
+		t.Fatalf("expected depth mismatch error, got %v", err)
+	}
+}
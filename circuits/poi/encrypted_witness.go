@@ -0,0 +1,156 @@
+package poi
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/MuriData/muri-zkproof/pkg/field"
+	"github.com/MuriData/muri-zkproof/pkg/merkle"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// EncryptedFile is a plaintext file together with its plaintext tree and the
+// tree of its encryption (see BuildCipherTree).
+type EncryptedFile struct {
+	Chunks        [][]byte
+	PlaintextTree *merkle.SparseMerkleTree
+	CipherTree    *merkle.SparseMerkleTree
+}
+
+// EncryptedWitnessResult holds the fully populated encrypted-storage circuit
+// assignment and derived public values.
+type EncryptedWitnessResult struct {
+	Assignment    EncryptedPoICircuit
+	ChunkIndices  []int
+	KeyCommitment *big.Int
+}
+
+// EncryptChunk encrypts a plaintext chunk stored at leafIndex, returning its
+// NumChunks ciphertext field elements c[j] = p[j] + H(key, leafIndex, j).
+func EncryptChunk(key *big.Int, leafIndex int, chunk []byte) []fr.Element {
+	return DefaultEncryptedParams.EncryptChunk(key, leafIndex, chunk)
+}
+
+// EncryptChunk is the package-level EncryptChunk for this parameter set's
+// element count.
+func (p Params) EncryptChunk(key *big.Int, leafIndex int, chunk []byte) []fr.Element {
+	elems := crypto.ChunkToElements(chunk, ElementSize, p.NumChunks())
+	idx := big.NewInt(int64(leafIndex))
+	for j := range elems {
+		var ks fr.Element
+		ks.SetBigInt(crypto.DeriveKeystreamElem(key, idx, big.NewInt(int64(j))))
+		elems[j].Add(&elems[j], &ks)
+	}
+	return elems
+}
+
+// HashCipherChunk hashes ciphertext elements with DomainTagReal, the same
+// leaf hash as a plaintext chunk.
+func HashCipherChunk(cipher []fr.Element) fr.Element {
+	return crypto.SpongeHash(crypto.DomainTagReal, cipher)
+}
+
+// BuildCipherTree encrypts every chunk under key and builds the depth
+// MaxTreeDepth sparse Merkle tree over the ciphertext leaves.
+func BuildCipherTree(key *big.Int, chunks [][]byte) (*merkle.SparseMerkleTree, error) {
+	return DefaultEncryptedParams.BuildCipherTree(key, chunks)
+}
+
+// BuildCipherTree is the package-level BuildCipherTree for this parameter
+// set, building the tree at depth p.MaxTreeDepth.
+func (p Params) BuildCipherTree(key *big.Int, chunks [][]byte) (*merkle.SparseMerkleTree, error) {
+	leafHashes := make([]fr.Element, len(chunks))
+	for i, chunk := range chunks {
+		leafHashes[i] = HashCipherChunk(p.EncryptChunk(key, i, chunk))
+	}
+	return merkle.BuildSMTFromLeafHashes(leafHashes, p.MaxTreeDepth, p.ZeroLeafHash())
+}
+
+// PrepareEncryptedWitness derives all public and private witness values for
+// an encrypted-storage proof of file under key, for DefaultEncryptedParams.
+func PrepareEncryptedWitness(key, randomness *big.Int, file EncryptedFile) (*EncryptedWitnessResult, error) {
+	return PrepareEncryptedWitnessWithParams(DefaultEncryptedParams, key, randomness, file)
+}
+
+// PrepareEncryptedWitnessWithParams is PrepareEncryptedWitness for an
+// arbitrary parameter set. Both trees must have depth p.MaxTreeDepth, and the
+// ciphertext tree must be p.BuildCipherTree of the chunks.
+func PrepareEncryptedWitnessWithParams(p Params, key, randomness *big.Int, file EncryptedFile) (*EncryptedWitnessResult, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	plain, cipher := file.PlaintextTree, file.CipherTree
+	if plain == nil || cipher == nil || plain.NumLeaves == 0 {
+		return nil, fmt.Errorf("encrypted file needs non-empty plaintext and ciphertext trees")
+	}
+	if cipher.NumLeaves != plain.NumLeaves {
+		return nil, fmt.Errorf("ciphertext tree has %d leaves, plaintext tree %d", cipher.NumLeaves, plain.NumLeaves)
+	}
+	if plain.Depth != p.MaxTreeDepth || cipher.Depth != p.MaxTreeDepth {
+		return nil, fmt.Errorf("trees must have depth %d", p.MaxTreeDepth)
+	}
+	if len(file.Chunks) != plain.NumLeaves {
+		return nil, fmt.Errorf("chunk count %d does not match tree numLeaves %d", len(file.Chunks), plain.NumLeaves)
+	}
+
+	keyCommitment := crypto.DeriveKeyCommitment(key)
+
+	assignment := NewEncryptedPoICircuit(p)
+	assignment.Randomness = randomness
+	assignment.KeyCommitment = keyCommitment
+	assignment.PlaintextRoot = plain.Root
+	assignment.CipherRoot = cipher.Root
+	assignment.NumLeaves = plain.NumLeaves
+	assignment.Key = key
+
+	chunkIndices := make([]int, p.OpeningsCount)
+
+	mask := new(big.Int).Lsh(big.NewInt(1), ChallengeWindowBits)
+	mask.Sub(mask, big.NewInt(1))
+
+	for k := 0; k < p.OpeningsCount; k++ {
+		// Raw index from the low ChallengeWindowBits of H(randomness, k).
+		var challenge fr.Element
+		challenge.SetBigInt(crypto.DeriveChallengeIdx(randomness, big.NewInt(int64(k))))
+		rawIndex := new(big.Int)
+		challenge.BigInt(rawIndex)
+		rawIndex.And(rawIndex, mask)
+
+		// Modular reduction: leafIndex = rawIndex % numLeaves.
+		quotientBig, leafIndexBig := new(big.Int).DivMod(rawIndex, big.NewInt(int64(plain.NumLeaves)), new(big.Int))
+		leafIndex := int(leafIndexBig.Int64())
+		chunkIndices[k] = leafIndex
+
+		chunk := file.Chunks[leafIndex]
+		if cipher.GetLeafHash(leafIndex) != HashCipherChunk(p.EncryptChunk(key, leafIndex, chunk)) {
+			return nil, fmt.Errorf("ciphertext leaf %d is not the encryption of chunk %d under key", leafIndex, leafIndex)
+		}
+
+		copy(assignment.Bytes[k], field.Bytes2Field(chunk, p.NumChunks(), ElementSize))
+		assignment.Quotients[k] = quotientBig
+		assignment.LeafIndices[k] = leafIndexBig
+		assignment.PlaintextProofs[k] = encryptedOpeningProof(plain, leafIndex)
+		assignment.CipherProofs[k] = encryptedOpeningProof(cipher, leafIndex)
+	}
+
+	return &EncryptedWitnessResult{
+		Assignment:    *assignment,
+		ChunkIndices:  chunkIndices,
+		KeyCommitment: keyCommitment,
+	}, nil
+}
+
+// encryptedOpeningProof builds the Merkle proof assignment of leafIndex at
+// the tree's depth.
+func encryptedOpeningProof(smt *merkle.SparseMerkleTree, leafIndex int) MerkleProofCircuit {
+	siblings, directions := smt.GetProof(leafIndex)
+	proof := NewMerkleProofCircuit(smt.Depth)
+	proof.RootHash = smt.Root
+	proof.LeafValue = smt.GetLeafHash(leafIndex)
+	for i := 0; i < smt.Depth; i++ {
+		proof.ProofPath[i] = siblings[i]
+		proof.Directions[i] = directions[i]
+	}
+	return proof
+}
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"math/big"
 
-	"github.com/MuriData/muri-zkproof/pkg/crypto"
 	"github.com/MuriData/muri-zkproof/pkg/merkle"
 	"github.com/MuriData/muri-zkproof/pkg/setup"
 	"github.com/consensys/gnark-crypto/ecc"
@@ -29,27 +28,33 @@ type ProofFixture struct {
 // ExportProofFixture generates a deterministic proof fixture for Solidity tests.
 // keysDir is the directory containing the proving and verifying keys.
 func ExportProofFixture(keysDir string) ([]byte, error) {
+	return ExportProofFixtureWithParams(keysDir, "poi", DefaultParams)
+}
+
+// ExportProofFixtureWithParams generates a deterministic proof fixture for the
+// parameter set p, loading keys stored under circuitName in keysDir.
+func ExportProofFixtureWithParams(keysDir, circuitName string, p Params) ([]byte, error) {
 	// 1. Compile the circuit
-	fmt.Println("Compiling circuit...")
-	ccs, err := setup.CompileCircuit(&PoICircuit{})
+	fmt.Printf("Compiling circuit (%s)...\n", p.Name())
+	ccs, err := setup.CompileCircuit(NewPoICircuit(p))
 	if err != nil {
 		return nil, fmt.Errorf("compile circuit: %w", err)
 	}
 
 	// 2. Load proving and verifying keys
 	fmt.Println("Loading keys...")
-	pk, vk, err := setup.LoadKeys(keysDir, "poi")
+	pk, vk, err := setup.LoadKeys(keysDir, circuitName)
 	if err != nil {
 		return nil, fmt.Errorf("load keys: %w", err)
 	}
 
 	// 3. Create a deterministic test file (128 KB = 8 chunks).
 	//    Using 8 chunks exercises all OpeningsCount openings with distinct leaves.
-	testFileData := make([]byte, 8*FileSize)
+	testFileData := make([]byte, 8*p.FileSize)
 	for i := range testFileData {
 		testFileData[i] = byte(i % 256)
 	}
-	chunks := merkle.SplitIntoChunks(testFileData, FileSize)
+	chunks := merkle.SplitIntoChunks(testFileData, p.FileSize)
 	fmt.Printf("Chunks: %d\n", len(chunks))
 
 	// 4. Deterministic randomness and secret key
@@ -66,15 +71,14 @@ func ExportProofFixture(keysDir string) ([]byte, error) {
 	skFr.BigInt(secretKey)
 
 	// 5. Build sparse Merkle tree and prepare the full witness
-	zeroLeaf := crypto.ComputeZeroLeafHashFr(ElementSize, NumChunks)
-	smt, err := merkle.GenerateSparseMerkleTree(chunks, MaxTreeDepth, HashChunk, zeroLeaf)
+	smt, err := merkle.GenerateSparseMerkleTree(chunks, p.MaxTreeDepth, p.HashChunk, p.ZeroLeafHash())
 	if err != nil {
 		return nil, fmt.Errorf("build SMT: %w", err)
 	}
 	fmt.Printf("Merkle root: 0x%x\n", smt.Root.Bytes())
 	fmt.Printf("Leaves: %d, Depth: %d\n", smt.NumLeaves, smt.Depth)
 
-	result, err := PrepareWitness(secretKey, randomness, chunks, smt)
+	result, err := PrepareWitnessWithParams(p, secretKey, randomness, chunks, smt)
 	if err != nil {
 		return nil, fmt.Errorf("prepare witness: %w", err)
 	}
@@ -6,24 +6,33 @@ import (
 	"github.com/consensys/gnark/frontend"
 )
 
-// MerkleProofCircuit verifies a Merkle proof in a fixed depth-20 sparse tree.
-// All MaxTreeDepth levels are always active (no skip logic).
+// MerkleProofCircuit verifies a Merkle proof in a fixed-depth sparse tree.
+// The depth is len(ProofPath); all levels are always active (no skip logic).
 type MerkleProofCircuit struct {
 	// Public inputs
 	RootHash frontend.Variable `gnark:"rootHash"`
 
 	// Private inputs
-	LeafValue  frontend.Variable                    `gnark:"leafValue"`  // The leaf hash we're proving membership of
-	ProofPath  [MaxTreeDepth]frontend.Variable `gnark:"proofPath"`  // Sibling hashes along the path to root
-	Directions [MaxTreeDepth]frontend.Variable `gnark:"directions"` // 0 = sibling on right, 1 = sibling on left
+	LeafValue  frontend.Variable   `gnark:"leafValue"`  // The leaf hash we're proving membership of
+	ProofPath  []frontend.Variable `gnark:"proofPath"`  // Sibling hashes along the path to root
+	Directions []frontend.Variable `gnark:"directions"` // 0 = sibling on right, 1 = sibling on left
+}
+
+// NewMerkleProofCircuit allocates a MerkleProofCircuit for a tree of the
+// given depth.
+func NewMerkleProofCircuit(depth int) MerkleProofCircuit {
+	return MerkleProofCircuit{
+		ProofPath:  make([]frontend.Variable, depth),
+		Directions: make([]frontend.Variable, depth),
+	}
 }
 
 // Define implements the circuit logic for Merkle proof verification.
-// All 20 levels are always hashed — no conditional skip.
+// All levels are always hashed — no conditional skip.
 func (circuit *MerkleProofCircuit) Define(api frontend.API, sponge *shared.SpongeHasher) error {
 	currentHash := circuit.LeafValue
 
-	for i := 0; i < MaxTreeDepth; i++ {
+	for i := range circuit.ProofPath {
 		sibling := circuit.ProofPath[i]
 		direction := circuit.Directions[i]
 
@@ -40,4 +49,3 @@ func (circuit *MerkleProofCircuit) Define(api frontend.API, sponge *shared.Spong
 
 	return nil
 }
-
@@ -0,0 +1,198 @@
+package poi
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// RandomnessBits is the number of usable bits in the BN254 scalar field
+// randomness. Bit-sliced leaf selection needs MaxTreeDepth bits per opening;
+// parameter sets needing more use DeriveChallengeIdx expansion instead.
+const RandomnessBits = 254
+
+// Params selects a PoI parameter set. Each set compiles to a distinct circuit
+// with its own trusted setup, keys and verifier contract.
+type Params struct {
+	FileSize      int // chunk size in bytes
+	MaxTreeDepth  int // sparse Merkle tree depth
+	OpeningsCount int // number of parallel Merkle openings per proof
+}
+
+// DefaultParams is the production parameter set matching the package
+// constants (16 KiB chunks, depth 20, 8 openings).
+var DefaultParams = Params{
+	FileSize:      FileSize,
+	MaxTreeDepth:  MaxTreeDepth,
+	OpeningsCount: OpeningsCount,
+}
+
+// ParamSets maps registry names to the supported PoI parameter sets.
+var ParamSets = map[string]Params{
+	"poi-d20-o8":  DefaultParams,
+	"poi-d20-o12": {FileSize: FileSize, MaxTreeDepth: 20, OpeningsCount: 12},
+	"poi-d24-o8":  {FileSize: FileSize, MaxTreeDepth: 24, OpeningsCount: 8},
+	"poi-d24-o16": {FileSize: FileSize, MaxTreeDepth: 24, OpeningsCount: 16},
+	"poi-d28-o8":  {FileSize: FileSize, MaxTreeDepth: 28, OpeningsCount: 8},
+	"poi-d28-o16": {FileSize: FileSize, MaxTreeDepth: 28, OpeningsCount: 16},
+}
+
+// DefaultEncryptedParams is the encrypted-storage PoI parameter set of the
+// "poi_encrypted" keys (depth 20, EncryptedOpeningsCount leaf pairs).
+var DefaultEncryptedParams = Params{
+	FileSize:      FileSize,
+	MaxTreeDepth:  MaxTreeDepth,
+	OpeningsCount: EncryptedOpeningsCount,
+}
+
+// EncryptedParamSets maps registry names to the supported encrypted-storage
+// PoI parameter sets. OpeningsCount is the number of plaintext/ciphertext
+// leaf pairs opened per proof.
+var EncryptedParamSets = map[string]Params{
+	"poi_encrypted-d20-o4": DefaultEncryptedParams,
+	"poi_encrypted-d24-o4": {FileSize: FileSize, MaxTreeDepth: 24, OpeningsCount: EncryptedOpeningsCount},
+	"poi_encrypted-d28-o4": {FileSize: FileSize, MaxTreeDepth: 28, OpeningsCount: EncryptedOpeningsCount},
+}
+
+// DefaultBatchParams is the batch PoI parameter set of the "poi_batch" keys
+// (depth 20, BatchOpeningsCount openings over BatchFileCount files).
+var DefaultBatchParams = Params{
+	FileSize:      FileSize,
+	MaxTreeDepth:  MaxTreeDepth,
+	OpeningsCount: BatchOpeningsCount,
+}
+
+// BatchParamSets maps registry names to the supported batch PoI parameter
+// sets. OpeningsCount is the total over all BatchFileCount files and must be
+// a multiple of BatchFileCount.
+var BatchParamSets = map[string]Params{
+	"poi_batch-d20-o16": DefaultBatchParams,
+	"poi_batch-d24-o16": {FileSize: FileSize, MaxTreeDepth: 24, OpeningsCount: BatchOpeningsCount},
+	"poi_batch-d28-o16": {FileSize: FileSize, MaxTreeDepth: 28, OpeningsCount: BatchOpeningsCount},
+}
+
+// LookupParams returns the parameter set registered under name.
+func LookupParams(name string) (Params, error) {
+	p, ok := ParamSets[name]
+	if !ok {
+		return Params{}, fmt.Errorf("unknown PoI parameter set %q", name)
+	}
+	return p, nil
+}
+
+// LookupEncryptedParams returns the encrypted-storage parameter set
+// registered under name.
+func LookupEncryptedParams(name string) (Params, error) {
+	p, ok := EncryptedParamSets[name]
+	if !ok {
+		return Params{}, fmt.Errorf("unknown encrypted PoI parameter set %q", name)
+	}
+	return p, nil
+}
+
+// LookupBatchParams returns the batch parameter set registered under name.
+func LookupBatchParams(name string) (Params, error) {
+	p, ok := BatchParamSets[name]
+	if !ok {
+		return Params{}, fmt.Errorf("unknown batch PoI parameter set %q", name)
+	}
+	return p, nil
+}
+
+// Name returns the registry name of the parameter set, e.g. "poi-d20-o8".
+// A non-default chunk size is appended as a "-c<KiB>k" suffix.
+func (p Params) Name() string {
+	return p.nameWithPrefix("poi")
+}
+
+// EncryptedName returns the registry name of the parameter set as an
+// encrypted-storage circuit, e.g. "poi_encrypted-d24-o4".
+func (p Params) EncryptedName() string {
+	return p.nameWithPrefix("poi_encrypted")
+}
+
+// BatchName returns the registry name of the parameter set as a batch
+// circuit, e.g. "poi_batch-d24-o16".
+func (p Params) BatchName() string {
+	return p.nameWithPrefix("poi_batch")
+}
+
+func (p Params) nameWithPrefix(prefix string) string {
+	name := fmt.Sprintf("%s-d%d-o%d", prefix, p.MaxTreeDepth, p.OpeningsCount)
+	if p.FileSize != FileSize {
+		name += fmt.Sprintf("-c%dk", p.FileSize/1024)
+	}
+	return name
+}
+
+// NumChunks returns the number of field elements per leaf.
+func (p Params) NumChunks() int {
+	return (p.FileSize + ElementSize - 1) / ElementSize
+}
+
+// TotalLeaves returns the number of leaf slots in the sparse Merkle tree.
+func (p Params) TotalLeaves() int {
+	return 1 << p.MaxTreeDepth
+}
+
+// Validate checks that the parameter set can be compiled into a circuit.
+func (p Params) Validate() error {
+	if p.FileSize <= 0 {
+		return fmt.Errorf("file size must be positive, got %d", p.FileSize)
+	}
+	if p.MaxTreeDepth <= 0 || p.MaxTreeDepth > 32 {
+		return fmt.Errorf("tree depth must be in [1, 32], got %d", p.MaxTreeDepth)
+	}
+	if p.OpeningsCount <= 0 {
+		return fmt.Errorf("openings count must be positive, got %d", p.OpeningsCount)
+	}
+	return nil
+}
+
+// ValidateBatch is Validate for a batch circuit, whose openings must split
+// evenly over the BatchFileCount files.
+func (p Params) ValidateBatch() error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if p.OpeningsCount%BatchFileCount != 0 {
+		return fmt.Errorf("batch openings count must be a multiple of %d, got %d", BatchFileCount, p.OpeningsCount)
+	}
+	return nil
+}
+
+// ExpandsRandomness reports whether the openings need more than
+// RandomnessBits bits of randomness. If so, the raw index of opening k is the
+// low MaxTreeDepth bits of DeriveChallengeIdx(randomness, k) instead of a
+// bit-sliced window of the randomness itself.
+func (p Params) ExpandsRandomness() bool {
+	return p.OpeningsCount*p.MaxTreeDepth > RandomnessBits
+}
+
+var (
+	zeroLeafMu    sync.Mutex
+	zeroLeafCache = map[int]fr.Element{}
+)
+
+// ZeroLeafHash returns the domain-separated padding leaf hash for this
+// parameter set's chunk size. Results are cached per NumChunks.
+func (p Params) ZeroLeafHash() fr.Element {
+	n := p.NumChunks()
+
+	zeroLeafMu.Lock()
+	defer zeroLeafMu.Unlock()
+	if h, ok := zeroLeafCache[n]; ok {
+		return h
+	}
+	h := crypto.ComputeZeroLeafHashFr(ElementSize, n)
+	zeroLeafCache[n] = h
+	return h
+}
+
+// HashChunk hashes a single chunk with DomainTagReal using this parameter
+// set's element count. It is the leaf hash function for GenerateSparseMerkleTree.
+func (p Params) HashChunk(chunk []byte) fr.Element {
+	return crypto.HashLeafFr(crypto.DomainTagReal, chunk, ElementSize, p.NumChunks())
+}
@@ -1,10 +1,16 @@
 package poi_test
 
 import (
+	"bytes"
+	"context"
 	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -62,7 +68,7 @@ func proveAndVerify(t *testing.T, ccs constraint.ConstraintSystem, pk groth16.Pr
 // generates a proof, and verifies it.
 func TestPoICircuitEndToEnd(t *testing.T) {
 	// 1. Compile
-	ccs, err := setup.CompileCircuit(&poi.PoICircuit{})
+	ccs, err := setup.CompileCircuit(poi.NewPoICircuit(poi.DefaultParams))
 	if err != nil {
 		t.Fatalf("compile circuit: %v", err)
 	}
@@ -109,7 +115,7 @@ func TestPoICircuitEndToEnd(t *testing.T) {
 
 // TestPoIMultipleFileSizes verifies the circuit works for various file sizes.
 func TestPoIMultipleFileSizes(t *testing.T) {
-	ccs, err := setup.CompileCircuit(&poi.PoICircuit{})
+	ccs, err := setup.CompileCircuit(poi.NewPoICircuit(poi.DefaultParams))
 	if err != nil {
 		t.Fatalf("compile circuit: %v", err)
 	}
@@ -164,7 +170,7 @@ func TestPoIMultipleFileSizes(t *testing.T) {
 // that it round-trips through JSON.
 func TestPoIExportFixture(t *testing.T) {
 	// 1. Compile and dev setup
-	ccs, err := setup.CompileCircuit(&poi.PoICircuit{})
+	ccs, err := setup.CompileCircuit(poi.NewPoICircuit(poi.DefaultParams))
 	if err != nil {
 		t.Fatalf("compile circuit: %v", err)
 	}
@@ -242,6 +248,139 @@ func TestPrepareWitnessRejectsNumLeavesAboveCapacity(t *testing.T) {
 	}
 }
 
+// TestPrepareWitnessFromCheckpointedSMT checks that a checkpointed tree
+// yields the same witness as the full tree it was saved from.
+func TestPrepareWitnessFromCheckpointedSMT(t *testing.T) {
+	wholeFileData := make([]byte, 40*poi.FileSize)
+	if _, err := rand.Read(wholeFileData); err != nil {
+		t.Fatalf("generate random data: %v", err)
+	}
+	smt, chunks := buildSMT(t, wholeFileData)
+
+	var buf bytes.Buffer
+	if err := smt.SaveCheckpointed(&buf, merkle.SchemeBalanced); err != nil {
+		t.Fatalf("save checkpointed: %v", err)
+	}
+	csmt, err := merkle.LoadCheckpointedSMT(&buf, crypto.ComputeZeroLeafHashFr(poi.ElementSize, poi.NumChunks))
+	if err != nil {
+		t.Fatalf("load checkpointed: %v", err)
+	}
+
+	randomness, err := rand.Int(rand.Reader, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatalf("generate randomness: %v", err)
+	}
+	secretKey, err := crypto.GenerateSecretKey()
+	if err != nil {
+		t.Fatalf("generate secret key: %v", err)
+	}
+
+	want, err := poi.PrepareWitness(secretKey, randomness, chunks, smt)
+	if err != nil {
+		t.Fatalf("prepare witness from full tree: %v", err)
+	}
+	got, err := poi.PrepareWitness(secretKey, randomness, chunks, csmt)
+	if err != nil {
+		t.Fatalf("prepare witness from checkpointed tree: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatal("checkpointed witness differs from full-tree witness")
+	}
+}
+
+// TestPrepareWitnessFromStore checks that a witness prepared from a file on
+// disk and its checkpointed tree matches the in-memory one, and that read
+// failures and mismatched files are reported.
+func TestPrepareWitnessFromStore(t *testing.T) {
+	wholeFileData := make([]byte, 40*poi.FileSize+1234)
+	if _, err := rand.Read(wholeFileData); err != nil {
+		t.Fatalf("generate random data: %v", err)
+	}
+	smt, chunks := buildSMT(t, wholeFileData)
+
+	var buf bytes.Buffer
+	if err := smt.SaveCheckpointed(&buf, merkle.SchemeCompact); err != nil {
+		t.Fatalf("save checkpointed: %v", err)
+	}
+	csmt, err := merkle.LoadCheckpointedSMT(&buf, crypto.ComputeZeroLeafHashFr(poi.ElementSize, poi.NumChunks))
+	if err != nil {
+		t.Fatalf("load checkpointed: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "file.bin")
+	if err := os.WriteFile(path, wholeFileData, 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open file: %v", err)
+	}
+	defer f.Close()
+
+	store, err := poi.NewFileStore(poi.DefaultParams, f, int64(len(wholeFileData)), csmt)
+	if err != nil {
+		t.Fatalf("new file store: %v", err)
+	}
+
+	randomness, err := rand.Int(rand.Reader, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatalf("generate randomness: %v", err)
+	}
+	secretKey, err := crypto.GenerateSecretKey()
+	if err != nil {
+		t.Fatalf("generate secret key: %v", err)
+	}
+
+	want, err := poi.PrepareWitness(secretKey, randomness, chunks, smt)
+	if err != nil {
+		t.Fatalf("prepare witness from memory: %v", err)
+	}
+	got, err := poi.PrepareWitnessFromStore(context.Background(), secretKey, randomness, store)
+	if err != nil {
+		t.Fatalf("prepare witness from store: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatal("store witness differs from in-memory witness")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := poi.PrepareWitnessFromStore(ctx, secretKey, randomness, store); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation error, got %v", err)
+	}
+
+	if _, err := poi.NewFileStore(poi.DefaultParams, f, int64(len(wholeFileData))+int64(poi.FileSize), csmt); err == nil {
+		t.Fatal("expected chunk count mismatch error")
+	}
+	halfChunks, err := merkle.NewFileChunkStore(f, int64(len(wholeFileData)), poi.FileSize/2)
+	if err != nil {
+		t.Fatalf("new chunk store: %v", err)
+	}
+	if _, err := poi.NewStore(poi.DefaultParams, halfChunks, csmt); err == nil {
+		t.Fatal("expected chunk size mismatch error")
+	}
+	// Stores assembled without NewStore are checked as well.
+	if _, err := poi.PrepareWitnessFromStore(context.Background(), secretKey, randomness, poi.Store{ProofSource: csmt, ChunkStore: halfChunks}); err == nil {
+		t.Fatal("expected chunk size mismatch error from unchecked store")
+	}
+	extraChunks, err := merkle.NewFileChunkStore(f, int64(len(wholeFileData))+int64(poi.FileSize), poi.FileSize)
+	if err != nil {
+		t.Fatalf("new chunk store: %v", err)
+	}
+	if _, err := poi.PrepareWitnessFromStore(context.Background(), secretKey, randomness, poi.Store{ProofSource: csmt, ChunkStore: extraChunks}); err == nil {
+		t.Fatal("expected chunk count mismatch error from unchecked store")
+	}
+
+	// A file shorter than the store believes fails to read.
+	short, err := poi.NewFileStore(poi.DefaultParams, bytes.NewReader(wholeFileData[:poi.FileSize]), int64(len(wholeFileData)), csmt)
+	if err != nil {
+		t.Fatalf("new file store: %v", err)
+	}
+	if _, err := poi.PrepareWitnessFromStore(context.Background(), secretKey, randomness, short); err == nil {
+		t.Fatal("expected read error from truncated file")
+	}
+}
+
 func TestPoICircuitRejectsNumLeavesAboveCapacity(t *testing.T) {
 	wholeFileData := make([]byte, 8*poi.FileSize)
 	if _, err := rand.Read(wholeFileData); err != nil {
@@ -264,7 +403,7 @@ func TestPoICircuitRejectsNumLeavesAboveCapacity(t *testing.T) {
 	}
 
 	result.Assignment.NumLeaves = poi.TotalLeaves + 1
-	ccs, err := setup.CompileCircuit(&poi.PoICircuit{})
+	ccs, err := setup.CompileCircuit(poi.NewPoICircuit(poi.DefaultParams))
 	if err != nil {
 		t.Fatalf("compile circuit: %v", err)
 	}
@@ -277,3 +416,106 @@ func TestPoICircuitRejectsNumLeavesAboveCapacity(t *testing.T) {
 		t.Fatal("expected circuit to reject oversized numLeaves")
 	}
 }
+
+// TestPoIParamSets checks that every registered parameter set is valid and
+// registered under its own name.
+func TestPoIParamSets(t *testing.T) {
+	for name, p := range poi.ParamSets {
+		if err := p.Validate(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if p.Name() != name {
+			t.Fatalf("param set registered as %q but named %q", name, p.Name())
+		}
+	}
+	if poi.DefaultParams.Name() != "poi-d20-o8" {
+		t.Fatalf("unexpected default params name %q", poi.DefaultParams.Name())
+	}
+
+	if poi.DefaultParams.ExpandsRandomness() {
+		t.Fatal("default params must use bit-sliced randomness")
+	}
+	if !poi.ParamSets["poi-d24-o16"].ExpandsRandomness() {
+		t.Fatal("poi-d24-o16 needs 384 randomness bits and must expand")
+	}
+	if _, err := setup.CompileCircuit(&poi.PoICircuit{}); err == nil {
+		t.Fatal("expected compile error for unallocated circuit")
+	}
+}
+
+// TestPoICustomParams solves small non-default parameter sets end to end and
+// checks that a tampered witness fails. The second set needs 256 randomness
+// bits and exercises DeriveChallengeIdx expansion.
+func TestPoICustomParams(t *testing.T) {
+	for _, p := range []poi.Params{
+		{FileSize: 1024, MaxTreeDepth: 8, OpeningsCount: 4},
+		{FileSize: 1024, MaxTreeDepth: 16, OpeningsCount: 16},
+	} {
+		t.Run(p.Name(), func(t *testing.T) {
+			testPoICustomParams(t, p)
+		})
+	}
+}
+
+func testPoICustomParams(t *testing.T, p poi.Params) {
+	data := make([]byte, 5*p.FileSize)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatalf("generate random data: %v", err)
+	}
+	chunks := merkle.SplitIntoChunks(data, p.FileSize)
+	smt, err := merkle.GenerateSparseMerkleTree(chunks, p.MaxTreeDepth, p.HashChunk, p.ZeroLeafHash())
+	if err != nil {
+		t.Fatalf("build SMT: %v", err)
+	}
+
+	randomness, err := rand.Int(rand.Reader, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatalf("generate randomness: %v", err)
+	}
+	secretKey, err := crypto.GenerateSecretKey()
+	if err != nil {
+		t.Fatalf("generate secret key: %v", err)
+	}
+
+	result, err := poi.PrepareWitnessWithParams(p, secretKey, randomness, chunks, smt)
+	if err != nil {
+		t.Fatalf("prepare witness: %v", err)
+	}
+	if len(result.ChunkIndices) != p.OpeningsCount {
+		t.Fatalf("got %d chunk indices, expected %d", len(result.ChunkIndices), p.OpeningsCount)
+	}
+
+	ccs, err := setup.CompileCircuit(poi.NewPoICircuit(p))
+	if err != nil {
+		t.Fatalf("compile circuit: %v", err)
+	}
+	witness, err := frontend.NewWitness(&result.Assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatalf("create witness: %v", err)
+	}
+	if err := ccs.IsSolved(witness); err != nil {
+		t.Fatalf("circuit not solved: %v", err)
+	}
+
+	// A tree over the same chunks at another depth must be rejected.
+	deeperSMT, err := merkle.GenerateSparseMerkleTree(chunks, p.MaxTreeDepth+1, p.HashChunk, p.ZeroLeafHash())
+	if err != nil {
+		t.Fatalf("build deeper SMT: %v", err)
+	}
+	_, err = poi.PrepareWitnessWithParams(p, secretKey, randomness, chunks, deeperSMT)
+	if err == nil {
+		t.Fatal("expected depth mismatch error")
+	}
+	if !strings.Contains(err.Error(), "does not match params depth") {
+		t.Fatalf("expected depth mismatch error, got %v", err)
+	}
+
+	result.Assignment.Bytes[0][0] = 1
+	tampered, err := frontend.NewWitness(&result.Assignment, ecc.BN254.ScalarField())
+	if err != nil {
+		t.Fatalf("create tampered witness: %v", err)
+	}
+	if err := ccs.IsSolved(tampered); err == nil {
+		t.Fatal("expected tampered chunk to be rejected")
+	}
+}
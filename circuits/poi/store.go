@@ -0,0 +1,86 @@
+package poi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/MuriData/muri-zkproof/pkg/merkle"
+)
+
+// WitnessStore gives PrepareWitnessFromStore access to a stored file and its
+// Merkle tree, so a prover answers a challenge without loading the file or a
+// fully materialized tree. The embedded ProofSource is typically a
+// *merkle.CheckpointedSMT; its proofs are rebuilt from chunks read through
+// the embedded ChunkStore.
+type WitnessStore interface {
+	merkle.ProofSource
+	merkle.ChunkStore
+}
+
+// Store is a WitnessStore pairing any merkle.ChunkStore (a flat file, a
+// chunk directory, an HTTP object) with the file's tree.
+type Store struct {
+	merkle.ProofSource
+	merkle.ChunkStore
+}
+
+// NewStore pairs chunks with tree. The chunk size must be p.FileSize and the
+// chunk count must match the tree's leaf count.
+func NewStore(p Params, chunks merkle.ChunkStore, tree merkle.ProofSource) (*Store, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if err := checkChunks(p, chunks, tree.Info()); err != nil {
+		return nil, err
+	}
+	return &Store{ProofSource: tree, ChunkStore: chunks}, nil
+}
+
+// checkChunks checks that chunks holds the p.FileSize-byte leaves of the
+// tree with the given info.
+func checkChunks(p Params, chunks merkle.ChunkStore, info merkle.TreeInfo) error {
+	if chunks.ChunkSize() != p.FileSize {
+		return fmt.Errorf("store chunk size is %d bytes, expected %d", chunks.ChunkSize(), p.FileSize)
+	}
+	if numChunks := chunks.NumChunks(); numChunks != info.NumLeaves {
+		return fmt.Errorf("file has %d chunks but tree has %d leaves", numChunks, info.NumLeaves)
+	}
+	return nil
+}
+
+// NewFileStore returns a store serving the size-byte file read through file
+// (e.g. an *os.File) in chunks of p.FileSize bytes.
+func NewFileStore(p Params, file io.ReaderAt, size int64, tree merkle.ProofSource) (*Store, error) {
+	chunks, err := merkle.NewFileChunkStore(file, size, p.FileSize)
+	if err != nil {
+		return nil, err
+	}
+	return NewStore(p, chunks, tree)
+}
+
+// PrepareWitnessFromStore is PrepareWitness for a file and tree held in a
+// WitnessStore, for DefaultParams. Only the opened chunks and those needed to
+// rebuild their proofs are read. Cancelling ctx (e.g. at the challenge
+// deadline) aborts the proof rebuild.
+func PrepareWitnessFromStore(ctx context.Context, secretKey, randomness *big.Int, store WitnessStore) (*WitnessResult, error) {
+	return PrepareWitnessFromStoreWithParams(ctx, DefaultParams, secretKey, randomness, store)
+}
+
+// PrepareWitnessFromStoreWithParams is PrepareWitnessFromStore for an
+// arbitrary parameter set. The store's chunk size must be p.FileSize and its
+// chunk count must match the tree's leaf count.
+func PrepareWitnessFromStoreWithParams(ctx context.Context, p Params, secretKey, randomness *big.Int, store WitnessStore) (*WitnessResult, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	info := store.Info()
+	if err := checkTree(p, info); err != nil {
+		return nil, err
+	}
+	if err := checkChunks(p, store, info); err != nil {
+		return nil, err
+	}
+	return prepareWitness(ctx, p, secretKey, randomness, store, store)
+}
@@ -1,6 +1,7 @@
 package poi
 
 import (
+	"context"
 	"fmt"
 	"math/big"
 	"sync"
@@ -16,7 +17,7 @@ import (
 // public values that callers typically need for logging or fixture export.
 type WitnessResult struct {
 	Assignment   PoICircuit
-	ChunkIndices [OpeningsCount]int // leafIndex (into original chunks) per opening
+	ChunkIndices []int // leafIndex (into original chunks) per opening
 	NumLeaves    int
 	PublicKey    *big.Int
 	Commitment   *big.Int
@@ -24,120 +25,183 @@ type WitnessResult struct {
 }
 
 // PrepareWitness derives all public and private witness values from the
-// minimal independent inputs and returns a ready-to-use circuit assignment.
+// minimal independent inputs and returns a ready-to-use circuit assignment
+// for DefaultParams. tree may be a full *merkle.SparseMerkleTree or a
+// *merkle.CheckpointedSMT, whose opened paths are rebuilt from chunks.
+func PrepareWitness(secretKey, randomness *big.Int, chunks [][]byte, tree merkle.ProofSource) (*WitnessResult, error) {
+	return PrepareWitnessWithParams(DefaultParams, secretKey, randomness, chunks, tree)
+}
+
+// PrepareWitnessWithParams is PrepareWitness for an arbitrary parameter set.
+// tree must have been built with depth p.MaxTreeDepth and p.HashChunk.
 //
-// For each of the OpeningsCount openings, a raw 20-bit index is extracted from
-// the randomness, then reduced modulo numLeaves to select a real chunk.
-func PrepareWitness(secretKey, randomness *big.Int, chunks [][]byte, smt *merkle.SparseMerkleTree) (*WitnessResult, error) {
-	if smt.NumLeaves == 0 {
-		return nil, fmt.Errorf("sparse merkle tree has no leaves")
+// For each of the p.OpeningsCount openings, a raw MaxTreeDepth-bit index is
+// extracted from the randomness (or from DeriveChallengeIdx when
+// p.ExpandsRandomness), then reduced modulo numLeaves to select a real chunk.
+// All openings are then proven in one tree.Proofs call, so a checkpointed
+// tree rebuilds shared gap subtrees once.
+func PrepareWitnessWithParams(p Params, secretKey, randomness *big.Int, chunks [][]byte, tree merkle.ProofSource) (*WitnessResult, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
 	}
-	if smt.NumLeaves > TotalLeaves {
-		return nil, fmt.Errorf("numLeaves %d exceeds circuit capacity %d", smt.NumLeaves, TotalLeaves)
+	info := tree.Info()
+	if err := checkTree(p, info); err != nil {
+		return nil, err
 	}
 	if len(chunks) == 0 {
 		return nil, fmt.Errorf("no chunks provided")
 	}
-	if len(chunks) != smt.NumLeaves {
-		return nil, fmt.Errorf("chunk count %d does not match tree numLeaves %d", len(chunks), smt.NumLeaves)
+	if len(chunks) != info.NumLeaves {
+		return nil, fmt.Errorf("chunk count %d does not match tree numLeaves %d", len(chunks), info.NumLeaves)
 	}
+	store, err := merkle.NewMemChunkStore(chunks, p.FileSize)
+	if err != nil {
+		return nil, err
+	}
+	return prepareWitness(context.Background(), p, secretKey, randomness, tree, store)
+}
 
-	numLeaves := smt.NumLeaves
+// checkTree checks that a tree with the given info fits parameter set p.
+func checkTree(p Params, info merkle.TreeInfo) error {
+	if info.NumLeaves == 0 {
+		return fmt.Errorf("sparse merkle tree has no leaves")
+	}
+	if info.NumLeaves > p.TotalLeaves() {
+		return fmt.Errorf("numLeaves %d exceeds circuit capacity %d", info.NumLeaves, p.TotalLeaves())
+	}
+	if info.Depth != p.MaxTreeDepth {
+		return fmt.Errorf("tree depth %d does not match params depth %d", info.Depth, p.MaxTreeDepth)
+	}
+	return nil
+}
+
+// openedChunks serves the chunks already read for the openings from memory
+// and reads any other chunk from the underlying store.
+type openedChunks struct {
+	merkle.ChunkStore
+	opened map[int][]byte
+}
+
+func (o openedChunks) ReadChunk(ctx context.Context, i int) ([]byte, error) {
+	if data, ok := o.opened[i]; ok {
+		return data, nil
+	}
+	return o.ChunkStore.ReadChunk(ctx, i)
+}
+
+// prepareWitness builds the witness once the tree and chunks have been
+// checked. Only the opened chunks, and those the tree needs to rebuild their
+// paths, are read.
+func prepareWitness(ctx context.Context, p Params, secretKey, randomness *big.Int, tree merkle.ProofSource, chunks merkle.ChunkStore) (*WitnessResult, error) {
+	info := tree.Info()
+	numLeaves := info.NumLeaves
 	publicKey := crypto.DerivePublicKey(secretKey)
 
-	var assignment PoICircuit
+	assignment := NewPoICircuit(p)
 	assignment.SecretKey = secretKey
 	assignment.Randomness = randomness
 	assignment.PublicKey = publicKey
-	assignment.RootHash = smt.Root
+	assignment.RootHash = info.Root
 	assignment.NumLeaves = numLeaves
 
-	var chunkIndices [OpeningsCount]int
-	var leafHashes [OpeningsCount]fr.Element
+	chunkIndices := make([]int, p.OpeningsCount)
+	leafHashes := make([]fr.Element, p.OpeningsCount)
+	quotients := make([]*big.Int, p.OpeningsCount)
+	leafIndices := make([]*big.Int, p.OpeningsCount)
 
 	numLeavesBig := big.NewInt(int64(numLeaves))
 
+	for k := 0; k < p.OpeningsCount; k++ {
+		// Derive rawIndex from window [k*MaxTreeDepth .. (k+1)*MaxTreeDepth-1]
+		// of the randomness, or of H(randomness, k) when expanded.
+		source, bitOffset := randomness, k*p.MaxTreeDepth
+		if p.ExpandsRandomness() {
+			source, bitOffset = crypto.DeriveChallengeIdx(randomness, big.NewInt(int64(k))), 0
+		}
+		var rawIndex int64
+		for i := 0; i < p.MaxTreeDepth; i++ {
+			bit := source.Bit(bitOffset + i)
+			rawIndex |= int64(bit) << i
+		}
+
+		// Modular reduction: leafIndex = rawIndex % numLeaves.
+		rawIndexBig := big.NewInt(rawIndex)
+		quotients[k] = new(big.Int).Div(rawIndexBig, numLeavesBig)
+		leafIndices[k] = new(big.Int).Mod(rawIndexBig, numLeavesBig)
+		chunkIndices[k] = int(leafIndices[k].Int64())
+	}
+
+	// Read each opened chunk once; the proof rebuild below reuses them.
+	opened := make(map[int][]byte, p.OpeningsCount)
+	for _, idx := range chunkIndices {
+		if _, ok := opened[idx]; ok {
+			continue
+		}
+		data, err := chunks.ReadChunk(ctx, idx)
+		if err != nil {
+			return nil, fmt.Errorf("read chunk %d: %w", idx, err)
+		}
+		opened[idx] = data
+	}
+
+	// Merkle proofs for all openings in one batch.
+	proofs, err := tree.Proofs(ctx, chunkIndices, openedChunks{ChunkStore: chunks, opened: opened}, p.HashChunk)
+	if err != nil {
+		return nil, fmt.Errorf("merkle proofs: %w", err)
+	}
+
 	// Per-opening results collected by parallel goroutines.
 	type openingResult struct {
-		chunkIndex  int
-		bytesArray  [NumChunks]frontend.Variable
-		quotient    *big.Int
-		leafIndex   *big.Int
+		bytesArray  []frontend.Variable
 		merkleProof MerkleProofCircuit
-		leafHash    fr.Element
 	}
-	var results [OpeningsCount]openingResult
+	results := make([]openingResult, p.OpeningsCount)
 
-	// The 8 openings are independent — compute them in parallel.
+	// The openings are independent — convert them in parallel.
 	var wg sync.WaitGroup
-	for k := 0; k < OpeningsCount; k++ {
+	for k := 0; k < p.OpeningsCount; k++ {
 		wg.Add(1)
 		go func(k int) {
 			defer wg.Done()
 
-			// Derive rawIndex from 20-bit window [k*MaxTreeDepth .. k*MaxTreeDepth+19].
-			bitOffset := k * MaxTreeDepth
-			var rawIndex int64
-			for i := 0; i < MaxTreeDepth; i++ {
-				bit := randomness.Bit(bitOffset + i)
-				rawIndex |= int64(bit) << i
-			}
-
-			// Modular reduction: leafIndex = rawIndex % numLeaves.
-			rawIndexBig := big.NewInt(rawIndex)
-			quotientBig := new(big.Int).Div(rawIndexBig, numLeavesBig)
-			leafIndexBig := new(big.Int).Mod(rawIndexBig, numLeavesBig)
-			leafIndex := int(leafIndexBig.Int64())
-
-			chunkData := chunks[leafIndex]
-
-			// Merkle proof for this opening from the SMT.
-			siblings, directions := smt.GetProof(leafIndex)
-
-			var proofPath [MaxTreeDepth]frontend.Variable
-			var proofDirections [MaxTreeDepth]frontend.Variable
-			for i := 0; i < MaxTreeDepth; i++ {
-				proofPath[i] = siblings[i]
-				proofDirections[i] = directions[i]
+			proof := proofs[k]
+			proofPath := make([]frontend.Variable, p.MaxTreeDepth)
+			proofDirections := make([]frontend.Variable, p.MaxTreeDepth)
+			for i := 0; i < p.MaxTreeDepth; i++ {
+				proofPath[i] = proof.Siblings[i]
+				proofDirections[i] = proof.Directions[i]
 			}
 
 			// Convert chunk bytes to field elements.
-			fieldSlice := field.Bytes2Field(chunkData, NumChunks, ElementSize)
-			var bytesArray [NumChunks]frontend.Variable
-			copy(bytesArray[:], fieldSlice)
+			bytesArray := field.Bytes2Field(opened[chunkIndices[k]], p.NumChunks(), ElementSize)
 
 			results[k] = openingResult{
-				chunkIndex: leafIndex,
 				bytesArray: bytesArray,
-				quotient:   quotientBig,
-				leafIndex:  leafIndexBig,
 				merkleProof: MerkleProofCircuit{
-					RootHash:   smt.Root,
-					LeafValue:  smt.GetLeafHash(leafIndex),
+					RootHash:   info.Root,
+					LeafValue:  proof.LeafHash,
 					ProofPath:  proofPath,
 					Directions: proofDirections,
 				},
-				leafHash: smt.GetLeafHash(leafIndex),
 			}
 		}(k)
 	}
 	wg.Wait()
 
 	// Collect results into assignment.
-	for k := 0; k < OpeningsCount; k++ {
+	for k := 0; k < p.OpeningsCount; k++ {
 		r := &results[k]
-		chunkIndices[k] = r.chunkIndex
-		leafHashes[k] = r.leafHash
+		leafHashes[k] = proofs[k].LeafHash
 		assignment.Bytes[k] = r.bytesArray
-		assignment.Quotients[k] = r.quotient
-		assignment.LeafIndices[k] = r.leafIndex
+		assignment.Quotients[k] = quotients[k]
+		assignment.LeafIndices[k] = leafIndices[k]
 		assignment.MerkleProofs[k] = r.merkleProof
 	}
 
 	// Aggregate message and commitment.
 	// Convert fr.Element leaf hashes to *big.Int for DeriveAggMsg.
-	leafHashesBig := make([]*big.Int, OpeningsCount)
-	for i := 0; i < OpeningsCount; i++ {
+	leafHashesBig := make([]*big.Int, p.OpeningsCount)
+	for i := 0; i < p.OpeningsCount; i++ {
 		leafHashesBig[i] = new(big.Int)
 		leafHashes[i].BigInt(leafHashesBig[i])
 	}
@@ -146,7 +210,7 @@ func PrepareWitness(secretKey, randomness *big.Int, chunks [][]byte, smt *merkle
 	assignment.Commitment = commitment
 
 	return &WitnessResult{
-		Assignment:   assignment,
+		Assignment:   *assignment,
 		ChunkIndices: chunkIndices,
 		NumLeaves:    numLeaves,
 		PublicKey:    publicKey,
@@ -156,7 +220,8 @@ func PrepareWitness(secretKey, randomness *big.Int, chunks [][]byte, smt *merkle
 }
 
 // HashChunk hashes a single chunk using Poseidon2 with domain tag = 1
-// (real leaf). This is the leaf hash function used by the sparse Merkle tree.
+// (real leaf). This is the leaf hash function used by the sparse Merkle tree
+// for DefaultParams.
 func HashChunk(chunk []byte) fr.Element {
-	return crypto.HashLeafFr(crypto.DomainTagReal, chunk, ElementSize, NumChunks)
+	return DefaultParams.HashChunk(chunk)
 }
@@ -0,0 +1,80 @@
+package retrieval
+
+import (
+	"fmt"
+
+	"github.com/MuriData/muri-zkproof/circuits/poi"
+	"github.com/MuriData/muri-zkproof/circuits/shared"
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/consensys/gnark/frontend"
+)
+
+// zeroLeafHash is the domain-separated hash for padding leaves, computed once
+// at package init. It is used as a circuit constant.
+var zeroLeafHash fr.Element
+
+func init() {
+	zeroLeafHash = crypto.ComputeZeroLeafHashFr(ElementSize, NumChunks)
+}
+
+// RetrievalCircuit proves that a served chunk is leaf LeafIndex of the file
+// committed to by RootHash. ChunkHash is the leaf hash
+// H(DomainTagReal, chunk elements) the client recomputes from the bytes it
+// received, so a proof is a succinct delivery receipt for exactly that chunk.
+//
+// It checks:
+//   - chunkHash != zeroLeaf (a padding leaf is not a deliverable chunk)
+//   - leafIndex in [0, 2^Depth); its bits are the Merkle path directions
+//   - the proof path from chunkHash reconstructs rootHash
+//
+// The proof path is sized from Depth, so instances must be built with
+// NewRetrievalCircuit (or PrepareWitness).
+type RetrievalCircuit struct {
+	// Public inputs (3)
+	RootHash  frontend.Variable `gnark:"rootHash,public"`
+	LeafIndex frontend.Variable `gnark:"leafIndex,public"`
+	ChunkHash frontend.Variable `gnark:"chunkHash,public"`
+
+	// Private inputs: sibling hashes from the leaf up to the root.
+	ProofPath []frontend.Variable `gnark:"proofPath"`
+
+	// Depth fixes the circuit shape; it is not part of the witness.
+	Depth int `gnark:"-"`
+}
+
+// NewRetrievalCircuit allocates a RetrievalCircuit for a tree of the given depth.
+func NewRetrievalCircuit(depth int) *RetrievalCircuit {
+	return &RetrievalCircuit{
+		ProofPath: make([]frontend.Variable, depth),
+		Depth:     depth,
+	}
+}
+
+func (circuit *RetrievalCircuit) Define(api frontend.API) error {
+	depth := circuit.Depth
+	if depth <= 0 || len(circuit.ProofPath) != depth {
+		return fmt.Errorf("proof path has %d levels, expected depth %d (use NewRetrievalCircuit)", len(circuit.ProofPath), depth)
+	}
+
+	sponge, err := shared.NewSpongeHasher(api)
+	if err != nil {
+		return err
+	}
+
+	// 1. The chunk must be real data, not padding.
+	api.AssertIsEqual(api.IsZero(api.Sub(circuit.ChunkHash, frontend.Variable(zeroLeafHash))), 0)
+
+	// 2. Direction bits are the binary decomposition of leafIndex, which
+	//    also range-checks leafIndex to [0, 2^Depth).
+	directions := api.ToBinary(circuit.LeafIndex, depth)
+
+	// 3. Merkle membership of chunkHash at leafIndex.
+	proof := poi.MerkleProofCircuit{
+		RootHash:   circuit.RootHash,
+		LeafValue:  circuit.ChunkHash,
+		ProofPath:  circuit.ProofPath,
+		Directions: directions,
+	}
+	return proof.Define(api, sponge)
+}
@@ -0,0 +1,152 @@
+package retrieval_test
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/MuriData/muri-zkproof/circuits/retrieval"
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/MuriData/muri-zkproof/pkg/merkle"
+	"github.com/MuriData/muri-zkproof/pkg/setup"
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/consensys/gnark/backend/groth16"
+	"github.com/consensys/gnark/frontend"
+)
+
+// buildSMT is a test helper that splits data into chunks and builds a
+// sparse Merkle tree with domain-separated leaf hashing.
+func buildSMT(t *testing.T, data []byte) (*merkle.SparseMerkleTree, [][]byte) {
+	t.Helper()
+	chunks := merkle.SplitIntoChunks(data, retrieval.FileSize)
+	zeroLeaf := crypto.ComputeZeroLeafHashFr(retrieval.ElementSize, retrieval.NumChunks)
+	smt, err := merkle.GenerateSparseMerkleTree(chunks, retrieval.MaxTreeDepth, retrieval.HashChunk, zeroLeaf)
+	if err != nil {
+		t.Fatalf("build SMT: %v", err)
+	}
+	return smt, chunks
+}
+
+// TestRetrievalCircuitEndToEnd proves delivery of every chunk of a small
+// file and checks that receipts for the wrong chunk or index are rejected.
+func TestRetrievalCircuitEndToEnd(t *testing.T) {
+	ccs, err := setup.CompileCircuit(retrieval.NewRetrievalCircuit(retrieval.MaxTreeDepth))
+	if err != nil {
+		t.Fatalf("compile circuit: %v", err)
+	}
+	pk, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		t.Fatalf("groth16 setup: %v", err)
+	}
+
+	data := make([]byte, 5*retrieval.FileSize)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatalf("generate random data: %v", err)
+	}
+	smt, chunks := buildSMT(t, data)
+
+	for i, chunk := range chunks {
+		result, err := retrieval.PrepareWitness(smt, i)
+		if err != nil {
+			t.Fatalf("prepare witness: %v", err)
+		}
+		// The client recomputes the public chunk hash from the served bytes.
+		if result.ChunkHash != retrieval.HashChunk(chunk) {
+			t.Fatalf("chunk %d: hash mismatch", i)
+		}
+
+		witness, err := frontend.NewWitness(&result.Assignment, ecc.BN254.ScalarField())
+		if err != nil {
+			t.Fatalf("create witness: %v", err)
+		}
+		publicWitness, err := witness.Public()
+		if err != nil {
+			t.Fatalf("extract public witness: %v", err)
+		}
+		proof, err := groth16.Prove(ccs, pk, witness)
+		if err != nil {
+			t.Fatalf("chunk %d: prove: %v", i, err)
+		}
+		if err := groth16.Verify(proof, vk, publicWitness); err != nil {
+			t.Fatalf("chunk %d: verify: %v", i, err)
+		}
+	}
+
+	result, err := retrieval.PrepareWitness(smt, 1)
+	if err != nil {
+		t.Fatalf("prepare witness: %v", err)
+	}
+	zeroLeaf := crypto.ComputeZeroLeafHashFr(retrieval.ElementSize, retrieval.NumChunks)
+	cases := map[string]func(a *retrieval.RetrievalCircuit){
+		"wrong index":   func(a *retrieval.RetrievalCircuit) { a.LeafIndex = 2 },
+		"wrong chunk":   func(a *retrieval.RetrievalCircuit) { a.ChunkHash = retrieval.HashChunk(chunks[2]) },
+		"padding leaf":  func(a *retrieval.RetrievalCircuit) { a.ChunkHash = zeroLeaf },
+		"index too big": func(a *retrieval.RetrievalCircuit) { a.LeafIndex = 1 + retrieval.TotalLeaves },
+	}
+	for name, tamper := range cases {
+		assignment := result.Assignment
+		tamper(&assignment)
+		witness, err := frontend.NewWitness(&assignment, ecc.BN254.ScalarField())
+		if err != nil {
+			t.Fatalf("%s: create witness: %v", name, err)
+		}
+		if err := ccs.IsSolved(witness); err == nil {
+			t.Fatalf("%s: expected witness to be rejected", name)
+		}
+	}
+
+	if _, err := retrieval.PrepareWitness(smt, len(chunks)); err == nil {
+		t.Fatal("expected PrepareWitness to reject an index past the last chunk")
+	}
+}
+
+// TestRetrievalExportFixture generates a deterministic fixture and verifies
+// that it round-trips through JSON.
+func TestRetrievalExportFixture(t *testing.T) {
+	// 1. Compile and dev setup
+	ccs, err := setup.CompileCircuit(retrieval.NewRetrievalCircuit(retrieval.MaxTreeDepth))
+	if err != nil {
+		t.Fatalf("compile circuit: %v", err)
+	}
+	pk, vk, err := groth16.Setup(ccs)
+	if err != nil {
+		t.Fatalf("groth16 setup: %v", err)
+	}
+
+	// 2. Write keys to temp directory
+	tmpDir := t.TempDir()
+	if err := setup.ExportKeys(pk, vk, tmpDir, "retrieval"); err != nil {
+		t.Fatalf("export keys: %v", err)
+	}
+
+	// 3. Generate fixture
+	jsonOut, err := retrieval.ExportProofFixture(tmpDir)
+	if err != nil {
+		t.Fatalf("export proof fixture: %v", err)
+	}
+
+	// 4. Verify JSON round-trips
+	var fixture retrieval.ProofFixture
+	if err := json.Unmarshal(jsonOut, &fixture); err != nil {
+		t.Fatalf("unmarshal fixture: %v", err)
+	}
+	if fixture.RootHash == "" || fixture.LeafIndex == "" || fixture.ChunkHash == "" {
+		t.Fatal("fixture public inputs are empty")
+	}
+	for i, p := range fixture.SolidityProof {
+		if p == "" {
+			t.Fatalf("fixture solidity proof[%d] is empty", i)
+		}
+	}
+
+	jsonRoundTrip, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		t.Fatalf("re-marshal fixture: %v", err)
+	}
+	if string(jsonRoundTrip) != string(jsonOut) {
+		t.Fatal("fixture JSON round-trip mismatch")
+	}
+
+	fmt.Println("Fixture round-trip OK")
+}
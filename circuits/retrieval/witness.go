@@ -0,0 +1,46 @@
+package retrieval
+
+import (
+	"fmt"
+
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/MuriData/muri-zkproof/pkg/merkle"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// WitnessResult holds the fully populated circuit assignment.
+type WitnessResult struct {
+	Assignment RetrievalCircuit
+	ChunkHash  fr.Element
+}
+
+// PrepareWitness builds a delivery receipt witness for leaf leafIndex of smt.
+// The circuit depth is taken from smt.Depth.
+func PrepareWitness(smt *merkle.SparseMerkleTree, leafIndex int) (*WitnessResult, error) {
+	if leafIndex < 0 || leafIndex >= smt.NumLeaves {
+		return nil, fmt.Errorf("leaf index %d out of range [0, %d)", leafIndex, smt.NumLeaves)
+	}
+
+	chunkHash := smt.GetLeafHash(leafIndex)
+	siblings, _ := smt.GetProof(leafIndex)
+
+	assignment := *NewRetrievalCircuit(smt.Depth)
+	assignment.RootHash = smt.Root
+	assignment.LeafIndex = leafIndex
+	assignment.ChunkHash = chunkHash
+	for i := 0; i < smt.Depth; i++ {
+		assignment.ProofPath[i] = siblings[i]
+	}
+
+	return &WitnessResult{
+		Assignment: assignment,
+		ChunkHash:  chunkHash,
+	}, nil
+}
+
+// HashChunk hashes a single chunk using Poseidon2 with domain tag = 1
+// (real leaf). Clients apply it to the bytes they received to obtain the
+// public chunkHash.
+func HashChunk(chunk []byte) fr.Element {
+	return crypto.HashLeafFr(crypto.DomainTagReal, chunk, ElementSize, NumChunks)
+}
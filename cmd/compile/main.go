@@ -5,9 +5,13 @@ import (
 	"log"
 	"os"
 
+	archivemuri "github.com/MuriData/muri-zkproof/circuits/archive_muri"
+	archivepoi "github.com/MuriData/muri-zkproof/circuits/archive_poi"
 	"github.com/MuriData/muri-zkproof/circuits/fsp"
 	"github.com/MuriData/muri-zkproof/circuits/keyleak"
+	"github.com/MuriData/muri-zkproof/circuits/keyrotate"
 	"github.com/MuriData/muri-zkproof/circuits/poi"
+	"github.com/MuriData/muri-zkproof/circuits/retrieval"
 	"github.com/MuriData/muri-zkproof/pkg/setup"
 	"github.com/consensys/gnark/frontend"
 )
@@ -20,9 +24,55 @@ type CircuitEntry struct {
 
 // circuitRegistry maps circuit names to their entries.
 var circuitRegistry = map[string]CircuitEntry{
-	"poi":     {NewCircuit: func() frontend.Circuit { return &poi.PoICircuit{} }, Backend: setup.Groth16Backend},
-	"fsp":     {NewCircuit: func() frontend.Circuit { return &fsp.FSPCircuit{} }, Backend: setup.Groth16Backend},
-	"keyleak": {NewCircuit: func() frontend.Circuit { return &keyleak.KeyLeakCircuit{} }, Backend: setup.PlonkBackend},
+	"poi":                 {NewCircuit: func() frontend.Circuit { return poi.NewPoICircuit(poi.DefaultParams) }, Backend: setup.Groth16Backend},
+	"poi_batch":           {NewCircuit: func() frontend.Circuit { return poi.NewBatchPoICircuit(poi.DefaultBatchParams) }, Backend: setup.Groth16Backend},
+	"poi_encrypted":       {NewCircuit: func() frontend.Circuit { return poi.NewEncryptedPoICircuit(poi.DefaultEncryptedParams) }, Backend: setup.Groth16Backend},
+	"fsp":                 {NewCircuit: func() frontend.Circuit { return fsp.NewFSPCircuit(fsp.MaxTreeDepth) }, Backend: setup.Groth16Backend},
+	"retrieval":           {NewCircuit: func() frontend.Circuit { return retrieval.NewRetrievalCircuit(retrieval.MaxTreeDepth) }, Backend: setup.Groth16Backend},
+	"keyleak":             {NewCircuit: func() frontend.Circuit { return &keyleak.KeyLeakCircuit{} }, Backend: setup.PlonkBackend},
+	"keyrotate":           {NewCircuit: func() frontend.Circuit { return &keyrotate.KeyRotateCircuit{} }, Backend: setup.PlonkBackend},
+	"archive_muri":        {NewCircuit: func() frontend.Circuit { return &archivemuri.ArchiveMuriCircuit{} }, Backend: setup.Groth16Backend},
+	"archive_poi":         {NewCircuit: func() frontend.Circuit { return &archivepoi.ArchivePoICircuit{} }, Backend: setup.Groth16Backend},
+	"archive_replication": {NewCircuit: func() frontend.Circuit { return &archivemuri.ReplicationCircuit{} }, Backend: setup.Groth16Backend},
+}
+
+// Register every PoI parameter set (poi-d20-o8, poi_batch-d24-o16,
+// poi_encrypted-d24-o4, ...) and FSP depth (fsp-d24, fsp_bytes-d24, ...) as
+// its own circuit. "poi", "poi_batch", "poi_encrypted", "fsp", "fsp_bytes"
+// and "fsp_append" remain the names of the default depth-20 keys.
+func init() {
+	for name, p := range poi.ParamSets {
+		circuitRegistry[name] = CircuitEntry{
+			NewCircuit: func() frontend.Circuit { return poi.NewPoICircuit(p) },
+			Backend:    setup.Groth16Backend,
+		}
+	}
+	for name, p := range poi.BatchParamSets {
+		circuitRegistry[name] = CircuitEntry{
+			NewCircuit: func() frontend.Circuit { return poi.NewBatchPoICircuit(p) },
+			Backend:    setup.Groth16Backend,
+		}
+	}
+	for name, p := range poi.EncryptedParamSets {
+		circuitRegistry[name] = CircuitEntry{
+			NewCircuit: func() frontend.Circuit { return poi.NewEncryptedPoICircuit(p) },
+			Backend:    setup.Groth16Backend,
+		}
+	}
+	for _, depth := range fsp.SupportedDepths {
+		circuitRegistry[fsp.CircuitName(depth)] = CircuitEntry{
+			NewCircuit: func() frontend.Circuit { return fsp.NewFSPCircuit(depth) },
+			Backend:    setup.Groth16Backend,
+		}
+		circuitRegistry[fsp.BytesCircuitName(depth)] = CircuitEntry{
+			NewCircuit: func() frontend.Circuit { return fsp.NewFSPBytesCircuit(depth) },
+			Backend:    setup.Groth16Backend,
+		}
+		circuitRegistry[fsp.AppendCircuitName(depth)] = CircuitEntry{
+			NewCircuit: func() frontend.Circuit { return fsp.NewFSPAppendCircuit(depth) },
+			Backend:    setup.Groth16Backend,
+		}
+	}
 }
 
 func main() {
@@ -120,7 +170,7 @@ func printUsage() {
   go run ./cmd/compile <circuit> ceremony p2-contribute      Add a Phase 2 contribution
   go run ./cmd/compile <circuit> ceremony p2-verify HEX      Verify Phase 2, seal & export keys
 
-Available circuits: poi (Groth16), fsp (Groth16), keyleak (PLONK)
+Available circuits: poi (Groth16), poi-d<depth>-o<openings> (Groth16), poi_batch (Groth16), poi_batch-d<depth>-o16 (Groth16), poi_encrypted (Groth16), poi_encrypted-d<depth>-o4 (Groth16), fsp (Groth16), fsp-d24 (Groth16), fsp-d28 (Groth16), fsp_bytes (Groth16), fsp_bytes-d24 (Groth16), fsp_bytes-d28 (Groth16), fsp_append (Groth16), fsp_append-d24 (Groth16), fsp_append-d28 (Groth16), retrieval (Groth16), keyleak (PLONK), keyrotate (PLONK), archive_muri (Groth16), archive_poi (Groth16), archive_replication (Groth16)
 
 Note: MPC ceremony is only available for Groth16 circuits.
       PLONK circuits use a universal SRS and only need "dev" setup.
@@ -5,17 +5,48 @@ import (
 	"log"
 	"os"
 
+	archivemuri "github.com/MuriData/muri-zkproof/circuits/archive_muri"
+	archivepoi "github.com/MuriData/muri-zkproof/circuits/archive_poi"
 	"github.com/MuriData/muri-zkproof/circuits/fsp"
 	"github.com/MuriData/muri-zkproof/circuits/keyleak"
+	"github.com/MuriData/muri-zkproof/circuits/keyrotate"
 	"github.com/MuriData/muri-zkproof/circuits/poi"
+	"github.com/MuriData/muri-zkproof/circuits/retrieval"
 	"github.com/MuriData/muri-zkproof/pkg/setup"
 )
 
 // backendRegistry maps circuit names to their proof backends.
 var backendRegistry = map[string]setup.Backend{
-	"poi":     setup.Groth16Backend,
-	"fsp":     setup.Groth16Backend,
-	"keyleak": setup.PlonkBackend,
+	"poi":                 setup.Groth16Backend,
+	"fsp":                 setup.Groth16Backend,
+	"retrieval":           setup.Groth16Backend,
+	"keyleak":             setup.PlonkBackend,
+	"keyrotate":           setup.PlonkBackend,
+	"archive_muri":        setup.Groth16Backend,
+	"archive_poi":         setup.Groth16Backend,
+	"poi_batch":           setup.Groth16Backend,
+	"poi_encrypted":       setup.Groth16Backend,
+	"archive_replication": setup.Groth16Backend,
+}
+
+// Register every PoI parameter set (poi-d20-o8, poi_batch-d24-o16,
+// poi_encrypted-d24-o4, ...) and FSP depth (fsp-d24, fsp_bytes-d24, ...)
+// under its own name.
+func init() {
+	for name := range poi.ParamSets {
+		backendRegistry[name] = setup.Groth16Backend
+	}
+	for name := range poi.BatchParamSets {
+		backendRegistry[name] = setup.Groth16Backend
+	}
+	for name := range poi.EncryptedParamSets {
+		backendRegistry[name] = setup.Groth16Backend
+	}
+	for _, depth := range fsp.SupportedDepths {
+		backendRegistry[fsp.CircuitName(depth)] = setup.Groth16Backend
+		backendRegistry[fsp.BytesCircuitName(depth)] = setup.Groth16Backend
+		backendRegistry[fsp.AppendCircuitName(depth)] = setup.Groth16Backend
+	}
 }
 
 func main() {
@@ -40,7 +71,7 @@ func exportVK(circuit string) {
 	backend, ok := backendRegistry[circuit]
 	if !ok {
 		fmt.Fprintf(os.Stderr, "Unknown circuit: %s\n", circuit)
-		fmt.Fprintln(os.Stderr, "Available circuits: poi, fsp, keyleak")
+		fmt.Fprintln(os.Stderr, "Available circuits: poi, poi-d<depth>-o<openings>, poi_batch, poi_batch-d<depth>-o16, poi_encrypted, poi_encrypted-d<depth>-o4, fsp, fsp-d24, fsp-d28, fsp_bytes, fsp_bytes-d24, fsp_bytes-d28, fsp_append, fsp_append-d24, fsp_append-d28, retrieval, keyleak, keyrotate, archive_muri, archive_poi, archive_replication")
 		os.Exit(1)
 	}
 
@@ -92,8 +123,53 @@ func exportProofFixture(circuit string) {
 			log.Fatalf("write fixture file: %v", err)
 		}
 		fmt.Println("\nFixture written to proof_fixture.json")
-	case "fsp":
-		jsonOut, err := fsp.ExportProofFixture(".")
+	case "poi_batch":
+		jsonOut, err := poi.ExportBatchProofFixture(".")
+		if err != nil {
+			log.Fatalf("export proof fixture: %v", err)
+		}
+		if err := os.WriteFile("proof_fixture.json", jsonOut, 0644); err != nil {
+			log.Fatalf("write fixture file: %v", err)
+		}
+		fmt.Println("\nFixture written to proof_fixture.json")
+	case "poi_encrypted":
+		jsonOut, err := poi.ExportEncryptedProofFixture(".")
+		if err != nil {
+			log.Fatalf("export proof fixture: %v", err)
+		}
+		if err := os.WriteFile("proof_fixture.json", jsonOut, 0644); err != nil {
+			log.Fatalf("write fixture file: %v", err)
+		}
+		fmt.Println("\nFixture written to proof_fixture.json")
+	case "fsp", "fsp-d24", "fsp-d28":
+		jsonOut, err := fsp.ExportProofFixtureForDepth(".", fspDepth(circuit))
+		if err != nil {
+			log.Fatalf("export proof fixture: %v", err)
+		}
+		if err := os.WriteFile("proof_fixture.json", jsonOut, 0644); err != nil {
+			log.Fatalf("write fixture file: %v", err)
+		}
+		fmt.Println("\nFixture written to proof_fixture.json")
+	case "fsp_bytes", "fsp_bytes-d24", "fsp_bytes-d28":
+		jsonOut, err := fsp.ExportBytesProofFixture(".", fspDepth(circuit))
+		if err != nil {
+			log.Fatalf("export proof fixture: %v", err)
+		}
+		if err := os.WriteFile("proof_fixture.json", jsonOut, 0644); err != nil {
+			log.Fatalf("write fixture file: %v", err)
+		}
+		fmt.Println("\nFixture written to proof_fixture.json")
+	case "fsp_append", "fsp_append-d24", "fsp_append-d28":
+		jsonOut, err := fsp.ExportAppendProofFixture(".", fspDepth(circuit))
+		if err != nil {
+			log.Fatalf("export proof fixture: %v", err)
+		}
+		if err := os.WriteFile("proof_fixture.json", jsonOut, 0644); err != nil {
+			log.Fatalf("write fixture file: %v", err)
+		}
+		fmt.Println("\nFixture written to proof_fixture.json")
+	case "retrieval":
+		jsonOut, err := retrieval.ExportProofFixture(".")
 		if err != nil {
 			log.Fatalf("export proof fixture: %v", err)
 		}
@@ -110,11 +186,76 @@ func exportProofFixture(circuit string) {
 			log.Fatalf("write fixture file: %v", err)
 		}
 		fmt.Println("\nFixture written to proof_fixture.json")
+	case "keyrotate":
+		jsonOut, err := keyrotate.ExportProofFixture(".")
+		if err != nil {
+			log.Fatalf("export proof fixture: %v", err)
+		}
+		if err := os.WriteFile("proof_fixture.json", jsonOut, 0644); err != nil {
+			log.Fatalf("write fixture file: %v", err)
+		}
+		fmt.Println("\nFixture written to proof_fixture.json")
+	case "archive_muri":
+		jsonOut, err := archivemuri.ExportProofFixture(".")
+		if err != nil {
+			log.Fatalf("export proof fixture: %v", err)
+		}
+		if err := os.WriteFile("proof_fixture.json", jsonOut, 0644); err != nil {
+			log.Fatalf("write fixture file: %v", err)
+		}
+		fmt.Println("\nFixture written to proof_fixture.json")
+	case "archive_poi":
+		jsonOut, err := archivepoi.ExportProofFixture(".")
+		if err != nil {
+			log.Fatalf("export proof fixture: %v", err)
+		}
+		if err := os.WriteFile("proof_fixture.json", jsonOut, 0644); err != nil {
+			log.Fatalf("write fixture file: %v", err)
+		}
+		fmt.Println("\nFixture written to proof_fixture.json")
+	case "archive_replication":
+		jsonOut, err := archivemuri.ExportReplicationProofFixture(".")
+		if err != nil {
+			log.Fatalf("export proof fixture: %v", err)
+		}
+		if err := os.WriteFile("proof_fixture.json", jsonOut, 0644); err != nil {
+			log.Fatalf("write fixture file: %v", err)
+		}
+		fmt.Println("\nFixture written to proof_fixture.json")
 	default:
-		fmt.Fprintf(os.Stderr, "Unknown circuit: %s\n", circuit)
-		fmt.Fprintln(os.Stderr, "Available circuits: poi, fsp, keyleak")
-		os.Exit(1)
+		var jsonOut []byte
+		var err error
+		if p, lookupErr := poi.LookupParams(circuit); lookupErr == nil {
+			jsonOut, err = poi.ExportProofFixtureWithParams(".", circuit, p)
+		} else if p, lookupErr := poi.LookupBatchParams(circuit); lookupErr == nil {
+			jsonOut, err = poi.ExportBatchProofFixtureWithParams(".", circuit, p)
+		} else if p, lookupErr := poi.LookupEncryptedParams(circuit); lookupErr == nil {
+			jsonOut, err = poi.ExportEncryptedProofFixtureWithParams(".", circuit, p)
+		} else {
+			fmt.Fprintf(os.Stderr, "Unknown circuit: %s\n", circuit)
+			fmt.Fprintln(os.Stderr, "Available circuits: poi, poi-d<depth>-o<openings>, poi_batch, poi_batch-d<depth>-o16, poi_encrypted, poi_encrypted-d<depth>-o4, fsp, fsp-d24, fsp-d28, fsp_bytes, fsp_bytes-d24, fsp_bytes-d28, fsp_append, fsp_append-d24, fsp_append-d28, retrieval, keyleak, keyrotate, archive_muri, archive_poi, archive_replication")
+			os.Exit(1)
+		}
+		if err != nil {
+			log.Fatalf("export proof fixture: %v", err)
+		}
+		if err := os.WriteFile("proof_fixture.json", jsonOut, 0644); err != nil {
+			log.Fatalf("write fixture file: %v", err)
+		}
+		fmt.Println("\nFixture written to proof_fixture.json")
+	}
+}
+
+// fspDepth returns the tree depth of a registered FSP, FSP-bytes or
+// FSP-append circuit name.
+func fspDepth(circuit string) int {
+	for _, depth := range fsp.SupportedDepths {
+		if fsp.CircuitName(depth) == circuit || fsp.BytesCircuitName(depth) == circuit ||
+			fsp.AppendCircuitName(depth) == circuit {
+			return depth
+		}
 	}
+	return fsp.MaxTreeDepth
 }
 
 func printUsage() {
@@ -122,7 +263,7 @@ func printUsage() {
   go run ./cmd/export <circuit>         Export proof fixture (JSON)
   go run ./cmd/export <circuit> vk      Export VK constants as Solidity library
 
-Available circuits: poi, fsp, keyleak
+Available circuits: poi, poi-d<depth>-o<openings>, poi_batch, poi_batch-d<depth>-o16, poi_encrypted, poi_encrypted-d<depth>-o4, fsp, fsp-d24, fsp-d28, fsp_bytes, fsp_bytes-d24, fsp_bytes-d28, fsp_append, fsp_append-d24, fsp_append-d28, retrieval, keyleak, keyrotate, archive_muri, archive_poi, archive_replication
 
 Keys must exist in the current directory (run 'go run ./cmd/compile <circuit> dev' first).`)
 }
@@ -1,17 +1,22 @@
 // cmd/wasm/main.go — Browser WASM module for MuriData file operations.
 //
 // Exposes to JavaScript:
-//   - muriComputeFileRoot(fileBytes)                          → { root, numChunks }
-//   - muriGenerateFSPProof(fileBytes, pk, vk [, onProgress]) → { proof, root, numChunks }
+//   - muriComputeFileRoot(fileBytes)                          → { root, numChunks, depth }
+//   - muriGenerateFSPProof(fileBytes, pk, vk [, onProgress]) → { proof, root, numChunks, depth }
 //   - muriHashChunks(filePortionBytes)                        → Uint8Array (32-byte leaf hashes)
-//   - muriComputeRootFromHashes(hashes, numLeaves)            → { root, numChunks }
+//   - muriComputeRootFromHashes(hashes, numLeaves)            → { root, numChunks, depth }
 //   - muriGenerateFSPProofFromHashes(hashes, numLeaves, pk, vk [, onProgress])
-//         → { proof, root, numChunks }
+//         → { proof, root, numChunks, depth }
 //
 // The *FromHashes variants accept pre-computed leaf hashes (produced by
 // muriHashChunks in parallel workers) so leaf hashing can be parallelized
 // across multiple Web Workers while proof generation runs in one.
 //
+// The tree depth is the smallest of fsp.SupportedDepths that holds the file
+// (20 up to 16 GiB, then 24 and 28). Every result object carries a "depth"
+// field; FSP proofs require the proving/verifying keys of that depth's
+// circuit (fsp.CircuitName(depth)).
+//
 // Build: GOOS=js GOARCH=wasm go build -o muri.wasm ./cmd/wasm/
 
 package main
@@ -35,10 +40,9 @@ import (
 )
 
 const (
-	fileSize     = fsp.FileSize     // 16 KB per chunk
-	elementSize  = fsp.ElementSize  // 31 bytes
-	numElements  = fsp.NumChunks    // 528 field elements per leaf hash
-	maxTreeDepth = fsp.MaxTreeDepth // 20
+	fileSize    = fsp.FileSize    // 16 KB per chunk
+	elementSize = fsp.ElementSize // 31 bytes
+	numElements = fsp.NumChunks   // 528 field elements per leaf hash
 )
 
 // zeroLeafHash is the Poseidon2 hash for padding leaves, computed once at init.
@@ -53,10 +57,25 @@ func hashChunk(chunk []byte) fr.Element {
 	return crypto.HashLeafFr(crypto.DomainTagReal, chunk, elementSize, numElements)
 }
 
-// buildSMT splits file bytes into chunks and builds the sparse Merkle tree.
+// buildSMT splits file bytes into chunks and builds the sparse Merkle tree
+// at the smallest supported depth that fits them.
 func buildSMT(fileBytes []byte) (*merkle.SparseMerkleTree, error) {
 	chunks := merkle.SplitIntoChunks(fileBytes, fileSize)
-	return merkle.GenerateSparseMerkleTree(chunks, maxTreeDepth, hashChunk, zeroLeafHash)
+	depth, err := fsp.DepthForChunks(len(chunks))
+	if err != nil {
+		return nil, err
+	}
+	return merkle.GenerateSparseMerkleTree(chunks, depth, hashChunk, zeroLeafHash)
+}
+
+// buildSMTFromHashes assembles the sparse Merkle tree from pre-computed leaf
+// hashes at the smallest supported depth that fits them.
+func buildSMTFromHashes(leafHashes []fr.Element) (*merkle.SparseMerkleTree, error) {
+	depth, err := fsp.DepthForChunks(len(leafHashes))
+	if err != nil {
+		return nil, err
+	}
+	return merkle.BuildSMTFromLeafHashes(leafHashes, depth, zeroLeafHash)
 }
 
 // jsUint8ArrayToBytes copies a JS Uint8Array into a Go []byte.
@@ -99,7 +118,7 @@ func makeProgressReporter(args []js.Value, idx int) func(string, map[string]any)
 // Groth16 prove+verify, and returns the compressed proof + SMT root as a JS
 // result object.
 func fspProveAndCompress(smt *merkle.SparseMerkleTree, pkBytes, vkBytes []byte) (js.Value, error) {
-	ccs, err := setup.CompileCircuit(&fsp.FSPCircuit{})
+	ccs, err := setup.CompileCircuit(fsp.NewFSPCircuit(smt.Depth))
 	if err != nil {
 		return js.Undefined(), fmt.Errorf("compile circuit: %w", err)
 	}
@@ -169,6 +188,7 @@ func fspProveAndCompress(smt *merkle.SparseMerkleTree, pkBytes, vkBytes []byte)
 	result := js.Global().Get("Object").New()
 	result.Set("root", smt.RootBigInt().Text(10))
 	result.Set("numChunks", smt.NumLeaves)
+	result.Set("depth", smt.Depth)
 
 	proofArray := js.Global().Get("Array").New(4)
 	for i := 0; i < 4; i++ {
@@ -183,7 +203,7 @@ func fspProveAndCompress(smt *merkle.SparseMerkleTree, pkBytes, vkBytes []byte)
 // JS-exposed functions
 // ---------------------------------------------------------------------------
 
-// computeFileRootJS: muriComputeFileRoot(fileBytes) → { root, numChunks }
+// computeFileRootJS: muriComputeFileRoot(fileBytes) → { root, numChunks, depth }
 func computeFileRootJS(_ js.Value, args []js.Value) any {
 	handler := js.FuncOf(func(_ js.Value, promiseArgs []js.Value) any {
 		resolve := promiseArgs[0]
@@ -211,6 +231,7 @@ func computeFileRootJS(_ js.Value, args []js.Value) any {
 			result := js.Global().Get("Object").New()
 			result.Set("root", smt.RootBigInt().Text(10))
 			result.Set("numChunks", smt.NumLeaves)
+			result.Set("depth", smt.Depth)
 			resolve.Invoke(result)
 		}()
 
@@ -253,6 +274,7 @@ func generateFSPProofJS(_ js.Value, args []js.Value) any {
 			reportProgress("root", map[string]any{
 				"root":      smt.RootBigInt().Text(10),
 				"numChunks": smt.NumLeaves,
+				"depth":     smt.Depth,
 			})
 
 			result, err := fspProveAndCompress(smt, pkBytes, vkBytes)
@@ -312,7 +334,7 @@ func hashChunksJS(_ js.Value, args []js.Value) any {
 	return js.Global().Get("Promise").New(handler)
 }
 
-// computeRootFromHashesJS: muriComputeRootFromHashes(hashes, numLeaves) → { root, numChunks }
+// computeRootFromHashesJS: muriComputeRootFromHashes(hashes, numLeaves) → { root, numChunks, depth }
 func computeRootFromHashesJS(_ js.Value, args []js.Value) any {
 	handler := js.FuncOf(func(_ js.Value, promiseArgs []js.Value) any {
 		resolve := promiseArgs[0]
@@ -334,7 +356,7 @@ func computeRootFromHashesJS(_ js.Value, args []js.Value) any {
 			numLeaves := args[1].Int()
 			leafHashes := deserializeLeafHashes(hashBytes)
 
-			smt, err := merkle.BuildSMTFromLeafHashes(leafHashes, maxTreeDepth, zeroLeafHash)
+			smt, err := buildSMTFromHashes(leafHashes)
 			if err != nil {
 				reject.Invoke(err.Error())
 				return
@@ -343,6 +365,7 @@ func computeRootFromHashesJS(_ js.Value, args []js.Value) any {
 			result := js.Global().Get("Object").New()
 			result.Set("root", smt.RootBigInt().Text(10))
 			result.Set("numChunks", numLeaves)
+			result.Set("depth", smt.Depth)
 			resolve.Invoke(result)
 		}()
 
@@ -380,7 +403,7 @@ func generateFSPProofFromHashesJS(_ js.Value, args []js.Value) any {
 			leafHashes := deserializeLeafHashes(hashBytes)
 			_ = numLeaves // numLeaves used for the result; tree uses len(leafHashes)
 
-			smt, err := merkle.BuildSMTFromLeafHashes(leafHashes, maxTreeDepth, zeroLeafHash)
+			smt, err := buildSMTFromHashes(leafHashes)
 			if err != nil {
 				reject.Invoke(err.Error())
 				return
@@ -389,6 +412,7 @@ func generateFSPProofFromHashesJS(_ js.Value, args []js.Value) any {
 			reportProgress("root", map[string]any{
 				"root":      smt.RootBigInt().Text(10),
 				"numChunks": smt.NumLeaves,
+				"depth":     smt.Depth,
 			})
 
 			result, err := fspProveAndCompress(smt, pkBytes, vkBytes)
@@ -0,0 +1,164 @@
+// Package archive builds the slot tree that binds the member files of a
+// multi-file archive to a single archiveOriginalRoot.
+//
+// Each member file occupies one slot, in archive order:
+//
+//	slotLeaf[i]         = H(DomainTagSlot, fileRoot_i, numChunks_i, cumulativeChunks_i)
+//	archiveOriginalRoot = H(DomainTagArchiveRoot, slotTreeRoot, totalRealChunks)
+//
+// where cumulativeChunks_i is the number of chunks in files 0..i-1, i.e. the
+// archive-global index of file i's first chunk. Unused slots hold the zero
+// field element.
+package archive
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/MuriData/muri-zkproof/pkg/merkle"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+const (
+	SlotTreeDepth = 10
+	MaxSlots      = 1 << SlotTreeDepth // max member files per archive
+)
+
+// File describes one member file by its FSP-certified root and chunk count.
+type File struct {
+	Root      fr.Element
+	NumChunks int
+}
+
+// Archive is a built slot tree over an ordered list of member files.
+type Archive struct {
+	Files           []File
+	FirstChunks     []int // cumulativeChunks per file (chunks before it)
+	TotalRealChunks int
+	SlotTree        *merkle.SparseMerkleTree
+	OriginalRoot    fr.Element // archiveOriginalRoot
+}
+
+// SlotProof proves that a file occupies a slot of the archive's slot tree.
+type SlotProof struct {
+	Slot       int
+	File       File
+	FirstChunk int          // cumulativeChunks of the file
+	Siblings   []fr.Element // SlotTreeDepth sibling hashes, leaf to root
+	Directions []int        // 0 = sibling on right, 1 = sibling on left
+}
+
+// Build lays out files in order, builds the slot tree and computes the
+// archive's original root.
+func Build(files []File) (*Archive, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("archive has no files")
+	}
+	if len(files) > MaxSlots {
+		return nil, fmt.Errorf("archive has %d files, exceeds slot capacity %d", len(files), MaxSlots)
+	}
+
+	firstChunks := make([]int, len(files))
+	leaves := make([]fr.Element, len(files))
+	total := 0
+	for i, f := range files {
+		if f.NumChunks <= 0 {
+			return nil, fmt.Errorf("file %d has no chunks", i)
+		}
+		firstChunks[i] = total
+		leaves[i] = SlotLeaf(f, total)
+		total += f.NumChunks
+	}
+
+	var emptySlot fr.Element
+	slotTree, err := merkle.BuildSMTFromLeafHashes(leaves, SlotTreeDepth, emptySlot)
+	if err != nil {
+		return nil, fmt.Errorf("build slot tree: %w", err)
+	}
+
+	var originalRoot fr.Element
+	originalRoot.SetBigInt(crypto.DeriveArchiveOriginalRoot(slotTree.RootBigInt(), big.NewInt(int64(total))))
+
+	return &Archive{
+		Files:           append([]File(nil), files...),
+		FirstChunks:     firstChunks,
+		TotalRealChunks: total,
+		SlotTree:        slotTree,
+		OriginalRoot:    originalRoot,
+	}, nil
+}
+
+// SlotLeaf computes the slot leaf of file when its first chunk sits at
+// archive-global index firstChunk.
+func SlotLeaf(file File, firstChunk int) fr.Element {
+	fileRoot := new(big.Int)
+	file.Root.BigInt(fileRoot)
+
+	var leaf fr.Element
+	leaf.SetBigInt(crypto.DeriveSlotLeaf(fileRoot, big.NewInt(int64(file.NumChunks)), big.NewInt(int64(firstChunk))))
+	return leaf
+}
+
+// OriginalRootBigInt returns archiveOriginalRoot as *big.Int for callers that
+// need it (e.g. DeriveGlobalR, hex formatting).
+func (a *Archive) OriginalRootBigInt() *big.Int {
+	out := new(big.Int)
+	a.OriginalRoot.BigInt(out)
+	return out
+}
+
+// SlotOf returns the slot of the file containing archive-global chunk index
+// chunk.
+func (a *Archive) SlotOf(chunk int) (int, error) {
+	if chunk < 0 || chunk >= a.TotalRealChunks {
+		return 0, fmt.Errorf("chunk %d out of range [0, %d)", chunk, a.TotalRealChunks)
+	}
+	// First slot whose first chunk is beyond chunk, minus one.
+	return sort.SearchInts(a.FirstChunks, chunk+1) - 1, nil
+}
+
+// SlotProof returns the slot membership proof of the file in slot.
+func (a *Archive) SlotProof(slot int) (*SlotProof, error) {
+	if slot < 0 || slot >= len(a.Files) {
+		return nil, fmt.Errorf("slot %d out of range [0, %d)", slot, len(a.Files))
+	}
+	siblings, directions := a.SlotTree.GetProof(slot)
+	return &SlotProof{
+		Slot:       slot,
+		File:       a.Files[slot],
+		FirstChunk: a.FirstChunks[slot],
+		Siblings:   siblings,
+		Directions: directions,
+	}, nil
+}
+
+// VerifySlotProof checks proof against an archive's original root and
+// total chunk count, recomputing the slot tree root from the proof.
+func VerifySlotProof(proof *SlotProof, originalRoot fr.Element, totalRealChunks int) bool {
+	if len(proof.Siblings) != SlotTreeDepth || len(proof.Directions) != SlotTreeDepth {
+		return false
+	}
+	if proof.FirstChunk < 0 || proof.File.NumChunks <= 0 || proof.FirstChunk+proof.File.NumChunks > totalRealChunks {
+		return false
+	}
+
+	current := SlotLeaf(proof.File, proof.FirstChunk)
+	for lvl := 0; lvl < SlotTreeDepth; lvl++ {
+		if proof.Directions[lvl] != (proof.Slot>>lvl)&1 {
+			return false
+		}
+		if proof.Directions[lvl] == 0 {
+			current = merkle.HashNodesFr(current, proof.Siblings[lvl])
+		} else {
+			current = merkle.HashNodesFr(proof.Siblings[lvl], current)
+		}
+	}
+
+	slotRoot := new(big.Int)
+	current.BigInt(slotRoot)
+	var root fr.Element
+	root.SetBigInt(crypto.DeriveArchiveOriginalRoot(slotRoot, big.NewInt(int64(totalRealChunks))))
+	return root.Equal(&originalRoot)
+}
@@ -0,0 +1,135 @@
+package archive_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/MuriData/muri-zkproof/pkg/archive"
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// testFiles returns member files with random roots and the given chunk counts.
+func testFiles(t *testing.T, chunkCounts ...int) []archive.File {
+	t.Helper()
+	files := make([]archive.File, len(chunkCounts))
+	for i, n := range chunkCounts {
+		if _, err := files[i].Root.SetRandom(); err != nil {
+			t.Fatalf("generate root: %v", err)
+		}
+		files[i].NumChunks = n
+	}
+	return files
+}
+
+// TestBuildLayout checks cumulative chunk offsets, the archive root formula
+// and chunk-to-slot lookup.
+func TestBuildLayout(t *testing.T) {
+	files := testFiles(t, 3, 1, 5)
+	arc, err := archive.Build(files)
+	if err != nil {
+		t.Fatalf("build archive: %v", err)
+	}
+
+	wantFirst := []int{0, 3, 4}
+	for i, want := range wantFirst {
+		if arc.FirstChunks[i] != want {
+			t.Fatalf("file %d first chunk = %d, want %d", i, arc.FirstChunks[i], want)
+		}
+	}
+	if arc.TotalRealChunks != 9 {
+		t.Fatalf("total chunks = %d, want 9", arc.TotalRealChunks)
+	}
+
+	// Slot leaf matches DeriveSlotLeaf.
+	root1 := new(big.Int)
+	files[1].Root.BigInt(root1)
+	var wantLeaf fr.Element
+	wantLeaf.SetBigInt(crypto.DeriveSlotLeaf(root1, big.NewInt(1), big.NewInt(3)))
+	gotLeaf := arc.SlotTree.GetLeafHash(1)
+	if !gotLeaf.Equal(&wantLeaf) {
+		t.Fatal("slot leaf 1 does not match DeriveSlotLeaf")
+	}
+
+	// Original root matches DeriveArchiveOriginalRoot.
+	want := crypto.DeriveArchiveOriginalRoot(arc.SlotTree.RootBigInt(), big.NewInt(9))
+	if arc.OriginalRootBigInt().Cmp(want) != 0 {
+		t.Fatal("original root does not match DeriveArchiveOriginalRoot")
+	}
+
+	wantSlots := []int{0, 0, 0, 1, 2, 2, 2, 2, 2}
+	for chunk, want := range wantSlots {
+		slot, err := arc.SlotOf(chunk)
+		if err != nil {
+			t.Fatalf("slot of chunk %d: %v", chunk, err)
+		}
+		if slot != want {
+			t.Fatalf("slot of chunk %d = %d, want %d", chunk, slot, want)
+		}
+	}
+	if _, err := arc.SlotOf(9); err == nil {
+		t.Fatal("expected error for chunk beyond archive")
+	}
+}
+
+// TestSlotProof verifies every slot proof and rejects tampered ones.
+func TestSlotProof(t *testing.T) {
+	arc, err := archive.Build(testFiles(t, 2, 7, 1, 4, 3))
+	if err != nil {
+		t.Fatalf("build archive: %v", err)
+	}
+
+	for slot := range arc.Files {
+		proof, err := arc.SlotProof(slot)
+		if err != nil {
+			t.Fatalf("slot proof %d: %v", slot, err)
+		}
+		if !archive.VerifySlotProof(proof, arc.OriginalRoot, arc.TotalRealChunks) {
+			t.Fatalf("slot proof %d does not verify", slot)
+		}
+	}
+
+	proof, err := arc.SlotProof(3)
+	if err != nil {
+		t.Fatalf("slot proof: %v", err)
+	}
+
+	tampered := *proof
+	tampered.FirstChunk++
+	if archive.VerifySlotProof(&tampered, arc.OriginalRoot, arc.TotalRealChunks) {
+		t.Fatal("expected tampered first chunk to fail verification")
+	}
+
+	tampered = *proof
+	tampered.File.NumChunks++
+	if archive.VerifySlotProof(&tampered, arc.OriginalRoot, arc.TotalRealChunks) {
+		t.Fatal("expected tampered chunk count to fail verification")
+	}
+
+	tampered = *proof
+	tampered.Slot = 2
+	if archive.VerifySlotProof(&tampered, arc.OriginalRoot, arc.TotalRealChunks) {
+		t.Fatal("expected wrong slot index to fail verification")
+	}
+
+	if archive.VerifySlotProof(proof, arc.OriginalRoot, arc.TotalRealChunks+1) {
+		t.Fatal("expected wrong total chunk count to fail verification")
+	}
+
+	if _, err := arc.SlotProof(len(arc.Files)); err == nil {
+		t.Fatal("expected error for out-of-range slot")
+	}
+}
+
+// TestBuildRejectsInvalid checks input validation.
+func TestBuildRejectsInvalid(t *testing.T) {
+	if _, err := archive.Build(nil); err == nil {
+		t.Fatal("expected error for empty archive")
+	}
+	if _, err := archive.Build(testFiles(t, 2, 0)); err == nil {
+		t.Fatal("expected error for empty member file")
+	}
+	if _, err := archive.Build(make([]archive.File, archive.MaxSlots+1)); err == nil {
+		t.Fatal("expected error for too many files")
+	}
+}
@@ -92,7 +92,24 @@ func DeriveCommitment(secretKey, msg, randomness, publicKey *big.Int) *big.Int {
 }
 
 // ---------------------------------------------------------------------------
-// Archive / MURI transform helpers (tags 3–6, 10–15)
+// Encrypted storage helpers (tags 17–18)
+// ---------------------------------------------------------------------------
+
+// DeriveKeyCommitment computes the public commitment to an encryption key:
+// keyCommitment = H(DomainTagEncKey, key)
+func DeriveKeyCommitment(key *big.Int) *big.Int {
+	return SpongeHashBigInt(DomainTagEncKey, key)
+}
+
+// DeriveKeystreamElem computes keystream element j of leaf leafIndex:
+// ks = H(DomainTagKeystream, key, leafIndex, j)
+// Ciphertext element j of the leaf is plaintext element j + ks (mod p).
+func DeriveKeystreamElem(key, leafIndex, j *big.Int) *big.Int {
+	return SpongeHashBigInt(DomainTagKeystream, key, leafIndex, j)
+}
+
+// ---------------------------------------------------------------------------
+// Archive / MURI transform helpers (tags 3–6, 10–16, 19)
 // ---------------------------------------------------------------------------
 
 // DeriveSlotLeaf computes the archive slot leaf hash:
@@ -113,6 +130,24 @@ func DeriveGlobalR(publicKey, archiveOriginalRoot *big.Int) *big.Int {
 	return SpongeHashBigInt(DomainTagGlobalR, publicKey, archiveOriginalRoot)
 }
 
+// DeriveReplicaR computes the sealing randomness of replica replicaIndex of
+// an archive held by publicKey. Replica 0 is the primary replica and uses
+// DeriveGlobalR unchanged; further replicas use
+// r = H(DomainTagReplicaR, publicKey, archiveOriginalRoot, replicaIndex).
+func DeriveReplicaR(publicKey, archiveOriginalRoot *big.Int, replicaIndex uint32) *big.Int {
+	if replicaIndex == 0 {
+		return DeriveGlobalR(publicKey, archiveOriginalRoot)
+	}
+	return SpongeHashBigInt(DomainTagReplicaR, publicKey, archiveOriginalRoot, new(big.Int).SetUint64(uint64(replicaIndex)))
+}
+
+// DeriveSealProofRandomness computes the randomness of sealing proof i of
+// the replica committed to by sealedRoot, for a seed drawn by the verifier:
+// randomness = H(DomainTagSealProof, seed, sealedRoot, i)
+func DeriveSealProofRandomness(seed, sealedRoot *big.Int, i int) *big.Int {
+	return SpongeHashBigInt(DomainTagSealProof, seed, sealedRoot, big.NewInt(int64(i)))
+}
+
 // DeriveChallengeIdx computes a challenge index derivation:
 // idx = H(DomainTagChallengeIdx, randomness, k)
 func DeriveChallengeIdx(randomness, k *big.Int) *big.Int {
@@ -157,6 +192,13 @@ func DeriveBackPointers(domainTag int, j, r *big.Int) *big.Int {
 // fr.Element directly. This is the optimized path for leaf hashing where
 // randomness is 1 — the per-element multiply is skipped entirely.
 func HashLeafFr(tag int, data []byte, elementSize, numChunks int) fr.Element {
+	return SpongeHash(tag, ChunkToElements(data, elementSize, numChunks))
+}
+
+// ChunkToElements splits raw chunk data into elementSize-byte big-endian
+// field elements, zero-padding the result to numChunks elements. This is the
+// element layout absorbed by HashLeafFr and opened by the circuits.
+func ChunkToElements(data []byte, elementSize, numChunks int) []fr.Element {
 	elems := make([]fr.Element, 0, numChunks)
 
 	buf := make([]byte, elementSize)
@@ -180,7 +222,7 @@ func HashLeafFr(tag int, data []byte, elementSize, numChunks int) fr.Element {
 		elems = append(elems, zero)
 	}
 
-	return SpongeHash(tag, elems)
+	return elems
 }
 
 // ComputeZeroLeafHashFr returns the fr.Element hash of a padding leaf.
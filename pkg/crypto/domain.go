@@ -4,7 +4,7 @@ package crypto
 // Each tag occupies the capacity lane of the sponge, ensuring
 // outputs from different contexts never collide.
 //
-// Tags 0–15 are allocated. New tags must use values >= 16.
+// Tags 0–19 are allocated. New tags must use values >= 20.
 const (
 	DomainTagPadding      = 0  // Padding chunk leaf hash
 	DomainTagReal         = 1  // Real chunk leaf hash
@@ -22,4 +22,8 @@ const (
 	DomainTagArchiveRoot  = 13 // Archive original root (slotTreeRoot, totalRealChunks)
 	DomainTagBackPtr1     = 14 // Pass 1 back-pointer position derivation (j, r)
 	DomainTagBackPtr2     = 15 // Pass 2 back-pointer position derivation (j, r)
+	DomainTagReplicaR     = 16 // Additional replica randomness (publicKey, archiveOriginalRoot, replicaIndex)
+	DomainTagKeystream    = 17 // Encrypted-storage keystream element (key, leafIndex, j)
+	DomainTagEncKey       = 18 // Encryption key commitment
+	DomainTagSealProof    = 19 // Archive MURI sealing-proof randomness (seed, sealedRoot, i)
 )
@@ -1,6 +1,7 @@
 package merkle
 
 import (
+	"context"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -29,7 +30,8 @@ import (
 
 // CheckpointScheme defines which SMT levels to persist.
 // Levels must be sorted ascending with the last element equal to the tree
-// depth. Presets below target depth-20 trees (MaxTreeDepth in the PoI circuit).
+// depth. Presets below target depth-20 trees (MaxTreeDepth in the PoI circuit)
+// and the depth-24/28 variants used for files above 16 GiB.
 type CheckpointScheme struct {
 	Levels []int
 }
@@ -52,6 +54,61 @@ var (
 	SchemeFast = CheckpointScheme{Levels: []int{3, 7, 12, 20}}
 )
 
+// Preset checkpoint schemes for depth-24 trees (files up to 256 GiB).
+//
+// Space estimates assume a 256 GiB file (16 777 216 chunks of 16 KB), with
+// the same hardware assumptions as the depth-20 presets.
+var (
+	// SchemeCompact24 stores only level 12 and root.
+	// Space: ~150 KB. Rebuild: ~1.5 s/opening.
+	SchemeCompact24 = CheckpointScheme{Levels: []int{12, 24}}
+
+	// SchemeBalanced24 stores 5 checkpoint levels with gaps (4,5,6,5,4).
+	// Space: ~39 MB. Rebuild: ~6 ms/opening.
+	SchemeBalanced24 = CheckpointScheme{Levels: []int{4, 9, 15, 20, 24}}
+
+	// SchemeFast24 stores 5 checkpoint levels with gaps (3,4,5,6,6).
+	// Space: ~78 MB. Rebuild: ~3 ms/opening.
+	SchemeFast24 = CheckpointScheme{Levels: []int{3, 7, 12, 18, 24}}
+)
+
+// Preset checkpoint schemes for depth-28 trees (files up to 4 TiB).
+//
+// Space estimates assume a 4 TiB file (268 435 456 chunks of 16 KB), with
+// the same hardware assumptions as the depth-20 presets.
+var (
+	// SchemeCompact28 stores only level 14 and root.
+	// Space: ~600 KB. Rebuild: ~6 s/opening.
+	SchemeCompact28 = CheckpointScheme{Levels: []int{14, 28}}
+
+	// SchemeBalanced28 stores 5 checkpoint levels with gaps (4,5,6,6,7).
+	// Space: ~620 MB. Rebuild: ~6 ms/opening.
+	SchemeBalanced28 = CheckpointScheme{Levels: []int{4, 9, 15, 21, 28}}
+
+	// SchemeFast28 stores 6 checkpoint levels with gaps (3,4,5,6,5,5).
+	// Space: ~1.2 GB. Rebuild: ~3 ms/opening.
+	SchemeFast28 = CheckpointScheme{Levels: []int{3, 7, 12, 18, 23, 28}}
+)
+
+// PresetScheme returns the named preset ("compact", "balanced" or "fast")
+// for a tree of the given depth (20, 24 or 28).
+func PresetScheme(name string, depth int) (CheckpointScheme, error) {
+	presets := map[int]map[string]CheckpointScheme{
+		20: {"compact": SchemeCompact, "balanced": SchemeBalanced, "fast": SchemeFast},
+		24: {"compact": SchemeCompact24, "balanced": SchemeBalanced24, "fast": SchemeFast24},
+		28: {"compact": SchemeCompact28, "balanced": SchemeBalanced28, "fast": SchemeFast28},
+	}
+	byName, ok := presets[depth]
+	if !ok {
+		return CheckpointScheme{}, fmt.Errorf("no checkpoint presets for tree depth %d", depth)
+	}
+	scheme, ok := byName[name]
+	if !ok {
+		return CheckpointScheme{}, fmt.Errorf("unknown checkpoint preset %q", name)
+	}
+	return scheme, nil
+}
+
 // CheckpointedSMT holds only the entries at checkpoint levels plus the
 // precomputed zero-subtree hash chain.
 type CheckpointedSMT struct {
@@ -70,6 +127,32 @@ type RebuildProofResult struct {
 	LeafHash   fr.Element
 }
 
+// TreeInfo identifies a sparse Merkle tree by its root and shape.
+type TreeInfo struct {
+	Root      fr.Element
+	Depth     int
+	NumLeaves int
+}
+
+// ProofSource is a sparse Merkle tree that can open batches of leaves, so
+// witness builders work with full and checkpointed trees alike. A
+// SparseMerkleTree looks the proofs up and never reads chunks; a
+// CheckpointedSMT rebuilds them with RebuildProofsFromStore.
+type ProofSource interface {
+	Info() TreeInfo
+	Proofs(ctx context.Context, leafIndices []int, chunks ChunkStore, hashLeaf HashFuncFr) ([]*RebuildProofResult, error)
+}
+
+// Info returns the root and shape of the checkpointed tree.
+func (csmt *CheckpointedSMT) Info() TreeInfo {
+	return TreeInfo{Root: csmt.Root, Depth: csmt.Depth, NumLeaves: csmt.NumLeaves}
+}
+
+// Proofs implements ProofSource with RebuildProofsFromStore.
+func (csmt *CheckpointedSMT) Proofs(ctx context.Context, leafIndices []int, chunks ChunkStore, hashLeaf HashFuncFr) ([]*RebuildProofResult, error) {
+	return csmt.RebuildProofsFromStore(ctx, leafIndices, chunks, hashLeaf)
+}
+
 // segment is a contiguous range of tree levels [lo, hi) that must be
 // rebuilt from the entries at level lo.
 type segment struct {
@@ -94,12 +177,32 @@ func (smt *SparseMerkleTree) SaveCheckpointed(w io.Writer, scheme CheckpointSche
 	if err := validateScheme(scheme, smt.Depth); err != nil {
 		return err
 	}
+	levels := make(map[int][]fr.Element, len(scheme.Levels))
+	for _, lvl := range scheme.Levels {
+		levels[lvl] = smt.Levels[lvl]
+	}
+	csmt := &CheckpointedSMT{
+		Depth:     smt.Depth,
+		NumLeaves: smt.NumLeaves,
+		Scheme:    scheme,
+		Levels:    levels,
+	}
+	return csmt.Save(w)
+}
+
+// Save writes the checkpointed SMT in the format read by
+// LoadCheckpointedSMT.
+func (csmt *CheckpointedSMT) Save(w io.Writer) error {
+	scheme := csmt.Scheme
+	if err := validateScheme(scheme, csmt.Depth); err != nil {
+		return err
+	}
 
 	// Header.
-	if err := binary.Write(w, binary.BigEndian, uint32(smt.Depth)); err != nil {
+	if err := binary.Write(w, binary.BigEndian, uint32(csmt.Depth)); err != nil {
 		return fmt.Errorf("write depth: %w", err)
 	}
-	if err := binary.Write(w, binary.BigEndian, uint32(smt.NumLeaves)); err != nil {
+	if err := binary.Write(w, binary.BigEndian, uint32(csmt.NumLeaves)); err != nil {
 		return fmt.Errorf("write numLeaves: %w", err)
 	}
 	if err := binary.Write(w, binary.BigEndian, uint32(len(scheme.Levels))); err != nil {
@@ -113,7 +216,7 @@ func (smt *SparseMerkleTree) SaveCheckpointed(w io.Writer, scheme CheckpointSche
 
 	// Per-checkpoint-level entries.
 	for _, lvl := range scheme.Levels {
-		entries := smt.Levels[lvl]
+		entries := csmt.Levels[lvl]
 		if err := binary.Write(w, binary.BigEndian, uint32(len(entries))); err != nil {
 			return fmt.Errorf("write level %d count: %w", lvl, err)
 		}
@@ -210,131 +313,152 @@ func LoadCheckpointedSMT(r io.Reader, zeroLeafHash fr.Element) (*CheckpointedSMT
 // The returned LeafHash is the hash at leafIndex (recomputed if necessary,
 // or the zero leaf hash for padding positions).
 func (csmt *CheckpointedSMT) RebuildProof(leafIndex int, readChunk func(int) []byte, hashLeaf HashFuncFr) *RebuildProofResult {
-	siblings := make([]fr.Element, csmt.Depth)
-	directions := make([]int, csmt.Depth)
-
-	// Compute directions (identical to SparseMerkleTree.GetProof).
-	idx := leafIndex
-	for lvl := 0; lvl < csmt.Depth; lvl++ {
-		if idx%2 == 0 {
-			directions[lvl] = 0
-		} else {
-			directions[lvl] = 1
+	return csmt.RebuildProofs([]int{leafIndex}, readChunk, hashLeaf)[0]
+}
+
+// RebuildProofContext is RebuildProof with a fallible readChunk and
+// cancellation; see RebuildProofsContext.
+func (csmt *CheckpointedSMT) RebuildProofContext(ctx context.Context, leafIndex int, readChunk func(int) ([]byte, error), hashLeaf HashFuncFr) (*RebuildProofResult, error) {
+	results, err := csmt.RebuildProofsContext(ctx, []int{leafIndex}, readChunk, hashLeaf)
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// RebuildProofs reconstructs the proofs of several leaves in one pass, e.g.
+// all openings of a PoI challenge. Leaves whose paths cross the same gap
+// subtree share a single rebuild of it, so each needed chunk is read and
+// hashed once however many openings land in its bottom-gap subtree. Results
+// are in the order of leafIndices; duplicates are allowed.
+func (csmt *CheckpointedSMT) RebuildProofs(leafIndices []int, readChunk func(int) []byte, hashLeaf HashFuncFr) []*RebuildProofResult {
+	// Neither the background context nor an infallible readChunk can fail.
+	results, _ := csmt.RebuildProofsContext(context.Background(), leafIndices,
+		func(i int) ([]byte, error) { return readChunk(i), nil }, hashLeaf)
+	return results
+}
+
+// RebuildProofsContext is RebuildProofs with a fallible readChunk. The first
+// read error, or ctx being cancelled (e.g. when the challenge deadline
+// passes), stops all leaf-hashing workers before their next chunk and is
+// returned; no partial results are returned.
+func (csmt *CheckpointedSMT) RebuildProofsContext(ctx context.Context, leafIndices []int, readChunk func(int) ([]byte, error), hashLeaf HashFuncFr) ([]*RebuildProofResult, error) {
+	return csmt.rebuildProofs(ctx, leafIndices, func(_ context.Context, i int) ([]byte, error) {
+		return readChunk(i)
+	}, hashLeaf)
+}
+
+// rebuildProofs implements RebuildProofsContext. readChunk receives the
+// rebuild's internal context, which is cancelled on the first read error.
+func (csmt *CheckpointedSMT) rebuildProofs(ctx context.Context, leafIndices []int, readChunk func(context.Context, int) ([]byte, error), hashLeaf HashFuncFr) ([]*RebuildProofResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	results := make([]*RebuildProofResult, len(leafIndices))
+	for k, leafIndex := range leafIndices {
+		res := &RebuildProofResult{
+			Siblings:   make([]fr.Element, csmt.Depth),
+			Directions: make([]int, csmt.Depth),
+			LeafHash:   csmt.ZeroHashes[0],
 		}
-		idx /= 2
+		// Directions are identical to SparseMerkleTree.GetProof; siblings
+		// default to zero hashes until their segment fills them in.
+		for lvl := 0; lvl < csmt.Depth; lvl++ {
+			res.Directions[lvl] = (leafIndex >> lvl) & 1
+			res.Siblings[lvl] = csmt.ZeroHashes[lvl]
+		}
+		results[k] = res
 	}
 
-	// Build segment list from checkpoint boundaries.
-	segments := csmt.buildSegments()
+	// Plan one rebuild per (segment, gap subtree) touched by any leaf.
+	type rebuild struct {
+		seg     segment
+		subtree int   // node index at level seg.hi
+		members []int // positions in leafIndices under this subtree
+	}
+	var rebuilds []rebuild
+	for _, seg := range csmt.buildSegments() {
+		bySubtree := make(map[int]int)
+		for k, leafIndex := range leafIndices {
+			subtree := leafIndex >> seg.hi
+			ri, ok := bySubtree[subtree]
+			if !ok {
+				ri = len(rebuilds)
+				bySubtree[subtree] = ri
+				rebuilds = append(rebuilds, rebuild{seg: seg, subtree: subtree})
+			}
+			rebuilds[ri].members = append(rebuilds[ri].members, k)
+		}
+	}
 
-	// Per-segment results. Each goroutine writes to its own slot.
-	type segResult struct {
-		siblings map[int]fr.Element // absLevel → sibling hash
-		leafHash fr.Element
-		hasLeaf  bool
+	// All bottom-gap rebuilds share one pool of at most NumCPU leaf hashers,
+	// however many subtrees the batch touches.
+	bottomLeaves := 0
+	for _, rb := range rebuilds {
+		if rb.seg.needsChunks {
+			bottomLeaves += 1 << (rb.seg.hi - rb.seg.lo)
+		}
+	}
+	var hashers *leafHashers
+	if bottomLeaves > 0 {
+		hashers = csmt.startLeafHashers(ctx, cancel, readChunk, hashLeaf, min(leafHashWorkers, bottomLeaves))
+		defer hashers.stop()
 	}
-	results := make([]segResult, len(segments))
 
-	// Launch one goroutine per segment.
+	// Launch one goroutine per rebuild. Rebuilds of one leaf cover disjoint
+	// levels, so each writes to its own sibling slots.
 	var wg sync.WaitGroup
-	for si, seg := range segments {
+	for _, rb := range rebuilds {
 		wg.Add(1)
-		go func(si int, seg segment) {
+		go func(rb rebuild) {
 			defer wg.Done()
+			seg := rb.seg
 			gapDepth := seg.hi - seg.lo
-			if gapDepth == 0 {
-				return
-			}
-
-			// Identify the subtree region at the base level.
-			subtreeAtHi := leafIndex >> seg.hi
-			baseStart := subtreeAtHi << gapDepth
+			baseStart := rb.subtree << gapDepth
 			subtreeSize := 1 << gapDepth
 
 			// Populate base-level entries for this subtree.
 			baseEntries := make([]fr.Element, subtreeSize)
 			baseSet := make([]bool, subtreeSize)
-			var segLeafHash fr.Element
-			hasLeaf := false
-
 			if seg.needsChunks {
-				// Bottom gap: parallel leaf hashing from chunk data.
-				csmt.rebuildBottomEntries(
-					baseStart, subtreeSize, leafIndex, readChunk, hashLeaf, len(segments),
-					baseEntries, baseSet,
-				)
-				localOffset := leafIndex - baseStart
-				if localOffset >= 0 && localOffset < subtreeSize && baseSet[localOffset] {
-					segLeafHash = baseEntries[localOffset]
-					hasLeaf = true
-				} else {
-					segLeafHash = csmt.ZeroHashes[0]
-					hasLeaf = true
+				// Bottom gap: leaf hashing from chunk data on the shared pool.
+				hashers.hashSubtree(ctx, baseStart, baseEntries, baseSet)
+				if ctx.Err() != nil {
+					return
 				}
-			} else {
+			} else if stored, ok := csmt.Levels[seg.lo]; ok {
 				// Middle/upper gap: look up stored entries at the base level.
-				if stored, ok := csmt.Levels[seg.lo]; ok {
-					for i := 0; i < subtreeSize; i++ {
-						absIdx := baseStart + i
-						if absIdx < len(stored) {
-							baseEntries[i] = stored[absIdx]
-							baseSet[i] = true
-						}
-					}
+				for i := 0; i < subtreeSize && baseStart+i < len(stored); i++ {
+					baseEntries[i] = stored[baseStart+i]
+					baseSet[i] = true
 				}
-				// If this segment covers level 0 (leaves are stored),
-				// extract the leaf hash directly.
-				if seg.lo == 0 {
-					localOffset := leafIndex - baseStart
-					if localOffset >= 0 && localOffset < subtreeSize && baseSet[localOffset] {
-						segLeafHash = baseEntries[localOffset]
-					} else {
-						segLeafHash = csmt.ZeroHashes[0]
-					}
-					hasLeaf = true
+			}
+
+			memberLeaves := make([]int, len(rb.members))
+			memberSiblings := make([][]fr.Element, len(rb.members))
+			for i, k := range rb.members {
+				memberLeaves[i] = leafIndices[k]
+				memberSiblings[i] = results[k].Siblings
+				// The segment based at level 0 (rebuilt from chunks or
+				// stored leaves) also yields the leaf hash.
+				if local := leafIndices[k] - baseStart; seg.lo == 0 && baseSet[local] {
+					results[k].LeafHash = baseEntries[local]
 				}
 			}
 
 			// Build upward through the gap, extracting siblings at each level.
-			segSiblings := csmt.buildGap(baseEntries, baseSet, seg.lo, gapDepth, leafIndex)
-
-			results[si].siblings = segSiblings
-			results[si].leafHash = segLeafHash
-			results[si].hasLeaf = hasLeaf
-		}(si, seg)
+			csmt.buildGap(baseEntries, baseSet, seg.lo, gapDepth, memberLeaves, memberSiblings)
+		}(rb)
 	}
 	wg.Wait()
 
-	// Assemble final siblings and leaf hash from segment results.
-	var leafHash fr.Element
-	leafHashSet := false
-	siblingSet := make([]bool, csmt.Depth)
-	for _, res := range results {
-		for lvl, sib := range res.siblings {
-			siblings[lvl] = sib
-			siblingSet[lvl] = true
-		}
-		if res.hasLeaf {
-			leafHash = res.leafHash
-			leafHashSet = true
-		}
-	}
-
-	// Fill any remaining unset siblings with zero hashes.
-	for i := 0; i < csmt.Depth; i++ {
-		if !siblingSet[i] {
-			siblings[i] = csmt.ZeroHashes[i]
-		}
-	}
-	if !leafHashSet {
-		leafHash = csmt.ZeroHashes[0]
-	}
-
-	return &RebuildProofResult{
-		Siblings:   siblings,
-		Directions: directions,
-		LeafHash:   leafHash,
+	if ctx.Err() != nil {
+		return nil, context.Cause(ctx)
 	}
+	return results, nil
 }
 
 // buildSegments partitions the tree levels into contiguous segments bounded
@@ -356,61 +480,97 @@ func (csmt *CheckpointedSMT) buildSegments() []segment {
 	return segments
 }
 
-// rebuildBottomEntries hashes chunks in parallel for the bottom gap.
-// Writes results into baseEntries and baseSet slices.
-func (csmt *CheckpointedSMT) rebuildBottomEntries(
-	baseStart, subtreeSize, leafIndex int,
-	readChunk func(int) []byte,
-	hashLeaf HashFuncFr,
-	numSegments int,
-	baseEntries []fr.Element,
-	baseSet []bool,
-) {
-	// Worker pool: reserve one core per non-bottom segment so they can
-	// run truly in parallel with the leaf hashing.
-	numWorkers := runtime.NumCPU()
-	if numSegments > 1 && numWorkers > numSegments {
-		numWorkers -= numSegments - 1
-	}
-	if numWorkers > subtreeSize {
-		numWorkers = subtreeSize
-	}
-	if numWorkers < 1 {
-		numWorkers = 1
-	}
+// leafHashWorkers is the number of leaf hashers shared by the bottom-gap
+// rebuilds of one rebuildProofs call (PlanConfig.Workers in the planner's
+// cost model).
+var leafHashWorkers = runtime.NumCPU()
+
+// leafJob asks the leaf hashers for the hash of chunk absIdx.
+type leafJob struct {
+	absIdx int
+	entry  *fr.Element
+	set    *bool
+	done   *sync.WaitGroup
+}
 
-	var leafWg sync.WaitGroup
-	work := make(chan int, subtreeSize)
+// leafHashers is a pool of workers reading and hashing chunks for the
+// bottom-gap rebuilds of one rebuildProofs call. A read error cancels ctx
+// through cancel; once ctx is done, workers skip the remaining jobs.
+type leafHashers struct {
+	numLeaves int
+	jobs      chan leafJob
+	wg        sync.WaitGroup
+}
+
+// startLeafHashers starts numWorkers leaf hashers; stop must be called once
+// every hashSubtree call has returned.
+func (csmt *CheckpointedSMT) startLeafHashers(
+	ctx context.Context,
+	cancel context.CancelCauseFunc,
+	readChunk func(context.Context, int) ([]byte, error),
+	hashLeaf HashFuncFr,
+	numWorkers int,
+) *leafHashers {
+	h := &leafHashers{numLeaves: csmt.NumLeaves, jobs: make(chan leafJob)}
 	for w := 0; w < numWorkers; w++ {
-		leafWg.Add(1)
+		h.wg.Add(1)
 		go func() {
-			defer leafWg.Done()
-			for localIdx := range work {
-				absIdx := baseStart + localIdx
-				if absIdx < csmt.NumLeaves {
-					baseEntries[localIdx] = hashLeaf(readChunk(absIdx))
-					baseSet[localIdx] = true
+			defer h.wg.Done()
+			for job := range h.jobs {
+				if ctx.Err() == nil {
+					chunk, err := readChunk(ctx, job.absIdx)
+					if err != nil {
+						cancel(fmt.Errorf("read chunk %d: %w", job.absIdx, err))
+					} else {
+						*job.entry = hashLeaf(chunk)
+						*job.set = true
+					}
 				}
-				// unset entries → zero leaf hash (handled during gap build)
+				job.done.Done()
 			}
 		}()
 	}
-	for i := 0; i < subtreeSize; i++ {
-		work <- i
+	return h
+}
+
+// hashSubtree hashes the real leaves of the bottom-gap subtree starting at
+// leaf baseStart into baseEntries and baseSet, and waits for them. Entries
+// left unset stand for the zero leaf hash (handled during the gap build).
+func (h *leafHashers) hashSubtree(ctx context.Context, baseStart int, baseEntries []fr.Element, baseSet []bool) {
+	var done sync.WaitGroup
+	for localIdx := range baseEntries {
+		if baseStart+localIdx >= h.numLeaves {
+			break
+		}
+		done.Add(1)
+		select {
+		case h.jobs <- leafJob{absIdx: baseStart + localIdx, entry: &baseEntries[localIdx], set: &baseSet[localIdx], done: &done}:
+		case <-ctx.Done():
+			done.Done()
+		}
+		if ctx.Err() != nil {
+			break
+		}
 	}
-	close(work)
-	leafWg.Wait()
+	done.Wait()
+}
+
+// stop shuts the workers down.
+func (h *leafHashers) stop() {
+	close(h.jobs)
+	h.wg.Wait()
 }
 
-// buildGap constructs intermediate levels from baseEntries and extracts the
-// sibling hash at each level for the proof path of leafIndex.
+// buildGap constructs intermediate levels from baseEntries and writes the
+// sibling hash at each level of the gap into siblings[i] for the proof path
+// of leafIndices[i].
 func (csmt *CheckpointedSMT) buildGap(
 	baseEntries []fr.Element,
 	baseSet []bool,
-	baseLvl, gapDepth, leafIndex int,
-) map[int]fr.Element {
-	segSiblings := make(map[int]fr.Element, gapDepth)
-
+	baseLvl, gapDepth int,
+	leafIndices []int,
+	siblings [][]fr.Element,
+) {
 	curEntries := baseEntries
 	curSet := baseSet
 	curSize := len(baseEntries)
@@ -418,15 +578,17 @@ func (csmt *CheckpointedSMT) buildGap(
 	for relLvl := 0; relLvl < gapDepth; relLvl++ {
 		absLvl := baseLvl + relLvl
 
-		// Extract sibling at this level.
-		nodeIdx := leafIndex >> absLvl
-		// Compute local index relative to the current subtree
-		localNode := nodeIdx & ((1 << (gapDepth - relLvl)) - 1)
-		localSib := localNode ^ 1
-		if localSib < curSize && curSet[localSib] {
-			segSiblings[absLvl] = curEntries[localSib]
-		} else {
-			segSiblings[absLvl] = csmt.ZeroHashes[absLvl]
+		// Extract siblings at this level.
+		for i, leafIndex := range leafIndices {
+			nodeIdx := leafIndex >> absLvl
+			// Compute local index relative to the current subtree
+			localNode := nodeIdx & ((1 << (gapDepth - relLvl)) - 1)
+			localSib := localNode ^ 1
+			if localSib < curSize && curSet[localSib] {
+				siblings[i][absLvl] = curEntries[localSib]
+			} else {
+				siblings[i][absLvl] = csmt.ZeroHashes[absLvl]
+			}
 		}
 
 		// Build next level from current entries.
@@ -462,8 +624,139 @@ func (csmt *CheckpointedSMT) buildGap(
 		curSet = nextSet
 		curSize = nextSize
 	}
+}
+
+// ---------------------------------------------------------------------------
+// Integrity verification
+// ---------------------------------------------------------------------------
+//
+// Loaded checkpoint levels are trusted as-is, so a flipped bit in a stored
+// level only surfaces when the rebuilt proof fails to verify in the prover.
+// Verify checks a whole CheckpointedSMT up front; RebuildProofChecked checks
+// a single rebuilt path against the stored entries it crosses.
+
+// CorruptSegmentError reports a checkpoint segment [Lo, Hi) whose stored
+// entries are inconsistent: the entry at Index of level Hi does not match the
+// hash recomputed from level Lo (or, for the bottom segment without stored
+// leaves, from the chunk data).
+type CorruptSegmentError struct {
+	Lo, Hi int
+	Index  int
+}
+
+func (e *CorruptSegmentError) Error() string {
+	return fmt.Sprintf("checkpoint segment [%d, %d) is corrupt: level %d entry %d does not match its recomputed hash",
+		e.Lo, e.Hi, e.Hi, e.Index)
+}
+
+// Verify recomputes every checkpoint level from the checkpoint level below it
+// and checks the stored entries and the root. The bottom gap is skipped when
+// level 0 is not stored, as the chunk data is needed to check it (see
+// RebuildProofChecked). Mismatching entries yield a *CorruptSegmentError.
+func (csmt *CheckpointedSMT) Verify() error {
+	if err := validateScheme(csmt.Scheme, csmt.Depth); err != nil {
+		return err
+	}
+	if len(csmt.ZeroHashes) != csmt.Depth+1 {
+		return fmt.Errorf("zero hash chain has %d entries, want %d", len(csmt.ZeroHashes), csmt.Depth+1)
+	}
+	for _, lvl := range csmt.Scheme.Levels {
+		entries, ok := csmt.Levels[lvl]
+		if !ok {
+			return fmt.Errorf("checkpoint level %d is missing", lvl)
+		}
+		if want := levelSize(csmt.NumLeaves, lvl); len(entries) != want {
+			return fmt.Errorf("checkpoint level %d has %d entries, want %d", lvl, len(entries), want)
+		}
+	}
+
+	for _, seg := range csmt.buildSegments() {
+		if seg.needsChunks {
+			continue
+		}
+		cur := csmt.Levels[seg.lo]
+		for lvl := seg.lo; lvl < seg.hi; lvl++ {
+			next := make([]fr.Element, levelSize(csmt.NumLeaves, lvl+1))
+			hashLevel(cur, next, csmt.ZeroHashes[lvl])
+			cur = next
+		}
+		stored := csmt.Levels[seg.hi]
+		for i := range cur {
+			if cur[i] != stored[i] {
+				return &CorruptSegmentError{Lo: seg.lo, Hi: seg.hi, Index: i}
+			}
+		}
+	}
+
+	root := csmt.ZeroHashes[csmt.Depth]
+	if top := csmt.Levels[csmt.Depth]; len(top) > 0 {
+		root = top[0]
+	}
+	if csmt.Root != root {
+		return fmt.Errorf("root does not match the stored top level")
+	}
+	return nil
+}
+
+// RebuildProofChecked is RebuildProof with a cross-check: the rebuilt path is
+// hashed up from the leaf and compared with the stored entry at every
+// checkpoint level it crosses, and with the root. The first mismatch is
+// reported as a *CorruptSegmentError naming the segment whose base entries
+// (or chunk data, for the bottom gap) or top entry is corrupt.
+func (csmt *CheckpointedSMT) RebuildProofChecked(leafIndex int, readChunk func(int) []byte, hashLeaf HashFuncFr) (*RebuildProofResult, error) {
+	return csmt.RebuildProofCheckedContext(context.Background(), leafIndex,
+		func(i int) ([]byte, error) { return readChunk(i), nil }, hashLeaf)
+}
+
+// RebuildProofCheckedContext is RebuildProofChecked with a fallible readChunk
+// and cancellation; see RebuildProofsContext. Read errors and cancellation
+// are returned as is, corrupt segments as a *CorruptSegmentError.
+func (csmt *CheckpointedSMT) RebuildProofCheckedContext(ctx context.Context, leafIndex int, readChunk func(int) ([]byte, error), hashLeaf HashFuncFr) (*RebuildProofResult, error) {
+	res, err := csmt.RebuildProofContext(ctx, leafIndex, readChunk, hashLeaf)
+	if err != nil {
+		return nil, err
+	}
+	if err := csmt.checkPath(leafIndex, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// checkPath hashes the rebuilt path of leafIndex up to the root, comparing it
+// with every stored checkpoint entry it crosses.
+func (csmt *CheckpointedSMT) checkPath(leafIndex int, res *RebuildProofResult) error {
+	stored := make(map[int]bool, len(csmt.Scheme.Levels))
+	for _, lvl := range csmt.Scheme.Levels {
+		stored[lvl] = true
+	}
 
-	return segSiblings
+	node := res.LeafHash
+	lo := 0
+	for lvl := 0; ; lvl++ {
+		if lvl > 0 && stored[lvl] {
+			idx := leafIndex >> lvl
+			want := csmt.ZeroHashes[lvl]
+			if entries := csmt.Levels[lvl]; idx < len(entries) {
+				want = entries[idx]
+			}
+			if node != want {
+				return &CorruptSegmentError{Lo: lo, Hi: lvl, Index: idx}
+			}
+			lo = lvl
+		}
+		if lvl == csmt.Depth {
+			break
+		}
+		if res.Directions[lvl] == 0 {
+			node = HashNodesFr(node, res.Siblings[lvl])
+		} else {
+			node = HashNodesFr(res.Siblings[lvl], node)
+		}
+	}
+	if node != csmt.Root {
+		return fmt.Errorf("rebuilt path does not hash to the root")
+	}
+	return nil
 }
 
 // ---------------------------------------------------------------------------
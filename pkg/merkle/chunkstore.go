@@ -0,0 +1,445 @@
+package merkle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// ---------------------------------------------------------------------------
+// Chunk stores
+// ---------------------------------------------------------------------------
+//
+// A ChunkStore gives random access to the fixed-size chunks of a stored file,
+// so tree construction, proof rebuilds and witness builders read only the
+// chunks they need from wherever the file lives. Chunking matches
+// SplitIntoChunks: the last chunk is zero-padded and an empty file has a
+// single zero chunk.
+
+// ChunkStore provides the chunks of one file.
+type ChunkStore interface {
+	// NumChunks returns the number of chunks, at least 1.
+	NumChunks() int
+	// ChunkSize returns the chunk size in bytes.
+	ChunkSize() int
+	// ReadChunk returns chunk i, zero-padded to ChunkSize. It may be called
+	// concurrently.
+	ReadChunk(ctx context.Context, i int) ([]byte, error)
+}
+
+// numChunksForSize returns the SplitIntoChunks chunk count of a size-byte file.
+func numChunksForSize(size int64, chunkSize int) (int, error) {
+	if chunkSize <= 0 {
+		return 0, fmt.Errorf("chunk size must be positive, got %d", chunkSize)
+	}
+	if size < 0 {
+		return 0, fmt.Errorf("file size must be non-negative, got %d", size)
+	}
+	n := (size + int64(chunkSize) - 1) / int64(chunkSize)
+	if n > math.MaxInt {
+		return 0, fmt.Errorf("file of %d bytes has too many chunks", size)
+	}
+	return max(int(n), 1), nil
+}
+
+func checkChunkIndex(store ChunkStore, i int) error {
+	if i < 0 || i >= store.NumChunks() {
+		return fmt.Errorf("chunk index %d out of range [0, %d)", i, store.NumChunks())
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// In memory
+// ---------------------------------------------------------------------------
+
+// MemChunkStore serves chunks already held in memory, e.g. the output of
+// SplitIntoChunks.
+type MemChunkStore struct {
+	chunks    [][]byte
+	chunkSize int
+}
+
+// NewMemChunkStore serves chunks, each at most chunkSize bytes. Shorter
+// chunks are zero-padded on read.
+func NewMemChunkStore(chunks [][]byte, chunkSize int) (*MemChunkStore, error) {
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("chunk size must be positive, got %d", chunkSize)
+	}
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("no chunks provided")
+	}
+	for i, chunk := range chunks {
+		if len(chunk) > chunkSize {
+			return nil, fmt.Errorf("chunk %d has %d bytes, exceeds %d", i, len(chunk), chunkSize)
+		}
+	}
+	return &MemChunkStore{chunks: chunks, chunkSize: chunkSize}, nil
+}
+
+// NumChunks returns the number of chunks.
+func (ms *MemChunkStore) NumChunks() int { return len(ms.chunks) }
+
+// ChunkSize returns the chunk size in bytes.
+func (ms *MemChunkStore) ChunkSize() int { return ms.chunkSize }
+
+// ReadChunk returns chunk i, zero-padded to ChunkSize. Full-size chunks are
+// returned without copying and must not be modified.
+func (ms *MemChunkStore) ReadChunk(ctx context.Context, i int) ([]byte, error) {
+	if err := checkChunkIndex(ms, i); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	chunk := ms.chunks[i]
+	if len(chunk) == ms.chunkSize {
+		return chunk, nil
+	}
+	buf := make([]byte, ms.chunkSize)
+	copy(buf, chunk)
+	return buf, nil
+}
+
+// ---------------------------------------------------------------------------
+// Flat file
+// ---------------------------------------------------------------------------
+
+// FileChunkStore serves chunks of a single flat file.
+type FileChunkStore struct {
+	r         io.ReaderAt
+	size      int64
+	chunkSize int
+	closer    io.Closer
+}
+
+// NewFileChunkStore serves the size-byte file read through r.
+func NewFileChunkStore(r io.ReaderAt, size int64, chunkSize int) (*FileChunkStore, error) {
+	if _, err := numChunksForSize(size, chunkSize); err != nil {
+		return nil, err
+	}
+	return &FileChunkStore{r: r, size: size, chunkSize: chunkSize}, nil
+}
+
+// OpenFileChunkStore opens the file at path. Close releases it.
+func OpenFileChunkStore(path string, chunkSize int) (*FileChunkStore, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open chunk file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("stat chunk file: %w", err)
+	}
+	fs, err := NewFileChunkStore(f, info.Size(), chunkSize)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	fs.closer = f
+	return fs, nil
+}
+
+// NumChunks returns the number of chunks in the file.
+func (fs *FileChunkStore) NumChunks() int {
+	n, _ := numChunksForSize(fs.size, fs.chunkSize)
+	return n
+}
+
+// ChunkSize returns the chunk size in bytes.
+func (fs *FileChunkStore) ChunkSize() int { return fs.chunkSize }
+
+// ReadChunk reads chunk i, zero-padding the last one.
+func (fs *FileChunkStore) ReadChunk(ctx context.Context, i int) ([]byte, error) {
+	if err := checkChunkIndex(fs, i); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	off := int64(i) * int64(fs.chunkSize)
+	buf := make([]byte, fs.chunkSize)
+	n := min(int64(fs.chunkSize), fs.size-off)
+	if n == 0 {
+		return buf, nil
+	}
+	// ReaderAt may report io.EOF alongside a full read of the last bytes.
+	if got, err := fs.r.ReadAt(buf[:n], off); err != nil && !(errors.Is(err, io.EOF) && int64(got) == n) {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// Close closes the file if the store opened it.
+func (fs *FileChunkStore) Close() error {
+	if fs.closer == nil {
+		return nil
+	}
+	return fs.closer.Close()
+}
+
+// ---------------------------------------------------------------------------
+// Directory of chunk files
+// ---------------------------------------------------------------------------
+
+// ChunkFileName returns the name of chunk i's file in a DirChunkStore.
+func ChunkFileName(i int) string {
+	return fmt.Sprintf("chunk_%08d", i)
+}
+
+// DirChunkStore serves chunks stored one per file, named by ChunkFileName.
+// Every chunk file holds at most ChunkSize bytes and is zero-padded on read.
+type DirChunkStore struct {
+	dir       string
+	numChunks int
+	chunkSize int
+}
+
+// NewDirChunkStore serves the chunk files in dir, which must be numbered
+// consecutively from 0.
+func NewDirChunkStore(dir string, chunkSize int) (*DirChunkStore, error) {
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("chunk size must be positive, got %d", chunkSize)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read chunk directory: %w", err)
+	}
+	present := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		present[e.Name()] = true
+	}
+	numChunks := 0
+	for present[ChunkFileName(numChunks)] {
+		numChunks++
+	}
+	if numChunks == 0 {
+		return nil, fmt.Errorf("chunk directory %s has no %s", dir, ChunkFileName(0))
+	}
+	return &DirChunkStore{dir: dir, numChunks: numChunks, chunkSize: chunkSize}, nil
+}
+
+// NumChunks returns the number of chunk files.
+func (ds *DirChunkStore) NumChunks() int { return ds.numChunks }
+
+// ChunkSize returns the chunk size in bytes.
+func (ds *DirChunkStore) ChunkSize() int { return ds.chunkSize }
+
+// ReadChunk reads chunk i's file, zero-padded to ChunkSize.
+func (ds *DirChunkStore) ReadChunk(ctx context.Context, i int) ([]byte, error) {
+	if err := checkChunkIndex(ds, i); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filepath.Join(ds.dir, ChunkFileName(i)))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Read one byte past ChunkSize to detect oversized chunk files.
+	buf := make([]byte, ds.chunkSize+1)
+	n, err := io.ReadFull(f, buf)
+	switch {
+	case err == nil:
+		return nil, fmt.Errorf("chunk file %s exceeds %d bytes", ChunkFileName(i), ds.chunkSize)
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		clear(buf[n:])
+		return buf[:ds.chunkSize], nil
+	default:
+		return nil, err
+	}
+}
+
+// ---------------------------------------------------------------------------
+// HTTP range requests
+// ---------------------------------------------------------------------------
+
+// HTTPChunkStore serves chunks of a file behind an HTTP(S) URL using range
+// requests, as supported by S3-compatible object stores and most static file
+// servers.
+type HTTPChunkStore struct {
+	client    *http.Client
+	url       string
+	size      int64
+	chunkSize int
+}
+
+// NewHTTPChunkStore issues a HEAD request to url to learn the file size.
+// A nil client uses http.DefaultClient.
+func NewHTTPChunkStore(ctx context.Context, client *http.Client, url string, chunkSize int) (*HTTPChunkStore, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("head %s: %w", url, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("head %s: %s", url, resp.Status)
+	}
+	if resp.ContentLength < 0 {
+		return nil, fmt.Errorf("head %s: no content length", url)
+	}
+	if _, err := numChunksForSize(resp.ContentLength, chunkSize); err != nil {
+		return nil, err
+	}
+	return &HTTPChunkStore{client: client, url: url, size: resp.ContentLength, chunkSize: chunkSize}, nil
+}
+
+// NumChunks returns the number of chunks in the remote file.
+func (hs *HTTPChunkStore) NumChunks() int {
+	n, _ := numChunksForSize(hs.size, hs.chunkSize)
+	return n
+}
+
+// ChunkSize returns the chunk size in bytes.
+func (hs *HTTPChunkStore) ChunkSize() int { return hs.chunkSize }
+
+// ReadChunk fetches chunk i with a single range request.
+func (hs *HTTPChunkStore) ReadChunk(ctx context.Context, i int) ([]byte, error) {
+	if err := checkChunkIndex(hs, i); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, hs.chunkSize)
+	off := int64(i) * int64(hs.chunkSize)
+	n := min(int64(hs.chunkSize), hs.size-off)
+	if n == 0 {
+		return buf, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, hs.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", "bytes="+strconv.FormatInt(off, 10)+"-"+strconv.FormatInt(off+n-1, 10))
+	resp, err := hs.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get chunk %d: %w", i, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("get chunk %d: %s", i, resp.Status)
+	}
+	if err := checkContentRange(resp.Header.Get("Content-Range"), off, n, hs.size); err != nil {
+		return nil, fmt.Errorf("get chunk %d: %w", i, err)
+	}
+	if _, err := io.ReadFull(resp.Body, buf[:n]); err != nil {
+		return nil, fmt.Errorf("get chunk %d: %w", i, err)
+	}
+	return buf, nil
+}
+
+// checkContentRange checks that a Content-Range header ("bytes first-last/size")
+// covers exactly the n bytes requested at off of a size-byte object.
+func checkContentRange(header string, off, n, size int64) error {
+	spec, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return fmt.Errorf("invalid content range %q", header)
+	}
+	span, total, ok := strings.Cut(spec, "/")
+	if !ok {
+		return fmt.Errorf("invalid content range %q", header)
+	}
+	first, last, ok := strings.Cut(span, "-")
+	if !ok {
+		return fmt.Errorf("invalid content range %q", header)
+	}
+	firstN, err1 := strconv.ParseInt(first, 10, 64)
+	lastN, err2 := strconv.ParseInt(last, 10, 64)
+	if err1 != nil || err2 != nil {
+		return fmt.Errorf("invalid content range %q", header)
+	}
+	if firstN != off || lastN != off+n-1 {
+		return fmt.Errorf("content range %q does not match requested bytes %d-%d", header, off, off+n-1)
+	}
+	if total != "*" && total != strconv.FormatInt(size, 10) {
+		return fmt.Errorf("content range %q does not match object size %d", header, size)
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// Tree construction and proofs from a ChunkStore
+// ---------------------------------------------------------------------------
+
+// GenerateSparseMerkleTreeFromStore builds the same tree as
+// GenerateSparseMerkleTree over the store's chunks, reading and hashing them
+// with a worker pool.
+func GenerateSparseMerkleTreeFromStore(ctx context.Context, store ChunkStore, depth int, hashLeaf HashFuncFr, zeroLeafHash fr.Element) (*SparseMerkleTree, error) {
+	numChunks := store.NumChunks()
+	if err := validateLeafCapacity(depth, numChunks); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	// Feed indices through an unbuffered channel so memory stays independent
+	// of numChunks; the feeder stops as soon as a worker fails.
+	leafHashes := make([]fr.Element, numChunks)
+	work := make(chan int)
+	go func() {
+		defer close(work)
+		for i := 0; i < numChunks; i++ {
+			select {
+			case work <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < min(runtime.NumCPU(), numChunks); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				if ctx.Err() != nil {
+					return
+				}
+				chunk, err := store.ReadChunk(ctx, i)
+				if err != nil {
+					cancel(fmt.Errorf("read chunk %d: %w", i, err))
+					return
+				}
+				leafHashes[i] = hashLeaf(chunk)
+			}
+		}()
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil, context.Cause(ctx)
+	}
+	return BuildSMTFromLeafHashes(leafHashes, depth, zeroLeafHash)
+}
+
+// RebuildProofsFromStore is RebuildProofsContext reading chunks from store,
+// which must hold exactly the tree's leaves. Reads are issued under the
+// rebuild's own context, so the first failed read also aborts reads still
+// in flight on other workers.
+func (csmt *CheckpointedSMT) RebuildProofsFromStore(ctx context.Context, leafIndices []int, store ChunkStore, hashLeaf HashFuncFr) ([]*RebuildProofResult, error) {
+	if csmt.NumLeaves > 0 && store.NumChunks() != csmt.NumLeaves {
+		return nil, fmt.Errorf("store has %d chunks but tree has %d leaves", store.NumChunks(), csmt.NumLeaves)
+	}
+	return csmt.rebuildProofs(ctx, leafIndices, store.ReadChunk, hashLeaf)
+}
@@ -0,0 +1,349 @@
+package merkle
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// faultyStore fails to read chunk fail. With block set, reads of earlier
+// chunks wait until their context is done.
+type faultyStore struct {
+	ChunkStore
+	fail  int
+	block bool
+}
+
+func (s faultyStore) ReadChunk(ctx context.Context, i int) ([]byte, error) {
+	if i == s.fail {
+		return nil, errors.New("injected read error")
+	}
+	if s.block && i < s.fail {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return s.ChunkStore.ReadChunk(ctx, i)
+}
+
+// newTestChunkStores returns an in-memory, a flat-file, a chunk-directory and
+// an HTTP range-request store over data, the last served by a local test
+// server.
+func newTestChunkStores(t *testing.T, data []byte) map[string]ChunkStore {
+	t.Helper()
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "file.bin")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	fileStore, err := OpenFileChunkStore(path, testChunkSize)
+	if err != nil {
+		t.Fatalf("open file store: %v", err)
+	}
+	t.Cleanup(func() { fileStore.Close() })
+
+	// The last chunk file is stored unpadded.
+	chunkDir := filepath.Join(dir, "chunks")
+	if err := os.Mkdir(chunkDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i == 0 || i*testChunkSize < len(data); i++ {
+		chunk := data[min(i*testChunkSize, len(data)):min((i+1)*testChunkSize, len(data))]
+		if err := os.WriteFile(filepath.Join(chunkDir, ChunkFileName(i)), chunk, 0o644); err != nil {
+			t.Fatalf("write chunk %d: %v", i, err)
+		}
+	}
+	dirStore, err := NewDirChunkStore(chunkDir, testChunkSize)
+	if err != nil {
+		t.Fatalf("new dir store: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(data))
+	}))
+	t.Cleanup(srv.Close)
+	httpStore, err := NewHTTPChunkStore(context.Background(), srv.Client(), srv.URL+"/file.bin", testChunkSize)
+	if err != nil {
+		t.Fatalf("new HTTP store: %v", err)
+	}
+
+	memStore, err := NewMemChunkStore(SplitIntoChunks(data, testChunkSize), testChunkSize)
+	if err != nil {
+		t.Fatalf("new mem store: %v", err)
+	}
+
+	return map[string]ChunkStore{"mem": memStore, "file": fileStore, "dir": dirStore, "http": httpStore}
+}
+
+// TestChunkStores checks that every backend serves the SplitIntoChunks
+// chunks and builds the same tree and proofs as the in-memory path.
+func TestChunkStores(t *testing.T) {
+	zeroLeaf := testZeroLeafHash()
+	for _, size := range []int{0, 1, testChunkSize, 3*testChunkSize + 100, 40 * testChunkSize} {
+		data := make([]byte, size)
+		if _, err := rand.Read(data); err != nil {
+			t.Fatal(err)
+		}
+		chunks := SplitIntoChunks(data, testChunkSize)
+		want, err := GenerateSparseMerkleTree(chunks, testMaxDepth, testHashChunk, zeroLeaf)
+		if err != nil {
+			t.Fatalf("build SMT: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := want.SaveCheckpointed(&buf, SchemeCompact); err != nil {
+			t.Fatalf("save checkpointed: %v", err)
+		}
+		csmt, err := LoadCheckpointedSMT(&buf, zeroLeaf)
+		if err != nil {
+			t.Fatalf("load checkpointed: %v", err)
+		}
+
+		for name, store := range newTestChunkStores(t, data) {
+			if store.NumChunks() != len(chunks) || store.ChunkSize() != testChunkSize {
+				t.Fatalf("%s size %d: %d chunks of %d bytes, expected %d of %d", name, size, store.NumChunks(), store.ChunkSize(), len(chunks), testChunkSize)
+			}
+			for i := range chunks {
+				chunk, err := store.ReadChunk(context.Background(), i)
+				if err != nil {
+					t.Fatalf("%s size %d: read chunk %d: %v", name, size, i, err)
+				}
+				if !bytes.Equal(chunk, chunks[i]) {
+					t.Fatalf("%s size %d: chunk %d mismatch", name, size, i)
+				}
+			}
+			if _, err := store.ReadChunk(context.Background(), len(chunks)); err == nil {
+				t.Fatalf("%s: expected out-of-range error", name)
+			}
+
+			got, err := GenerateSparseMerkleTreeFromStore(context.Background(), store, testMaxDepth, testHashChunk, zeroLeaf)
+			if err != nil {
+				t.Fatalf("%s size %d: build SMT from store: %v", name, size, err)
+			}
+			if got.Root != want.Root || got.NumLeaves != want.NumLeaves {
+				t.Fatalf("%s size %d: root or numLeaves mismatch", name, size)
+			}
+
+			indices := []int{0, len(chunks) - 1, len(chunks)}
+			results, err := csmt.RebuildProofsFromStore(context.Background(), indices, store, testHashChunk)
+			if err != nil {
+				t.Fatalf("%s size %d: rebuild proofs: %v", name, size, err)
+			}
+			for k, leafIdx := range indices {
+				sibs, dirs := want.GetProof(leafIdx)
+				for lvl := range sibs {
+					if results[k].Siblings[lvl] != sibs[lvl] || results[k].Directions[lvl] != dirs[lvl] {
+						t.Fatalf("%s size %d leaf %d: proof mismatch at level %d", name, size, leafIdx, lvl)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestChunkStoresRejectInvalid(t *testing.T) {
+	data := make([]byte, 3*testChunkSize)
+	stores := newTestChunkStores(t, data)
+
+	// Trees must cover exactly the store's chunks.
+	smt, err := GenerateSparseMerkleTree(SplitIntoChunks(data[:testChunkSize], testChunkSize), testMaxDepth, testHashChunk, testZeroLeafHash())
+	if err != nil {
+		t.Fatalf("build SMT: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := smt.SaveCheckpointed(&buf, SchemeCompact); err != nil {
+		t.Fatalf("save checkpointed: %v", err)
+	}
+	csmt, err := LoadCheckpointedSMT(&buf, testZeroLeafHash())
+	if err != nil {
+		t.Fatalf("load checkpointed: %v", err)
+	}
+	if _, err := csmt.RebuildProofsFromStore(context.Background(), []int{0}, stores["file"], testHashChunk); err == nil {
+		t.Fatal("expected chunk count mismatch error")
+	}
+	if _, err := GenerateSparseMerkleTreeFromStore(context.Background(), stores["dir"], 1, testHashChunk, testZeroLeafHash()); err == nil {
+		t.Fatal("expected oversized tree error")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for name, store := range stores {
+		if _, err := GenerateSparseMerkleTreeFromStore(ctx, store, testMaxDepth, testHashChunk, testZeroLeafHash()); err == nil {
+			t.Fatalf("%s: expected cancellation error", name)
+		}
+	}
+
+	// Chunk directories must start at chunk 0 and hold no oversized chunks.
+	dir := t.TempDir()
+	if _, err := NewDirChunkStore(dir, testChunkSize); err == nil {
+		t.Fatal("expected empty directory error")
+	}
+	if err := os.WriteFile(filepath.Join(dir, ChunkFileName(0)), make([]byte, testChunkSize+1), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ds, err := NewDirChunkStore(dir, testChunkSize)
+	if err != nil {
+		t.Fatalf("new dir store: %v", err)
+	}
+	if _, err := ds.ReadChunk(context.Background(), 0); err == nil {
+		t.Fatal("expected oversized chunk file error")
+	}
+
+	// A server without range support, or without the object, is rejected.
+	noRanges := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Write(data)
+	}))
+	defer noRanges.Close()
+	hs, err := NewHTTPChunkStore(context.Background(), noRanges.Client(), noRanges.URL, testChunkSize)
+	if err != nil {
+		t.Fatalf("new HTTP store: %v", err)
+	}
+	if _, err := hs.ReadChunk(context.Background(), 1); err == nil {
+		t.Fatal("expected error from server ignoring ranges")
+	}
+	// A 206 response must carry exactly the requested range.
+	for name, contentRange := range map[string]func(first, last int) string{
+		"shifted range": func(first, last int) string {
+			return "bytes " + strconv.Itoa(first+1) + "-" + strconv.Itoa(last+1) + "/" + strconv.Itoa(len(data))
+		},
+		"wrong size": func(first, last int) string {
+			return "bytes " + strconv.Itoa(first) + "-" + strconv.Itoa(last) + "/" + strconv.Itoa(len(data)+1)
+		},
+		"missing": func(int, int) string { return "" },
+	} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead {
+				w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+				return
+			}
+			first, last := testChunkSize, 2*testChunkSize-1
+			if cr := contentRange(first, last); cr != "" {
+				w.Header().Set("Content-Range", cr)
+			}
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(data[first : last+1])
+		}))
+		hs, err := NewHTTPChunkStore(context.Background(), srv.Client(), srv.URL, testChunkSize)
+		if err != nil {
+			t.Fatalf("%s: new HTTP store: %v", name, err)
+		}
+		if _, err := hs.ReadChunk(context.Background(), 1); err == nil {
+			t.Fatalf("%s: expected content range error", name)
+		}
+		srv.Close()
+	}
+
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+	if _, err := NewHTTPChunkStore(context.Background(), missing.Client(), missing.URL, testChunkSize); err == nil {
+		t.Fatal("expected error for missing object")
+	}
+}
+
+// TestChunkStoreReadErrors checks that a failed read aborts tree builds and
+// proof rebuilds, including reads blocked on other workers.
+func TestChunkStoreReadErrors(t *testing.T) {
+	data := make([]byte, 8*testChunkSize)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	store := newTestChunkStores(t, data)["file"]
+
+	if _, err := GenerateSparseMerkleTreeFromStore(context.Background(), faultyStore{ChunkStore: store, fail: 5}, testMaxDepth, testHashChunk, testZeroLeafHash()); err == nil {
+		t.Fatal("expected read error from tree build")
+	}
+
+	// Leaves 0 and 4 fall in different bottom subtrees, rebuilt
+	// concurrently: the read of chunk 4 fails while chunks 0-3 block. A
+	// fifth leaf hasher keeps chunk 4 from queueing behind the blocked reads.
+	defer func(n int) { leafHashWorkers = n }(leafHashWorkers)
+	leafHashWorkers = 5
+	smt, err := GenerateSparseMerkleTree(SplitIntoChunks(data, testChunkSize), testMaxDepth, testHashChunk, testZeroLeafHash())
+	if err != nil {
+		t.Fatalf("build SMT: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := smt.SaveCheckpointed(&buf, CheckpointScheme{Levels: []int{2, testMaxDepth}}); err != nil {
+		t.Fatalf("save checkpointed: %v", err)
+	}
+	csmt, err := LoadCheckpointedSMT(&buf, testZeroLeafHash())
+	if err != nil {
+		t.Fatalf("load checkpointed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := csmt.RebuildProofsFromStore(ctx, []int{0, 4}, faultyStore{ChunkStore: store, fail: 4, block: true}, testHashChunk); err == nil {
+		t.Fatal("expected read error from proof rebuild")
+	}
+	if ctx.Err() != nil {
+		t.Fatal("blocked read was released by the caller's deadline, not the read error")
+	}
+}
+
+// countingStore records the largest number of concurrent reads.
+type countingStore struct {
+	ChunkStore
+	mu             sync.Mutex
+	active, maxAct int
+}
+
+func (s *countingStore) ReadChunk(ctx context.Context, i int) ([]byte, error) {
+	s.mu.Lock()
+	s.active++
+	s.maxAct = max(s.maxAct, s.active)
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.active--
+		s.mu.Unlock()
+	}()
+	time.Sleep(time.Millisecond)
+	return s.ChunkStore.ReadChunk(ctx, i)
+}
+
+// TestRebuildProofsSharesLeafHashers checks that bottom-gap rebuilds of
+// several subtrees share one bounded pool of leaf hashers.
+func TestRebuildProofsSharesLeafHashers(t *testing.T) {
+	data := make([]byte, 16*testChunkSize)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	smt, err := GenerateSparseMerkleTree(SplitIntoChunks(data, testChunkSize), testMaxDepth, testHashChunk, testZeroLeafHash())
+	if err != nil {
+		t.Fatalf("build SMT: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := smt.SaveCheckpointed(&buf, CheckpointScheme{Levels: []int{2, testMaxDepth}}); err != nil {
+		t.Fatalf("save checkpointed: %v", err)
+	}
+	csmt, err := LoadCheckpointedSMT(&buf, testZeroLeafHash())
+	if err != nil {
+		t.Fatalf("load checkpointed: %v", err)
+	}
+
+	defer func(n int) { leafHashWorkers = n }(leafHashWorkers)
+	leafHashWorkers = 2
+	store := &countingStore{ChunkStore: newTestChunkStores(t, data)["mem"]}
+	indices := []int{0, 4, 8, 12}
+	results, err := csmt.RebuildProofsFromStore(context.Background(), indices, store, testHashChunk)
+	if err != nil {
+		t.Fatalf("rebuild proofs: %v", err)
+	}
+	if store.maxAct > leafHashWorkers {
+		t.Fatalf("%d concurrent reads, expected at most %d", store.maxAct, leafHashWorkers)
+	}
+	for k, idx := range indices {
+		if results[k].LeafHash != smt.GetLeafHash(idx) {
+			t.Fatalf("leaf %d: leaf hash mismatch", idx)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// ---------------------------------------------------------------------------
+// Versioned SMT serialization
+// ---------------------------------------------------------------------------
+//
+// Format (all integers big-endian):
+//   [4]byte magic "MSMT" | uint32(version) | uint32(depth) |
+//   uint64(numLeaves) | uint32(chunkSize) | [32]byte(zeroLeafHash)
+//   numLeaves × [32]byte(leaf hash as big-endian fr.Element)
+//   [32]byte(root)
+//   [32]byte(SHA-256 of everything above)
+//
+// Only the leaf hashes and the root are stored; the inner levels are
+// recomputed on load, which also proves the stored root matches the leaves.
+// The zero leaf hash fingerprints the leaf hash function and chunk layout the
+// tree was built with, so a file cannot be loaded against the wrong one.
+
+const (
+	smtFileMagic   = "MSMT"
+	smtFileVersion = 1
+	// magic | version | depth | numLeaves | chunkSize | zeroLeafHash
+	smtFileHeaderSize = 4 + 4 + 4 + 8 + 4 + 32
+)
+
+// SaveVersioned writes the tree to w in the versioned, checksummed format.
+// chunkSize records the file chunk size the leaves were hashed from.
+func (smt *SparseMerkleTree) SaveVersioned(w io.Writer, chunkSize int) error {
+	if chunkSize <= 0 || uint64(chunkSize) > 1<<32-1 {
+		return fmt.Errorf("invalid chunk size %d", chunkSize)
+	}
+	digest := sha256.New()
+	mw := io.MultiWriter(w, digest)
+
+	var header [smtFileHeaderSize]byte
+	copy(header[0:4], smtFileMagic)
+	binary.BigEndian.PutUint32(header[4:8], smtFileVersion)
+	binary.BigEndian.PutUint32(header[8:12], uint32(smt.Depth))
+	binary.BigEndian.PutUint64(header[12:20], uint64(smt.NumLeaves))
+	binary.BigEndian.PutUint32(header[20:24], uint32(chunkSize))
+	zl := smt.ZeroHashes[0].Bytes()
+	copy(header[24:56], zl[:])
+	if _, err := mw.Write(header[:]); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+
+	for i := 0; i < smt.NumLeaves; i++ {
+		b := smt.Levels[0][i].Bytes()
+		if _, err := mw.Write(b[:]); err != nil {
+			return fmt.Errorf("write leaf %d: %w", i, err)
+		}
+	}
+	root := smt.Root.Bytes()
+	if _, err := mw.Write(root[:]); err != nil {
+		return fmt.Errorf("write root: %w", err)
+	}
+
+	if _, err := w.Write(digest.Sum(nil)); err != nil {
+		return fmt.Errorf("write digest: %w", err)
+	}
+	return nil
+}
+
+// LoadVersionedSMT reads a tree written by SaveVersioned and returns it with
+// the recorded chunk size. It fails if the file is truncated, its digest does
+// not match, it was built with a different zeroLeafHash, or its stored root
+// does not recompute from its leaves.
+func LoadVersionedSMT(r io.Reader, zeroLeafHash fr.Element) (*SparseMerkleTree, int, error) {
+	digest := sha256.New()
+	tr := io.TeeReader(r, digest)
+
+	var header [smtFileHeaderSize]byte
+	if _, err := io.ReadFull(tr, header[:]); err != nil {
+		return nil, 0, fmt.Errorf("read header: %w", err)
+	}
+	if string(header[0:4]) != smtFileMagic {
+		return nil, 0, fmt.Errorf("not a versioned SMT file")
+	}
+	if v := binary.BigEndian.Uint32(header[4:8]); v != smtFileVersion {
+		return nil, 0, fmt.Errorf("unsupported SMT file version %d", v)
+	}
+	depth := int(binary.BigEndian.Uint32(header[8:12]))
+	maxLeaves, err := maxLeavesForDepth(depth)
+	if err != nil || depth > 32 {
+		return nil, 0, fmt.Errorf("invalid tree depth %d", depth)
+	}
+	numLeaves64 := binary.BigEndian.Uint64(header[12:20])
+	if numLeaves64 > uint64(maxLeaves) {
+		return nil, 0, fmt.Errorf("tree depth %d supports at most %d leaves, got %d", depth, maxLeaves, numLeaves64)
+	}
+	numLeaves := int(numLeaves64)
+	chunkSize := int(binary.BigEndian.Uint32(header[20:24]))
+	if chunkSize == 0 {
+		return nil, 0, fmt.Errorf("invalid chunk size 0")
+	}
+	zl := zeroLeafHash.Bytes()
+	if !bytes.Equal(header[24:56], zl[:]) {
+		return nil, 0, fmt.Errorf("tree was built with a different zero leaf hash")
+	}
+
+	// Leaves are read in bounded batches so a forged leaf count cannot force
+	// a large allocation before the data is actually there.
+	const batch = 1 << 16
+	var leafHashes []fr.Element
+	var hashBuf [32]byte
+	for len(leafHashes) < numLeaves {
+		n := min(batch, numLeaves-len(leafHashes))
+		leafHashes = append(leafHashes, make([]fr.Element, n)...)
+		for i := len(leafHashes) - n; i < len(leafHashes); i++ {
+			if _, err := io.ReadFull(tr, hashBuf[:]); err != nil {
+				return nil, 0, fmt.Errorf("read leaf %d: %w", i, err)
+			}
+			if err := leafHashes[i].SetBytesCanonical(hashBuf[:]); err != nil {
+				return nil, 0, fmt.Errorf("leaf %d: %w", i, err)
+			}
+		}
+	}
+
+	var storedRoot fr.Element
+	if _, err := io.ReadFull(tr, hashBuf[:]); err != nil {
+		return nil, 0, fmt.Errorf("read root: %w", err)
+	}
+	if err := storedRoot.SetBytesCanonical(hashBuf[:]); err != nil {
+		return nil, 0, fmt.Errorf("root: %w", err)
+	}
+
+	want := digest.Sum(nil)
+	if _, err := io.ReadFull(r, hashBuf[:]); err != nil {
+		return nil, 0, fmt.Errorf("read digest: %w", err)
+	}
+	if !bytes.Equal(hashBuf[:], want) {
+		return nil, 0, fmt.Errorf("digest mismatch: file is corrupted")
+	}
+
+	smt, err := BuildSMTFromLeafHashes(leafHashes, depth, zeroLeafHash)
+	if err != nil {
+		return nil, 0, err
+	}
+	if smt.Root != storedRoot {
+		return nil, 0, fmt.Errorf("stored root does not match the recomputed root")
+	}
+	return smt, chunkSize, nil
+}
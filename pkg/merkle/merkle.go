@@ -1,7 +1,7 @@
 package merkle
 
 import (
-	"bytes"
+	"context"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -14,46 +14,6 @@ import (
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
 )
 
-// MerkleNode represents a node in the Merkle tree.
-type MerkleNode struct {
-	Hash   *big.Int
-	Left   *MerkleNode
-	Right  *MerkleNode
-	Parent *MerkleNode
-	IsLeaf bool
-}
-
-// MerkleTree represents the complete Merkle tree.
-type MerkleTree struct {
-	Root       *MerkleNode
-	Leaves     []*MerkleNode
-	FileSize   int64
-	ChunkCount int
-}
-
-// HashFunc is the function used to hash leaf chunks. Callers provide it so the
-// merkle package stays independent of circuit-specific hashing parameters.
-type HashFunc func(chunk []byte) *big.Int
-
-// NewMerkleNode creates a new Merkle tree node.
-func NewMerkleNode(hash *big.Int, left, right *MerkleNode) *MerkleNode {
-	node := &MerkleNode{
-		Hash:   hash,
-		Left:   left,
-		Right:  right,
-		IsLeaf: left == nil && right == nil,
-	}
-
-	if left != nil {
-		left.Parent = node
-	}
-	if right != nil {
-		right.Parent = node
-	}
-
-	return node
-}
-
 // SplitIntoChunks splits the file data into chunkSize-sized chunks.
 // The last chunk is zero-padded so that every returned slice has the same
 // length. An empty input produces a single zero chunk.
@@ -113,221 +73,6 @@ func validateLeafCapacity(depth, numLeaves int) error {
 	return nil
 }
 
-// GenerateMerkleTree builds a Merkle tree from pre-split chunks.
-// hashLeaf is used to hash each leaf chunk (typically Poseidon2 with randomness=1).
-// chunkSize is the expected size of each chunk (used for zero-padding fallback).
-func GenerateMerkleTree(chunks [][]byte, chunkSize int, hashLeaf HashFunc) *MerkleTree {
-	if len(chunks) == 0 {
-		// No chunks provided - create a single zero chunk
-		chunks = [][]byte{make([]byte, chunkSize)}
-	}
-
-	// Pad to at least two leaves and then to the next power-of-two by
-	// repeating existing chunks.
-	chunks = padToPowerOfTwo(chunks)
-
-	// Create leaf nodes by hashing each chunk
-	leaves := make([]*MerkleNode, len(chunks))
-	for i, chunk := range chunks {
-		leaves[i] = NewMerkleNode(hashLeaf(chunk), nil, nil)
-	}
-
-	// Build the tree bottom-up
-	currentLevel := leaves
-	for len(currentLevel) > 1 {
-		nextLevel := make([]*MerkleNode, 0, (len(currentLevel)+1)/2)
-		for i := 0; i < len(currentLevel); i += 2 {
-			left := currentLevel[i]
-			var right *MerkleNode
-			if i+1 < len(currentLevel) {
-				right = currentLevel[i+1]
-			} else {
-				// Duplicate the last node for odd counts
-				right = left
-			}
-
-			parent := NewMerkleNode(HashNodes(left.Hash, right.Hash), left, right)
-			nextLevel = append(nextLevel, parent)
-		}
-		currentLevel = nextLevel
-	}
-
-	return &MerkleTree{
-		Root:       currentLevel[0],
-		Leaves:     leaves,
-		FileSize:   int64(len(chunks) * chunkSize),
-		ChunkCount: len(chunks),
-	}
-}
-
-// GetRoot returns the root hash of the Merkle tree.
-func (mt *MerkleTree) GetRoot() *big.Int {
-	if mt.Root == nil {
-		return big.NewInt(0)
-	}
-	return mt.Root.Hash
-}
-
-// GetLeafCount returns the number of leaf nodes.
-func (mt *MerkleTree) GetLeafCount() int {
-	return len(mt.Leaves)
-}
-
-// GetHeight returns the height of the tree (number of levels).
-func (mt *MerkleTree) GetHeight() int {
-	if mt.Root == nil {
-		return 0
-	}
-	return getNodeHeight(mt.Root)
-}
-
-// getNodeHeight calculates the height of a node recursively.
-func getNodeHeight(node *MerkleNode) int {
-	if node == nil || node.IsLeaf {
-		return 1
-	}
-
-	leftHeight := getNodeHeight(node.Left)
-	rightHeight := getNodeHeight(node.Right)
-
-	if leftHeight > rightHeight {
-		return leftHeight + 1
-	}
-	return rightHeight + 1
-}
-
-// GetMerkleProof generates a Merkle proof for the leaf at the given index.
-func (mt *MerkleTree) GetMerkleProof(leafIndex int) ([]*big.Int, []bool, error) {
-	if leafIndex < 0 || leafIndex >= len(mt.Leaves) {
-		return nil, nil, fmt.Errorf("invalid leaf index: %d", leafIndex)
-	}
-
-	var proof []*big.Int
-	var directions []bool // true for right, false for left
-
-	current := mt.Leaves[leafIndex]
-
-	for current.Parent != nil {
-		parent := current.Parent
-
-		if parent.Left == current {
-			// Current is left child, so sibling is right
-			if parent.Right != nil {
-				proof = append(proof, parent.Right.Hash)
-				directions = append(directions, true) // sibling is on the right
-			}
-		} else {
-			// Current is right child, so sibling is left
-			if parent.Left != nil {
-				proof = append(proof, parent.Left.Hash)
-				directions = append(directions, false) // sibling is on the left
-			}
-		}
-
-		current = parent
-	}
-
-	return proof, directions, nil
-}
-
-// VerifyMerkleProof verifies a Merkle proof for a given leaf hash.
-func VerifyMerkleProof(leafHash *big.Int, proof []*big.Int, directions []bool, rootHash *big.Int) bool {
-	if len(proof) != len(directions) {
-		return false
-	}
-
-	current := leafHash
-
-	for i := 0; i < len(proof); i++ {
-		sibling := proof[i]
-		isRight := directions[i]
-
-		if isRight {
-			// Sibling is on the right
-			current = HashNodes(current, sibling)
-		} else {
-			// Sibling is on the left
-			current = HashNodes(sibling, current)
-		}
-	}
-
-	return current.Cmp(rootHash) == 0
-}
-
-// String returns a string representation of the tree structure.
-func (mt *MerkleTree) String() string {
-	if mt.Root == nil {
-		return "Empty tree"
-	}
-
-	var buf bytes.Buffer
-	printNode(mt.Root, "", true, &buf)
-	return buf.String()
-}
-
-// printNode recursively prints the tree structure.
-func printNode(node *MerkleNode, prefix string, isLast bool, buf *bytes.Buffer) {
-	if node == nil {
-		return
-	}
-
-	// Print current node
-	connector := "├── "
-	if isLast {
-		connector = "└── "
-	}
-
-	nodeType := "Node"
-	if node.IsLeaf {
-		nodeType = "Leaf"
-	}
-
-	buf.WriteString(fmt.Sprintf("%s%s%s: %s\n", prefix, connector, nodeType, node.Hash.String()[:16]+"..."))
-
-	// Calculate prefix for children
-	childPrefix := prefix
-	if isLast {
-		childPrefix += "    "
-	} else {
-		childPrefix += "│   "
-	}
-
-	// Print children
-	if node.Left != nil || node.Right != nil {
-		if node.Left != nil {
-			printNode(node.Left, childPrefix, node.Right == nil, buf)
-		}
-		if node.Right != nil {
-			printNode(node.Right, childPrefix, true, buf)
-		}
-	}
-}
-
-// padToPowerOfTwo duplicates existing chunks until the slice length is at least
-// two and then the next power of two. The minimum-two rule guarantees proof
-// depth >= 1, so singleton files remain provable without allowing 0-depth paths.
-func padToPowerOfTwo(chunks [][]byte) [][]byte {
-	n := len(chunks)
-	if n == 0 {
-		return chunks
-	}
-
-	// Compute next power of two >= n.
-	nextPow := 1
-	for nextPow < n {
-		nextPow <<= 1
-	}
-	if nextPow < 2 {
-		nextPow = 2
-	}
-
-	// Duplicate chunks in round-robin fashion until we reach nextPow length.
-	for i := 0; len(chunks) < nextPow; i++ {
-		chunks = append(chunks, chunks[i%n])
-	}
-	return chunks
-}
-
 // ---------------------------------------------------------------------------
 // Sparse Merkle Tree (fixed-depth, fr.Element + flat slices)
 // ---------------------------------------------------------------------------
@@ -453,57 +198,60 @@ func GenerateSparseMerkleTree(chunks [][]byte, depth int, hashLeaf HashFuncFr, z
 // buildTreeLevels builds intermediate tree levels bottom-up, using parallel
 // workers for levels with more parents than parallelBuildThreshold.
 func buildTreeLevels(levels [][]fr.Element, zeroHashes []fr.Element, depth int) {
+	for lvl := 0; lvl < depth; lvl++ {
+		hashLevel(levels[lvl], levels[lvl+1], zeroHashes[lvl])
+	}
+}
+
+// hashLevel fills next with the parents of cur, pairing a trailing odd entry
+// with the zero subtree hash zh. Levels with more parents than
+// parallelBuildThreshold are hashed by parallel workers.
+func hashLevel(cur, next []fr.Element, zh fr.Element) {
+	numParents := len(next)
+	if numParents == 0 {
+		return
+	}
 	numCPU := runtime.NumCPU()
 
-	for lvl := 0; lvl < depth; lvl++ {
-		cur := levels[lvl]
-		numParents := len(levels[lvl+1])
-		if numParents == 0 {
-			continue
+	if numParents > parallelBuildThreshold && numCPU > 1 {
+		numWorkers := numCPU
+		if numWorkers > numParents {
+			numWorkers = numParents
 		}
-		next := levels[lvl+1]
-		zh := zeroHashes[lvl]
 
-		if numParents > parallelBuildThreshold && numCPU > 1 {
-			numWorkers := numCPU
-			if numWorkers > numParents {
-				numWorkers = numParents
-			}
-
-			var wg sync.WaitGroup
-			work := make(chan int, numParents)
-			for w := 0; w < numWorkers; w++ {
-				wg.Add(1)
-				go func() {
-					defer wg.Done()
-					for p := range work {
-						left := cur[2*p]
-						var right fr.Element
-						if 2*p+1 < len(cur) {
-							right = cur[2*p+1]
-						} else {
-							right = zh
-						}
-						next[p] = HashNodesFr(left, right)
+		var wg sync.WaitGroup
+		work := make(chan int, numParents)
+		for w := 0; w < numWorkers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for p := range work {
+					left := cur[2*p]
+					var right fr.Element
+					if 2*p+1 < len(cur) {
+						right = cur[2*p+1]
+					} else {
+						right = zh
 					}
-				}()
-			}
-			for p := 0; p < numParents; p++ {
-				work <- p
-			}
-			close(work)
-			wg.Wait()
-		} else {
-			for p := 0; p < numParents; p++ {
-				left := cur[2*p]
-				var right fr.Element
-				if 2*p+1 < len(cur) {
-					right = cur[2*p+1]
-				} else {
-					right = zh
+					next[p] = HashNodesFr(left, right)
 				}
-				next[p] = HashNodesFr(left, right)
+			}()
+		}
+		for p := 0; p < numParents; p++ {
+			work <- p
+		}
+		close(work)
+		wg.Wait()
+	} else {
+		for p := 0; p < numParents; p++ {
+			left := cur[2*p]
+			var right fr.Element
+			if 2*p+1 < len(cur) {
+				right = cur[2*p+1]
+			} else {
+				right = zh
 			}
+			next[p] = HashNodesFr(left, right)
 		}
 	}
 }
@@ -543,41 +291,110 @@ func BuildSMTFromLeafHashes(leafHashes []fr.Element, depth int, zeroLeafHash fr.
 	}, nil
 }
 
-// GetProof returns a fixed-size Merkle proof for the leaf at the given index.
-func (smt *SparseMerkleTree) GetProof(leafIndex int) ([]fr.Element, []int) {
-	siblings := make([]fr.Element, smt.Depth)
-	directions := make([]int, smt.Depth)
+// Append hashes chunks with hashLeaf and appends them as real leaves
+// NumLeaves..NumLeaves+len(chunks)-1, updating the tree in place. Only the
+// nodes on or to the right of the path of the first appended leaf are
+// rehashed; everything to its left is unchanged.
+func (smt *SparseMerkleTree) Append(chunks [][]byte, hashLeaf HashFuncFr) error {
+	leafHashes := make([]fr.Element, len(chunks))
+	for i, chunk := range chunks {
+		leafHashes[i] = hashLeaf(chunk)
+	}
+	return smt.AppendLeafHashes(leafHashes)
+}
+
+// AppendLeafHashes appends pre-computed leaf hashes after the current real
+// leaves and updates the tree in place.
+func (smt *SparseMerkleTree) AppendLeafHashes(leafHashes []fr.Element) error {
+	oldLeaves := smt.NumLeaves
+	newLeaves := oldLeaves + len(leafHashes)
+	if err := validateLeafCapacity(smt.Depth, newLeaves); err != nil {
+		return err
+	}
+	if len(leafHashes) == 0 {
+		return nil
+	}
+
+	smt.Levels[0] = append(smt.Levels[0][:oldLeaves], leafHashes...)
 
-	idx := leafIndex
+	// first is the lowest index at the current level whose hash may change.
+	first := oldLeaves
 	for lvl := 0; lvl < smt.Depth; lvl++ {
-		var sibIdx int
-		if idx%2 == 0 {
-			sibIdx = idx + 1
-			directions[lvl] = 0
-		} else {
-			sibIdx = idx - 1
-			directions[lvl] = 1
+		cur := smt.Levels[lvl]
+		first /= 2
+		size := levelSize(newLeaves, lvl+1)
+		next := smt.Levels[lvl+1]
+		if len(next) < size {
+			next = append(next, make([]fr.Element, size-len(next))...)
 		}
-
-		if sibIdx >= 0 && sibIdx < len(smt.Levels[lvl]) {
-			siblings[lvl] = smt.Levels[lvl][sibIdx]
-		} else {
-			siblings[lvl] = smt.ZeroHashes[lvl]
+		for p := first; p < size; p++ {
+			left := cur[2*p]
+			right := smt.ZeroHashes[lvl]
+			if 2*p+1 < len(cur) {
+				right = cur[2*p+1]
+			}
+			next[p] = HashNodesFr(left, right)
 		}
+		smt.Levels[lvl+1] = next
+	}
 
-		idx /= 2
+	smt.NumLeaves = newLeaves
+	smt.Root = smt.Levels[smt.Depth][0]
+	return nil
+}
+
+// UpdateLeaf replaces the hash of real leaf leafIndex and rehashes its path
+// to the root.
+func (smt *SparseMerkleTree) UpdateLeaf(leafIndex int, leafHash fr.Element) error {
+	return smt.UpdateLeaves(map[int]fr.Element{leafIndex: leafHash})
+}
+
+// UpdateLeaves replaces the hashes of the given real leaves and rehashes only
+// the nodes on their paths (see StoredSMT.UpdateLeaves). Indices must be in
+// [0, NumLeaves); use AppendLeafHashes to add leaves. On error the tree is
+// left unchanged.
+func (smt *SparseMerkleTree) UpdateLeaves(updates map[int]fr.Element) error {
+	if err := smt.Stored().UpdateLeaves(updates); err != nil {
+		return err
 	}
+	if len(updates) > 0 {
+		smt.Root = smt.Levels[smt.Depth][0]
+	}
+	return nil
+}
 
-	return siblings, directions
+// GetProof returns a fixed-size Merkle proof for the leaf at the given index.
+func (smt *SparseMerkleTree) GetProof(leafIndex int) ([]fr.Element, []int) {
+	return smt.Stored().GetProof(leafIndex)
 }
 
 // GetLeafHash returns the hash at the given leaf index, using the zero leaf
 // hash for positions beyond the real leaves.
 func (smt *SparseMerkleTree) GetLeafHash(leafIndex int) fr.Element {
-	if leafIndex >= 0 && leafIndex < len(smt.Levels[0]) {
-		return smt.Levels[0][leafIndex]
+	return smt.Stored().GetLeafHash(leafIndex)
+}
+
+// Info returns the root and shape of the tree.
+func (smt *SparseMerkleTree) Info() TreeInfo {
+	return TreeInfo{Root: smt.Root, Depth: smt.Depth, NumLeaves: smt.NumLeaves}
+}
+
+// Proofs implements ProofSource by looking each proof up with GetProof and
+// GetLeafHash; chunks and hashLeaf are not used.
+func (smt *SparseMerkleTree) Proofs(ctx context.Context, leafIndices []int, _ ChunkStore, _ HashFuncFr) ([]*RebuildProofResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
-	return smt.ZeroHashes[0]
+	results := make([]*RebuildProofResult, len(leafIndices))
+	for k, leafIndex := range leafIndices {
+		siblings, directions := smt.GetProof(leafIndex)
+		results[k] = &RebuildProofResult{
+			Siblings:   siblings,
+			Directions: directions,
+			LeafHash:   smt.GetLeafHash(leafIndex),
+		}
+	}
+	return results, nil
 }
 
 // RootBigInt returns the root hash as *big.Int for callers that need it
@@ -600,6 +417,8 @@ func (smt *SparseMerkleTree) RootBigInt() *big.Int {
 //       uint32(index) | [32]byte(hash as big-endian fr.Element)
 //
 // Zero hashes are NOT stored — they are recomputed from zeroLeafHash on load.
+// This legacy format has no header or checksum; new files should use
+// SaveVersioned (see format.go).
 
 // Save writes the sparse Merkle tree to w in a deterministic binary format.
 func (smt *SparseMerkleTree) Save(w io.Writer) error {
@@ -632,6 +451,10 @@ func (smt *SparseMerkleTree) Save(w io.Writer) error {
 
 // LoadSparseMerkleTree reads a sparse Merkle tree from r that was written by
 // Save. The zeroLeafHash is needed to recompute the zero-subtree hash chain.
+// Every level must list each of its indices exactly once, and every inner
+// node must be the hash of its children, so the loaded root is the root of
+// the loaded leaves. Entries are read in bounded batches, so a forged count
+// cannot force an allocation larger than the data actually present.
 func LoadSparseMerkleTree(r io.Reader, zeroLeafHash fr.Element) (*SparseMerkleTree, error) {
 	var depth, numLeaves uint32
 	if err := binary.Read(r, binary.BigEndian, &depth); err != nil {
@@ -640,6 +463,13 @@ func LoadSparseMerkleTree(r io.Reader, zeroLeafHash fr.Element) (*SparseMerkleTr
 	if err := binary.Read(r, binary.BigEndian, &numLeaves); err != nil {
 		return nil, fmt.Errorf("read numLeaves: %w", err)
 	}
+	maxLeaves, err := maxLeavesForDepth(int(depth))
+	if err != nil || depth > 32 {
+		return nil, fmt.Errorf("invalid tree depth %d", depth)
+	}
+	if uint64(numLeaves) > uint64(maxLeaves) {
+		return nil, fmt.Errorf("tree depth %d supports at most %d leaves, got %d", depth, maxLeaves, numLeaves)
+	}
 
 	zeroHashes := PrecomputeZeroHashes(int(depth), zeroLeafHash)
 
@@ -649,22 +479,25 @@ func LoadSparseMerkleTree(r io.Reader, zeroLeafHash fr.Element) (*SparseMerkleTr
 		if err := binary.Read(r, binary.BigEndian, &count); err != nil {
 			return nil, fmt.Errorf("read level %d count: %w", lvl, err)
 		}
+		if want := levelSize(int(numLeaves), lvl); int(count) != want {
+			return nil, fmt.Errorf("level %d: %d entries, want %d", lvl, count, want)
+		}
+		entries, err := readLevelEntries(r, lvl, int(count))
+		if err != nil {
+			return nil, err
+		}
+		levels[lvl] = entries
+	}
 
-		entries := make([]fr.Element, count)
-		var hashBuf [32]byte
-		for j := 0; j < int(count); j++ {
-			var idx uint32
-			if err := binary.Read(r, binary.BigEndian, &idx); err != nil {
-				return nil, fmt.Errorf("read level %d index: %w", lvl, err)
-			}
-			if _, err := io.ReadFull(r, hashBuf[:]); err != nil {
-				return nil, fmt.Errorf("read level %d hash: %w", lvl, err)
-			}
-			if int(idx) < len(entries) {
-				entries[idx].SetBytes(hashBuf[:])
+	// Recompute every inner level from the one below and compare.
+	for lvl := 0; lvl < int(depth); lvl++ {
+		parents := make([]fr.Element, len(levels[lvl+1]))
+		hashLevel(levels[lvl], parents, zeroHashes[lvl])
+		for j := range parents {
+			if parents[j] != levels[lvl+1][j] {
+				return nil, fmt.Errorf("level %d: node %d does not match its children", lvl+1, j)
 			}
 		}
-		levels[lvl] = entries
 	}
 
 	var root fr.Element
@@ -683,6 +516,50 @@ func LoadSparseMerkleTree(r io.Reader, zeroLeafHash fr.Element) (*SparseMerkleTr
 	}, nil
 }
 
+// legacyLoadBatch bounds how many level entries LoadSparseMerkleTree
+// allocates before reading them.
+const legacyLoadBatch = 1 << 16
+
+// readLevelEntries reads count (index, hash) entries of one level in any
+// index order. Each index in [0, count) must appear exactly once.
+func readLevelEntries(r io.Reader, lvl, count int) ([]fr.Element, error) {
+	type entry struct {
+		idx  uint32
+		hash fr.Element
+	}
+	var read []entry
+	var rec [4 + 32]byte
+	for len(read) < count {
+		n := min(legacyLoadBatch, count-len(read))
+		read = append(read, make([]entry, n)...)
+		for j := len(read) - n; j < len(read); j++ {
+			if _, err := io.ReadFull(r, rec[:]); err != nil {
+				return nil, fmt.Errorf("read level %d entry %d: %w", lvl, j, err)
+			}
+			read[j].idx = binary.BigEndian.Uint32(rec[:4])
+			if read[j].idx >= uint32(count) {
+				return nil, fmt.Errorf("level %d: index %d out of range [0, %d)", lvl, read[j].idx, count)
+			}
+			if err := read[j].hash.SetBytesCanonical(rec[4:]); err != nil {
+				return nil, fmt.Errorf("level %d entry %d: %w", lvl, j, err)
+			}
+		}
+	}
+
+	// All count entries are now backed by data, so allocating the level is
+	// proportional to the input.
+	entries := make([]fr.Element, count)
+	seen := make([]bool, count)
+	for _, e := range read {
+		if seen[e.idx] {
+			return nil, fmt.Errorf("level %d: duplicate index %d", lvl, e.idx)
+		}
+		seen[e.idx] = true
+		entries[e.idx] = e.hash
+	}
+	return entries, nil
+}
+
 // sortInts sorts a slice of ints in ascending order (insertion sort,
 // suitable for the typically small per-level entry counts).
 func sortInts(s []int) {
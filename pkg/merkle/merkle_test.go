@@ -2,9 +2,19 @@ package merkle
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"math/big"
+	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"testing/iotest"
+	"time"
 
 	"github.com/MuriData/muri-zkproof/pkg/crypto"
 	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
@@ -180,6 +190,164 @@ func TestSMTSaveLoadEmpty(t *testing.T) {
 	}
 }
 
+// TestSMTSaveLoadVersioned verifies SaveVersioned/LoadVersionedSMT
+// round-trips trees, including an empty one, and the chunk size.
+func TestSMTSaveLoadVersioned(t *testing.T) {
+	zeroLeaf := testZeroLeafHash()
+	for _, n := range []int{0, 1, 5, 100} {
+		t.Run(fmtChunks(n), func(t *testing.T) {
+			leafHashes := make([]fr.Element, n)
+			for i := range leafHashes {
+				leafHashes[i].SetInt64(int64(i + 1))
+			}
+			original, err := BuildSMTFromLeafHashes(leafHashes, testMaxDepth, zeroLeaf)
+			if err != nil {
+				t.Fatalf("build SMT: %v", err)
+			}
+
+			var buf bytes.Buffer
+			if err := original.SaveVersioned(&buf, testChunkSize); err != nil {
+				t.Fatalf("save: %v", err)
+			}
+			if want := smtFileHeaderSize + (n+2)*32; buf.Len() != want {
+				t.Fatalf("serialized size %d, want %d", buf.Len(), want)
+			}
+
+			loaded, chunkSize, err := LoadVersionedSMT(&buf, zeroLeaf)
+			if err != nil {
+				t.Fatalf("load: %v", err)
+			}
+			if chunkSize != testChunkSize {
+				t.Fatalf("chunk size: got %d, want %d", chunkSize, testChunkSize)
+			}
+			if loaded.Root != original.Root || loaded.Depth != original.Depth || loaded.NumLeaves != n {
+				t.Fatal("root or shape mismatch")
+			}
+			for lvl := 0; lvl <= testMaxDepth; lvl++ {
+				for i := range original.Levels[lvl] {
+					if loaded.Levels[lvl][i] != original.Levels[lvl][i] {
+						t.Fatalf("level %d index %d: hash mismatch", lvl, i)
+					}
+				}
+			}
+		})
+	}
+}
+
+// TestLoadVersionedSMTRejectsCorruption checks that truncated, tampered,
+// foreign and mis-rooted files are rejected.
+func TestLoadVersionedSMTRejectsCorruption(t *testing.T) {
+	zeroLeaf := testZeroLeafHash()
+	leafHashes := make([]fr.Element, 6)
+	for i := range leafHashes {
+		leafHashes[i].SetInt64(int64(i + 1))
+	}
+	smt, err := BuildSMTFromLeafHashes(leafHashes, 8, zeroLeaf)
+	if err != nil {
+		t.Fatalf("build SMT: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := smt.SaveVersioned(&buf, testChunkSize); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	good := buf.Bytes()
+
+	mutate := func(f func(b []byte) []byte) []byte {
+		return f(append([]byte(nil), good...))
+	}
+	// resign recomputes the trailing digest so only the content check fires.
+	resign := func(b []byte) []byte {
+		sum := sha256.Sum256(b[:len(b)-32])
+		copy(b[len(b)-32:], sum[:])
+		return b
+	}
+
+	var otherZero fr.Element
+	otherZero.SetInt64(12345)
+
+	cases := []struct {
+		name     string
+		data     []byte
+		zeroLeaf fr.Element
+	}{
+		{"truncated", good[:len(good)-1], zeroLeaf},
+		{"header only", good[:smtFileHeaderSize], zeroLeaf},
+		{"bad magic", mutate(func(b []byte) []byte { b[0] ^= 0xff; return b }), zeroLeaf},
+		{"bad version", mutate(func(b []byte) []byte { b[7] = 9; return resign(b) }), zeroLeaf},
+		{"flipped leaf", mutate(func(b []byte) []byte { b[smtFileHeaderSize+5] ^= 1; return b }), zeroLeaf},
+		{"flipped digest", mutate(func(b []byte) []byte { b[len(b)-1] ^= 1; return b }), zeroLeaf},
+		{"resigned leaf", mutate(func(b []byte) []byte { b[smtFileHeaderSize+31] ^= 1; return resign(b) }), zeroLeaf},
+		{"huge leaf count", mutate(func(b []byte) []byte { b[12] = 0xff; return resign(b) }), zeroLeaf},
+		{"wrong zero leaf", good, otherZero},
+	}
+	for _, tc := range cases {
+		if _, _, err := LoadVersionedSMT(bytes.NewReader(tc.data), tc.zeroLeaf); err == nil {
+			t.Fatalf("%s: expected error", tc.name)
+		}
+	}
+
+	if err := smt.SaveVersioned(io.Discard, 0); err == nil {
+		t.Fatal("expected error for zero chunk size")
+	}
+}
+
+// TestLoadSparseMerkleTreeRejectsBadIndex checks that the legacy loader
+// rejects entry indices outside their level instead of skipping them.
+func TestLoadSparseMerkleTreeRejectsBadIndex(t *testing.T) {
+	var a, b fr.Element
+	a.SetInt64(1)
+	b.SetInt64(2)
+	smt, err := BuildSMTFromLeafHashes([]fr.Element{a, b}, 2, testZeroLeafHash())
+	if err != nil {
+		t.Fatalf("build SMT: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := smt.Save(&buf); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	data := buf.Bytes()
+	// Second leaf entry: depth | numLeaves | count | (index | hash) | index.
+	data[4+4+4+36+3] = 7
+	if _, err := LoadSparseMerkleTree(bytes.NewReader(data), testZeroLeafHash()); err == nil {
+		t.Fatal("expected out-of-range index error")
+	}
+}
+
+// TestLoadSparseMerkleTreeRejectsForged checks that the legacy loader rejects
+// forged counts without allocating for them, duplicate indices, and inner
+// nodes that do not match the leaves.
+func TestLoadSparseMerkleTreeRejectsForged(t *testing.T) {
+	// depth 32, 2^32-1 leaves, 2^32-1 level-0 entries and no data.
+	forged := []byte{0, 0, 0, 32, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	if _, err := LoadSparseMerkleTree(bytes.NewReader(forged), testZeroLeafHash()); err == nil {
+		t.Fatal("expected error for forged counts")
+	}
+
+	var a, b fr.Element
+	a.SetInt64(1)
+	b.SetInt64(2)
+	smt, err := BuildSMTFromLeafHashes([]fr.Element{a, b}, 2, testZeroLeafHash())
+	if err != nil {
+		t.Fatalf("build SMT: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := smt.Save(&buf); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	// Leaf entries start after depth | numLeaves | count; each is index | hash.
+	duplicate := bytes.Clone(buf.Bytes())
+	duplicate[12+36+3] = 0
+	if _, err := LoadSparseMerkleTree(bytes.NewReader(duplicate), testZeroLeafHash()); err == nil {
+		t.Fatal("expected duplicate index error")
+	}
+	tampered := bytes.Clone(buf.Bytes())
+	tampered[12+4+31] ^= 2
+	if _, err := LoadSparseMerkleTree(bytes.NewReader(tampered), testZeroLeafHash()); err == nil {
+		t.Fatal("expected inner node mismatch error")
+	}
+}
+
 func TestGenerateSparseMerkleTreeRejectsTooManyLeaves(t *testing.T) {
 	chunks := [][]byte{
 		make([]byte, testChunkSize),
@@ -212,6 +380,169 @@ func TestBuildSMTFromLeafHashesRejectsTooManyLeaves(t *testing.T) {
 	}
 }
 
+// TestSMTAppend checks that appending leaves in place yields the same tree as
+// building it from scratch, including appends to an empty tree and up to
+// full capacity.
+func TestSMTAppend(t *testing.T) {
+	const depth = 4
+	leafHashes := make([]fr.Element, 1<<depth)
+	for i := range leafHashes {
+		leafHashes[i].SetInt64(int64(i + 1))
+	}
+	zeroLeaf := testZeroLeafHash()
+
+	splits := [][2]int{{0, 1}, {1, 2}, {1, 5}, {3, 4}, {4, 9}, {7, 16}, {8, 8}, {15, 16}}
+	for _, s := range splits {
+		oldN, newN := s[0], s[1]
+		smt, err := BuildSMTFromLeafHashes(leafHashes[:oldN], depth, zeroLeaf)
+		if err != nil {
+			t.Fatalf("build SMT: %v", err)
+		}
+		if err := smt.AppendLeafHashes(leafHashes[oldN:newN]); err != nil {
+			t.Fatalf("append %d..%d: %v", oldN, newN, err)
+		}
+
+		want, err := BuildSMTFromLeafHashes(leafHashes[:newN], depth, zeroLeaf)
+		if err != nil {
+			t.Fatalf("build SMT: %v", err)
+		}
+		if smt.Root != want.Root || smt.NumLeaves != newN {
+			t.Fatalf("append %d..%d: root or numLeaves mismatch", oldN, newN)
+		}
+		for lvl := 0; lvl <= depth; lvl++ {
+			if len(smt.Levels[lvl]) != len(want.Levels[lvl]) {
+				t.Fatalf("append %d..%d: level %d has %d entries, want %d", oldN, newN, lvl, len(smt.Levels[lvl]), len(want.Levels[lvl]))
+			}
+			for i := range want.Levels[lvl] {
+				if smt.Levels[lvl][i] != want.Levels[lvl][i] {
+					t.Fatalf("append %d..%d: level %d entry %d mismatch", oldN, newN, lvl, i)
+				}
+			}
+		}
+	}
+
+	// Append hashes chunks with the supplied leaf hash function.
+	data := make([]byte, 3*testChunkSize)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	chunks := SplitIntoChunks(data, testChunkSize)
+	smt, err := GenerateSparseMerkleTree(chunks[:1], testMaxDepth, testHashChunk, zeroLeaf)
+	if err != nil {
+		t.Fatalf("build SMT: %v", err)
+	}
+	if err := smt.Append(chunks[1:], testHashChunk); err != nil {
+		t.Fatalf("append chunks: %v", err)
+	}
+	want, err := GenerateSparseMerkleTree(chunks, testMaxDepth, testHashChunk, zeroLeaf)
+	if err != nil {
+		t.Fatalf("build SMT: %v", err)
+	}
+	if smt.Root != want.Root {
+		t.Fatal("appended chunk root mismatch")
+	}
+}
+
+func TestSMTAppendRejectsTooManyLeaves(t *testing.T) {
+	var a, b, c fr.Element
+	a.SetInt64(1)
+	b.SetInt64(2)
+	c.SetInt64(3)
+
+	smt, err := BuildSMTFromLeafHashes([]fr.Element{a}, 1, testZeroLeafHash())
+	if err != nil {
+		t.Fatalf("build SMT: %v", err)
+	}
+	root := smt.Root
+	err = smt.AppendLeafHashes([]fr.Element{b, c})
+	if err == nil {
+		t.Fatal("expected oversized tree error")
+	}
+	if !strings.Contains(err.Error(), "supports at most 2 leaves") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if smt.Root != root || smt.NumLeaves != 1 {
+		t.Fatal("failed append modified the tree")
+	}
+}
+
+// TestSMTUpdateLeaves checks that in-place leaf updates yield the same tree
+// as a rebuild, for single updates and for a batch large enough to take the
+// parallel path.
+func TestSMTUpdateLeaves(t *testing.T) {
+	const depth = 12
+	const numLeaves = 3000
+	leafHashes := make([]fr.Element, numLeaves)
+	for i := range leafHashes {
+		leafHashes[i].SetInt64(int64(i + 1))
+	}
+	zeroLeaf := testZeroLeafHash()
+
+	smt, err := BuildSMTFromLeafHashes(leafHashes, depth, zeroLeaf)
+	if err != nil {
+		t.Fatalf("build SMT: %v", err)
+	}
+
+	checkAgainstRebuild := func(name string) {
+		t.Helper()
+		want, err := BuildSMTFromLeafHashes(leafHashes, depth, zeroLeaf)
+		if err != nil {
+			t.Fatalf("build SMT: %v", err)
+		}
+		if smt.Root != want.Root || smt.NumLeaves != numLeaves {
+			t.Fatalf("%s: root or numLeaves mismatch", name)
+		}
+		for lvl := 0; lvl <= depth; lvl++ {
+			for i := range want.Levels[lvl] {
+				if smt.Levels[lvl][i] != want.Levels[lvl][i] {
+					t.Fatalf("%s: level %d entry %d mismatch", name, lvl, i)
+				}
+			}
+		}
+	}
+
+	// Single updates at the edges and in the middle.
+	for _, idx := range []int{0, 1, 1499, numLeaves - 1} {
+		leafHashes[idx].SetInt64(int64(100000 + idx))
+		if err := smt.UpdateLeaf(idx, leafHashes[idx]); err != nil {
+			t.Fatalf("update leaf %d: %v", idx, err)
+		}
+		checkAgainstRebuild("single " + itoa(idx))
+	}
+
+	// Batch update of every other leaf (more dirty parents than
+	// parallelBuildThreshold at the lower levels).
+	updates := make(map[int]fr.Element)
+	for idx := 0; idx < numLeaves; idx += 2 {
+		leafHashes[idx].SetInt64(int64(200000 + idx))
+		updates[idx] = leafHashes[idx]
+	}
+	if err := smt.UpdateLeaves(updates); err != nil {
+		t.Fatalf("update leaves: %v", err)
+	}
+	checkAgainstRebuild("batch")
+}
+
+func TestSMTUpdateLeavesRejectsOutOfRange(t *testing.T) {
+	var a, b fr.Element
+	a.SetInt64(1)
+	b.SetInt64(2)
+
+	smt, err := BuildSMTFromLeafHashes([]fr.Element{a, b}, 4, testZeroLeafHash())
+	if err != nil {
+		t.Fatalf("build SMT: %v", err)
+	}
+	root := smt.Root
+	for _, idx := range []int{-1, 2, 16} {
+		if err := smt.UpdateLeaves(map[int]fr.Element{0: b, idx: a}); err == nil {
+			t.Fatalf("expected error for leaf index %d", idx)
+		}
+	}
+	if smt.Root != root || smt.GetLeafHash(0) != a {
+		t.Fatal("failed update modified the tree")
+	}
+}
+
 func BenchmarkSMTConstruction(b *testing.B) {
 	// 8 chunks ≈ 128 KB (same as the standard PoI test).
 	data := make([]byte, 8*testChunkSize)
@@ -260,6 +591,217 @@ func BenchmarkSMTSaveLoad(b *testing.B) {
 	})
 }
 
+// TestGenerateSparseMerkleTreeFromReader checks that streaming construction
+// matches GenerateSparseMerkleTree for empty, partial-chunk and
+// chunk-aligned inputs delivered in short reads.
+func TestGenerateSparseMerkleTreeFromReader(t *testing.T) {
+	zeroLeaf := testZeroLeafHash()
+	for _, size := range []int{0, 1, testChunkSize - 1, testChunkSize, 3*testChunkSize + 100, 40 * testChunkSize} {
+		data := make([]byte, size)
+		if _, err := rand.Read(data); err != nil {
+			t.Fatal(err)
+		}
+		want, err := GenerateSparseMerkleTree(SplitIntoChunks(data, testChunkSize), testMaxDepth, testHashChunk, zeroLeaf)
+		if err != nil {
+			t.Fatalf("build SMT: %v", err)
+		}
+
+		got, err := GenerateSparseMerkleTreeFromReader(iotest.HalfReader(bytes.NewReader(data)), testChunkSize, testMaxDepth, testHashChunk, zeroLeaf)
+		if err != nil {
+			t.Fatalf("size %d: build SMT from reader: %v", size, err)
+		}
+		if got.Root != want.Root || got.NumLeaves != want.NumLeaves {
+			t.Fatalf("size %d: root or numLeaves mismatch", size)
+		}
+
+		// The checkpointed builder serializes identically to SaveCheckpointed.
+		var wantBuf, gotBuf bytes.Buffer
+		if err := want.SaveCheckpointed(&wantBuf, SchemeBalanced); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+		csmt, err := GenerateCheckpointedSMTFromReader(bytes.NewReader(data), testChunkSize, testMaxDepth, testHashChunk, zeroLeaf, SchemeBalanced)
+		if err != nil {
+			t.Fatalf("size %d: build checkpointed SMT from reader: %v", size, err)
+		}
+		if csmt.Root != want.Root {
+			t.Fatalf("size %d: checkpointed root mismatch", size)
+		}
+		if err := csmt.Save(&gotBuf); err != nil {
+			t.Fatalf("save checkpointed: %v", err)
+		}
+		if !bytes.Equal(gotBuf.Bytes(), wantBuf.Bytes()) {
+			t.Fatalf("size %d: checkpointed serialization mismatch", size)
+		}
+	}
+}
+
+func TestGenerateSparseMerkleTreeFromReaderErrors(t *testing.T) {
+	zeroLeaf := testZeroLeafHash()
+
+	data := make([]byte, 3*testChunkSize)
+	_, err := GenerateSparseMerkleTreeFromReader(bytes.NewReader(data), testChunkSize, 1, testHashChunk, zeroLeaf)
+	if err == nil || !strings.Contains(err.Error(), "supports at most 2 leaves") {
+		t.Fatalf("expected oversized tree error, got %v", err)
+	}
+
+	readErr := errors.New("disk failure")
+	r := io.MultiReader(bytes.NewReader(data[:testChunkSize+5]), iotest.ErrReader(readErr))
+	_, err = GenerateSparseMerkleTreeFromReader(r, testChunkSize, testMaxDepth, testHashChunk, zeroLeaf)
+	if !errors.Is(err, readErr) {
+		t.Fatalf("expected read error, got %v", err)
+	}
+
+	_, err = GenerateCheckpointedSMTFromReader(bytes.NewReader(data), testChunkSize, testMaxDepth, testHashChunk, zeroLeaf, CheckpointScheme{Levels: []int{4, 9}})
+	if err == nil {
+		t.Fatal("expected invalid scheme error")
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Multi-proof tests
+// ---------------------------------------------------------------------------
+
+// TestMultiProof verifies multi-proofs for overlapping, adjacent, duplicate
+// and padding indices, and checks they carry fewer siblings than the
+// individual proofs.
+func TestMultiProof(t *testing.T) {
+	leafHashes := make([]fr.Element, 100)
+	for i := range leafHashes {
+		leafHashes[i].SetInt64(int64(i + 1))
+	}
+	smt, err := BuildSMTFromLeafHashes(leafHashes, testMaxDepth, testZeroLeafHash())
+	if err != nil {
+		t.Fatalf("build SMT: %v", err)
+	}
+
+	cases := [][]int{
+		{0},
+		{99},
+		{0, 1},
+		{5, 3, 7, 3},
+		{0, 31, 32, 63, 64, 99},
+		{10, 11, 12, 13, 14, 15, 16, 17},
+		{2, 98, 150, 1 << 19},
+	}
+	for _, indices := range cases {
+		proof, err := smt.GetMultiProof(indices)
+		if err != nil {
+			t.Fatalf("%v: get multi-proof: %v", indices, err)
+		}
+		leaves := make([]fr.Element, len(proof.Indices))
+		for i, idx := range proof.Indices {
+			leaves[i] = smt.GetLeafHash(idx)
+		}
+		if !VerifyMultiProof(smt.Root, smt.Depth, proof, leaves) {
+			t.Fatalf("%v: valid multi-proof rejected", indices)
+		}
+		if len(proof.Siblings) > len(proof.Indices)*testMaxDepth {
+			t.Fatalf("%v: %d siblings exceed individual proofs", indices, len(proof.Siblings))
+		}
+
+		// Round-trip through the binary format.
+		var buf bytes.Buffer
+		if err := proof.Save(&buf); err != nil {
+			t.Fatalf("%v: save: %v", indices, err)
+		}
+		loaded, err := LoadMultiProof(&buf)
+		if err != nil {
+			t.Fatalf("%v: load: %v", indices, err)
+		}
+		if !VerifyMultiProof(smt.Root, smt.Depth, loaded, leaves) {
+			t.Fatalf("%v: loaded multi-proof rejected", indices)
+		}
+
+		// Any tampered leaf or sibling is rejected.
+		var one fr.Element
+		one.SetOne()
+		leaves[len(leaves)-1].Add(&leaves[len(leaves)-1], &one)
+		if VerifyMultiProof(smt.Root, smt.Depth, proof, leaves) {
+			t.Fatalf("%v: tampered leaf accepted", indices)
+		}
+		leaves[len(leaves)-1].Sub(&leaves[len(leaves)-1], &one)
+		proof.Siblings[0].Add(&proof.Siblings[0], &one)
+		if VerifyMultiProof(smt.Root, smt.Depth, proof, leaves) {
+			t.Fatalf("%v: tampered sibling accepted", indices)
+		}
+	}
+
+	// Eight adjacent leaves share all but three levels of their paths.
+	proof, err := smt.GetMultiProof([]int{16, 17, 18, 19, 20, 21, 22, 23})
+	if err != nil {
+		t.Fatalf("get multi-proof: %v", err)
+	}
+	if want := testMaxDepth - 3; len(proof.Siblings) != want {
+		t.Fatalf("got %d siblings, want %d", len(proof.Siblings), want)
+	}
+}
+
+func TestMultiProofRejectsMalformed(t *testing.T) {
+	var a fr.Element
+	a.SetInt64(1)
+	smt, err := BuildSMTFromLeafHashes([]fr.Element{a, a, a}, 4, testZeroLeafHash())
+	if err != nil {
+		t.Fatalf("build SMT: %v", err)
+	}
+
+	for _, indices := range [][]int{nil, {-1}, {16}} {
+		if _, err := smt.GetMultiProof(indices); err == nil {
+			t.Fatalf("%v: expected error", indices)
+		}
+	}
+
+	proof, err := smt.GetMultiProof([]int{0, 2})
+	if err != nil {
+		t.Fatalf("get multi-proof: %v", err)
+	}
+	leaves := []fr.Element{a, a}
+	if VerifyMultiProof(smt.Root, smt.Depth, proof, leaves[:1]) {
+		t.Fatal("accepted wrong leaf count")
+	}
+	unsorted := &MultiProof{Depth: proof.Depth, Indices: []int{2, 0}, Siblings: proof.Siblings}
+	if VerifyMultiProof(smt.Root, smt.Depth, unsorted, leaves) {
+		t.Fatal("accepted non-canonical indices")
+	}
+	extra := &MultiProof{Depth: proof.Depth, Indices: proof.Indices, Siblings: append(proof.Siblings, a)}
+	if VerifyMultiProof(smt.Root, smt.Depth, extra, leaves) {
+		t.Fatal("accepted unused siblings")
+	}
+
+	// A proof truncated below the root opens the inner node it reaches;
+	// it must not pass for a tree of the expected depth.
+	truncated := &MultiProof{Depth: proof.Depth - 1, Indices: proof.Indices, Siblings: proof.Siblings[:len(proof.Siblings)-1]}
+	inner := smt.Levels[proof.Depth-1][0]
+	if !VerifyMultiProof(inner, proof.Depth-1, truncated, leaves) {
+		t.Fatal("truncated proof does not reach the inner node")
+	}
+	if VerifyMultiProof(inner, smt.Depth, truncated, leaves) {
+		t.Fatal("accepted a proof of another depth")
+	}
+	if VerifyMultiProof(smt.Root, smt.Depth+1, proof, leaves) {
+		t.Fatal("accepted a proof shallower than the expected depth")
+	}
+
+	var buf bytes.Buffer
+	if err := proof.Save(&buf); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if _, err := LoadMultiProof(bytes.NewReader(buf.Bytes()[:buf.Len()-1])); err == nil {
+		t.Fatal("expected error for truncated multi-proof")
+	}
+
+	// Forged counts with no data behind them must fail without allocating
+	// for the declared counts: depth 32 with 2^32-1 indices, and one index
+	// followed by the largest sibling count depth 32 allows.
+	forged := []byte{0, 0, 0, 32, 0xff, 0xff, 0xff, 0xff}
+	if _, err := LoadMultiProof(bytes.NewReader(forged)); err == nil {
+		t.Fatal("expected error for forged index count")
+	}
+	forged = []byte{0, 0, 0, 32, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 32}
+	if _, err := LoadMultiProof(bytes.NewReader(forged)); err == nil {
+		t.Fatal("expected error for forged sibling count")
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Checkpointed SMT tests
 // ---------------------------------------------------------------------------
@@ -338,6 +880,61 @@ func TestCheckpointedRebuildProof(t *testing.T) {
 	}
 }
 
+// TestCheckpointedDeepPresets verifies the depth-24 and depth-28 presets
+// rebuild proofs identical to the full tree.
+func TestCheckpointedDeepPresets(t *testing.T) {
+	data := make([]byte, 5*testChunkSize)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	chunks := SplitIntoChunks(data, testChunkSize)
+	zeroLeaf := testZeroLeafHash()
+	readChunk := func(i int) []byte { return chunks[i] }
+
+	for _, depth := range []int{24, 28} {
+		fullSMT, err := GenerateSparseMerkleTree(chunks, depth, testHashChunk, zeroLeaf)
+		if err != nil {
+			t.Fatalf("build SMT: %v", err)
+		}
+		for _, name := range []string{"compact", "balanced", "fast"} {
+			t.Run(name+"/depth_"+itoa(depth), func(t *testing.T) {
+				scheme, err := PresetScheme(name, depth)
+				if err != nil {
+					t.Fatalf("preset: %v", err)
+				}
+				var buf bytes.Buffer
+				if err := fullSMT.SaveCheckpointed(&buf, scheme); err != nil {
+					t.Fatalf("save checkpointed: %v", err)
+				}
+				csmt, err := LoadCheckpointedSMT(bytes.NewReader(buf.Bytes()), zeroLeaf)
+				if err != nil {
+					t.Fatalf("load checkpointed: %v", err)
+				}
+				if csmt.Root != fullSMT.Root {
+					t.Fatal("root mismatch")
+				}
+
+				for _, leafIdx := range []int{0, 4, 9} {
+					fullSib, _ := fullSMT.GetProof(leafIdx)
+					result := csmt.RebuildProof(leafIdx, readChunk, testHashChunk)
+					for lvl := 0; lvl < depth; lvl++ {
+						if fullSib[lvl] != result.Siblings[lvl] {
+							t.Fatalf("leaf %d: sibling mismatch at level %d", leafIdx, lvl)
+						}
+					}
+					if result.LeafHash != fullSMT.GetLeafHash(leafIdx) {
+						t.Fatalf("leaf %d: leaf hash mismatch", leafIdx)
+					}
+				}
+			})
+		}
+	}
+
+	if _, err := PresetScheme("balanced", 22); err == nil {
+		t.Fatal("expected error for depth without presets")
+	}
+}
+
 // TestCheckpointedSaveLoad verifies serialization round-trip fidelity.
 func TestCheckpointedSaveLoad(t *testing.T) {
 	data := make([]byte, 8*testChunkSize)
@@ -518,6 +1115,420 @@ func TestCheckpointedEmpty(t *testing.T) {
 	}
 }
 
+// TestCheckpointedRebuildProofs checks batched rebuilds against the full
+// tree, including duplicate, shared-subtree and padding indices, and that
+// each chunk is read at most once per batch.
+func TestCheckpointedRebuildProofs(t *testing.T) {
+	data := make([]byte, 40*testChunkSize)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	chunks := SplitIntoChunks(data, testChunkSize)
+	zeroLeaf := testZeroLeafHash()
+
+	fullSMT, err := GenerateSparseMerkleTree(chunks, testMaxDepth, testHashChunk, zeroLeaf)
+	if err != nil {
+		t.Fatalf("build SMT: %v", err)
+	}
+	indices := []int{3, 5, 3, 17, 39, 40, 1 << 19, 0, 16}
+
+	for _, scheme := range []CheckpointScheme{SchemeCompact, SchemeBalanced, {Levels: []int{0, 8, 20}}} {
+		var buf bytes.Buffer
+		if err := fullSMT.SaveCheckpointed(&buf, scheme); err != nil {
+			t.Fatalf("save checkpointed: %v", err)
+		}
+		csmt, err := LoadCheckpointedSMT(&buf, zeroLeaf)
+		if err != nil {
+			t.Fatalf("load checkpointed: %v", err)
+		}
+
+		var mu sync.Mutex
+		reads := make(map[int]int)
+		readChunk := func(i int) []byte {
+			mu.Lock()
+			reads[i]++
+			mu.Unlock()
+			return chunks[i]
+		}
+
+		results := csmt.RebuildProofs(indices, readChunk, testHashChunk)
+		if len(results) != len(indices) {
+			t.Fatalf("scheme %v: %d results for %d indices", scheme.Levels, len(results), len(indices))
+		}
+		for k, leafIdx := range indices {
+			fullSib, fullDir := fullSMT.GetProof(leafIdx)
+			for lvl := 0; lvl < testMaxDepth; lvl++ {
+				if results[k].Siblings[lvl] != fullSib[lvl] || results[k].Directions[lvl] != fullDir[lvl] {
+					t.Fatalf("scheme %v leaf %d: proof mismatch at level %d", scheme.Levels, leafIdx, lvl)
+				}
+			}
+			if results[k].LeafHash != fullSMT.GetLeafHash(leafIdx) {
+				t.Fatalf("scheme %v leaf %d: leaf hash mismatch", scheme.Levels, leafIdx)
+			}
+		}
+		for i, n := range reads {
+			if n != 1 {
+				t.Fatalf("scheme %v: chunk %d read %d times", scheme.Levels, i, n)
+			}
+		}
+	}
+
+	// A full tree serves the same proofs through ProofSource.
+	var source ProofSource = fullSMT
+	proofs, err := source.Proofs(context.Background(), indices, nil, nil)
+	if err != nil {
+		t.Fatalf("ProofSource proofs: %v", err)
+	}
+	for k, res := range proofs {
+		if res.LeafHash != fullSMT.GetLeafHash(indices[k]) {
+			t.Fatalf("ProofSource leaf %d: leaf hash mismatch", indices[k])
+		}
+	}
+}
+
+// TestCheckpointedRebuildProofContext checks that read errors and
+// cancellation are returned and stop the leaf-hashing workers.
+func TestCheckpointedRebuildProofContext(t *testing.T) {
+	data := make([]byte, 40*testChunkSize)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	chunks := SplitIntoChunks(data, testChunkSize)
+	zeroLeaf := testZeroLeafHash()
+
+	fullSMT, err := GenerateSparseMerkleTree(chunks, testMaxDepth, testHashChunk, zeroLeaf)
+	if err != nil {
+		t.Fatalf("build SMT: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := fullSMT.SaveCheckpointed(&buf, SchemeCompact); err != nil {
+		t.Fatalf("save checkpointed: %v", err)
+	}
+	csmt, err := LoadCheckpointedSMT(&buf, zeroLeaf)
+	if err != nil {
+		t.Fatalf("load checkpointed: %v", err)
+	}
+
+	var reads atomic.Int32
+	readChunk := func(i int) ([]byte, error) {
+		reads.Add(1)
+		return chunks[i], nil
+	}
+	res, err := csmt.RebuildProofContext(context.Background(), 17, readChunk, testHashChunk)
+	if err != nil {
+		t.Fatalf("rebuild: %v", err)
+	}
+	fullSib, _ := fullSMT.GetProof(17)
+	for lvl := range fullSib {
+		if res.Siblings[lvl] != fullSib[lvl] {
+			t.Fatalf("sibling mismatch at level %d", lvl)
+		}
+	}
+
+	errDisk := errors.New("disk failure")
+	failing := func(i int) ([]byte, error) {
+		if i == 7 {
+			return nil, errDisk
+		}
+		return chunks[i], nil
+	}
+	if _, err := csmt.RebuildProofsContext(context.Background(), []int{3, 30}, failing, testHashChunk); !errors.Is(err, errDisk) {
+		t.Fatalf("expected read error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	reads.Store(0)
+	if _, err := csmt.RebuildProofContext(ctx, 3, readChunk, testHashChunk); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation error, got %v", err)
+	}
+	if reads.Load() != 0 {
+		t.Fatalf("cancelled rebuild read %d chunks", reads.Load())
+	}
+
+	// Cancelling mid-rebuild stops each worker before its next chunk.
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	reads.Store(0)
+	cancelling := func(i int) ([]byte, error) {
+		reads.Add(1)
+		cancel()
+		return chunks[i], nil
+	}
+	if _, err := csmt.RebuildProofContext(ctx, 3, cancelling, testHashChunk); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation error, got %v", err)
+	}
+	if n := int(reads.Load()); n > runtime.NumCPU() {
+		t.Fatalf("cancelled rebuild read %d chunks", n)
+	}
+}
+
+// TestCheckpointedVerify checks that Verify and RebuildProofChecked accept an
+// intact checkpointed SMT and name the corrupt segment after a stored entry
+// or a chunk is altered, and that RebuildProofCheckedContext returns read
+// errors and cancellation.
+func TestCheckpointedVerify(t *testing.T) {
+	data := make([]byte, 40*testChunkSize)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	chunks := SplitIntoChunks(data, testChunkSize)
+	zeroLeaf := testZeroLeafHash()
+	readChunk := func(i int) []byte { return chunks[i] }
+
+	fullSMT, err := GenerateSparseMerkleTree(chunks, testMaxDepth, testHashChunk, zeroLeaf)
+	if err != nil {
+		t.Fatalf("build SMT: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := fullSMT.SaveCheckpointed(&buf, SchemeBalanced); err != nil {
+		t.Fatalf("save checkpointed: %v", err)
+	}
+	load := func() *CheckpointedSMT {
+		t.Helper()
+		csmt, err := LoadCheckpointedSMT(bytes.NewReader(buf.Bytes()), zeroLeaf)
+		if err != nil {
+			t.Fatalf("load checkpointed: %v", err)
+		}
+		return csmt
+	}
+	wantSegment := func(name string, err error, lo, hi, index int) {
+		t.Helper()
+		var segErr *CorruptSegmentError
+		if !errors.As(err, &segErr) {
+			t.Fatalf("%s: expected CorruptSegmentError, got %v", name, err)
+		}
+		if segErr.Lo != lo || segErr.Hi != hi || segErr.Index != index {
+			t.Fatalf("%s: got segment [%d, %d) index %d, want [%d, %d) index %d",
+				name, segErr.Lo, segErr.Hi, segErr.Index, lo, hi, index)
+		}
+	}
+
+	csmt := load()
+	if err := csmt.Verify(); err != nil {
+		t.Fatalf("verify intact tree: %v", err)
+	}
+	for _, leafIdx := range []int{0, 17, 39, 40, 1 << 19} {
+		res, err := csmt.RebuildProofChecked(leafIdx, readChunk, testHashChunk)
+		if err != nil {
+			t.Fatalf("leaf %d: %v", leafIdx, err)
+		}
+		fullSib, _ := fullSMT.GetProof(leafIdx)
+		for lvl := range fullSib {
+			if res.Siblings[lvl] != fullSib[lvl] {
+				t.Fatalf("leaf %d: sibling mismatch at level %d", leafIdx, lvl)
+			}
+		}
+	}
+
+	// A flipped entry at level 9 breaks segment [4, 9).
+	csmt = load()
+	csmt.Levels[9][0].SetInt64(1)
+	wantSegment("verify level 9", csmt.Verify(), 4, 9, 0)
+	_, err = csmt.RebuildProofChecked(0, readChunk, testHashChunk)
+	wantSegment("rebuild level 9", err, 4, 9, 0)
+
+	// A flipped entry at level 4 is the base of [4, 9) and the top of the
+	// bottom gap [0, 4); paths through it see the latter.
+	csmt = load()
+	csmt.Levels[4][1].SetInt64(1)
+	wantSegment("verify level 4", csmt.Verify(), 4, 9, 0)
+	_, err = csmt.RebuildProofChecked(0, readChunk, testHashChunk)
+	wantSegment("rebuild sibling level 4", err, 4, 9, 0)
+	_, err = csmt.RebuildProofChecked(20, readChunk, testHashChunk)
+	wantSegment("rebuild path level 4", err, 0, 4, 1)
+
+	// Altered chunk data is caught only by the rebuild.
+	csmt = load()
+	badChunk := func(i int) []byte {
+		if i == 3 {
+			return make([]byte, testChunkSize)
+		}
+		return chunks[i]
+	}
+	if err := csmt.Verify(); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	_, err = csmt.RebuildProofChecked(3, badChunk, testHashChunk)
+	wantSegment("rebuild bad chunk", err, 0, 4, 0)
+	_, err = csmt.RebuildProofCheckedContext(context.Background(), 3, func(i int) ([]byte, error) {
+		return badChunk(i), nil
+	}, testHashChunk)
+	wantSegment("context rebuild bad chunk", err, 0, 4, 0)
+
+	// Read errors and cancellation are returned, not a segment error.
+	readErr := errors.New("injected read error")
+	_, err = csmt.RebuildProofCheckedContext(context.Background(), 3, func(i int) ([]byte, error) {
+		return nil, readErr
+	}, testHashChunk)
+	if !errors.Is(err, readErr) {
+		t.Fatalf("expected read error, got %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = csmt.RebuildProofCheckedContext(ctx, 3, func(i int) ([]byte, error) {
+		return chunks[i], nil
+	}, testHashChunk)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation error, got %v", err)
+	}
+
+	// Structural damage is reported without a segment.
+	csmt = load()
+	csmt.Root.SetInt64(1)
+	if err := csmt.Verify(); err == nil {
+		t.Fatal("expected root mismatch error")
+	}
+	csmt = load()
+	csmt.Levels[15] = csmt.Levels[15][:0]
+	if err := csmt.Verify(); err == nil {
+		t.Fatal("expected level length error")
+	}
+}
+
+// TestEstimateSchemePresets checks the cost model against the figures
+// documented on the depth-20 presets (10 GB file, 11 cores, 4 ms per leaf).
+func TestEstimateSchemePresets(t *testing.T) {
+	cfg := PlanConfig{NumLeaves: 655360, Depth: 20, Workers: 11}
+	cases := []struct {
+		scheme     CheckpointScheme
+		minBytes   int64
+		maxBytes   int64
+		maxRebuild time.Duration
+	}{
+		{SchemeCompact, 20_000, 26_000, 450 * time.Millisecond},
+		{SchemeBalanced, 1_400_000, 1_600_000, 10 * time.Millisecond},
+		{SchemeFast, 2_900_000, 3_300_000, 10 * time.Millisecond},
+	}
+	for _, tc := range cases {
+		est, err := EstimateScheme(tc.scheme, cfg)
+		if err != nil {
+			t.Fatalf("estimate %v: %v", tc.scheme.Levels, err)
+		}
+		if est.Bytes < tc.minBytes || est.Bytes > tc.maxBytes || est.Rebuild > tc.maxRebuild {
+			t.Fatalf("scheme %v: %d bytes, %v rebuild", tc.scheme.Levels, est.Bytes, est.Rebuild)
+		}
+	}
+
+	// The estimate's byte count is exactly what Save writes.
+	leafHashes := make([]fr.Element, 300)
+	for i := range leafHashes {
+		leafHashes[i].SetInt64(int64(i + 1))
+	}
+	smt, err := BuildSMTFromLeafHashes(leafHashes, 12, testZeroLeafHash())
+	if err != nil {
+		t.Fatalf("build SMT: %v", err)
+	}
+	scheme := CheckpointScheme{Levels: []int{0, 3, 7, 12}}
+	var buf bytes.Buffer
+	if err := smt.SaveCheckpointed(&buf, scheme); err != nil {
+		t.Fatalf("save checkpointed: %v", err)
+	}
+	est, err := EstimateScheme(scheme, PlanConfig{NumLeaves: 300, Depth: 12})
+	if err != nil {
+		t.Fatalf("estimate: %v", err)
+	}
+	if est.Bytes != int64(buf.Len()) {
+		t.Fatalf("estimated %d bytes, Save wrote %d", est.Bytes, buf.Len())
+	}
+}
+
+// TestPlanScheme compares PlanScheme against an exhaustive search over every
+// scheme of a small tree, for both time and space budgets.
+func TestPlanScheme(t *testing.T) {
+	const depth = 9
+	base := PlanConfig{NumLeaves: 300, Depth: depth, Workers: 4}
+
+	var all []SchemeEstimate
+	for mask := 0; mask < 1<<depth; mask++ {
+		var levels []int
+		for lvl := 0; lvl < depth; lvl++ {
+			if mask&(1<<lvl) != 0 {
+				levels = append(levels, lvl)
+			}
+		}
+		est, err := EstimateScheme(CheckpointScheme{Levels: append(levels, depth)}, base)
+		if err != nil {
+			t.Fatalf("estimate: %v", err)
+		}
+		all = append(all, est)
+	}
+
+	for _, target := range []time.Duration{time.Millisecond, 5 * time.Millisecond, 20 * time.Millisecond, time.Second} {
+		cfg := base
+		cfg.MaxRebuild = target
+		plan, err := PlanScheme(cfg)
+		if err != nil {
+			t.Fatalf("plan within %v: %v", target, err)
+		}
+		if plan.Rebuild > target {
+			t.Fatalf("plan within %v rebuilds in %v", target, plan.Rebuild)
+		}
+		for _, est := range all {
+			if est.Rebuild <= target && est.Bytes < plan.Bytes {
+				t.Fatalf("plan within %v: %v takes %d bytes, %v takes %d",
+					target, plan.Scheme.Levels, plan.Bytes, est.Scheme.Levels, est.Bytes)
+			}
+		}
+	}
+
+	for _, budget := range []int64{100, 500, 2_000, 20_000} {
+		cfg := base
+		cfg.MaxBytes = budget
+		plan, err := PlanScheme(cfg)
+		if err != nil {
+			t.Fatalf("plan in %d bytes: %v", budget, err)
+		}
+		if plan.Bytes > budget {
+			t.Fatalf("plan in %d bytes takes %d", budget, plan.Bytes)
+		}
+		for _, est := range all {
+			if est.Bytes <= budget && est.Rebuild < plan.Rebuild {
+				t.Fatalf("plan in %d bytes: %v rebuilds in %v, %v in %v",
+					budget, plan.Scheme.Levels, plan.Rebuild, est.Scheme.Levels, est.Rebuild)
+			}
+		}
+	}
+
+	// Planned schemes are valid and work with the checkpoint format.
+	cfg := base
+	cfg.MaxRebuild = 5 * time.Millisecond
+	plan, err := PlanScheme(cfg)
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	leafHashes := make([]fr.Element, base.NumLeaves)
+	for i := range leafHashes {
+		leafHashes[i].SetInt64(int64(i + 1))
+	}
+	smt, err := BuildSMTFromLeafHashes(leafHashes, depth, testZeroLeafHash())
+	if err != nil {
+		t.Fatalf("build SMT: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := smt.SaveCheckpointed(&buf, plan.Scheme); err != nil {
+		t.Fatalf("save with planned scheme: %v", err)
+	}
+	if int64(buf.Len()) != plan.Bytes {
+		t.Fatalf("planned %d bytes, Save wrote %d", plan.Bytes, buf.Len())
+	}
+}
+
+func TestPlanSchemeRejectsInfeasible(t *testing.T) {
+	base := PlanConfig{NumLeaves: 655360, Depth: 20}
+	cases := []PlanConfig{
+		base,
+		{NumLeaves: 655360, Depth: 20, MaxBytes: 10},
+		{NumLeaves: 655360, Depth: 20, MaxRebuild: time.Nanosecond, MaxBytes: 1 << 20},
+		{NumLeaves: 1 << 21, Depth: 20, MaxBytes: 1 << 20},
+	}
+	for i, cfg := range cases {
+		if _, err := PlanScheme(cfg); err == nil {
+			t.Fatalf("case %d: expected error", i)
+		}
+	}
+}
+
 func BenchmarkCheckpointedRebuildProof(b *testing.B) {
 	data := make([]byte, 8*testChunkSize)
 	if _, err := rand.Read(data); err != nil {
@@ -591,6 +1602,103 @@ func BenchmarkCheckpointedSaveLoad(b *testing.B) {
 	}
 }
 
+// ---------------------------------------------------------------------------
+// Unified tree API tests
+// ---------------------------------------------------------------------------
+
+// TestDenseMerkleTree checks dense tree depths and that dense trees, sparse
+// trees and their stored views all serve proofs accepted by VerifyProof.
+func TestDenseMerkleTree(t *testing.T) {
+	for n, want := range map[int]int{0: 1, 1: 1, 2: 1, 3: 2, 4: 2, 5: 3, 1024: 10, 1025: 11} {
+		if got := DenseDepth(n); got != want {
+			t.Fatalf("DenseDepth(%d) = %d, expected %d", n, got, want)
+		}
+	}
+
+	zeroLeaf := testZeroLeafHash()
+	for _, numChunks := range []int{1, 2, 5, 17} {
+		data := make([]byte, numChunks*testChunkSize)
+		if _, err := rand.Read(data); err != nil {
+			t.Fatal(err)
+		}
+		chunks := SplitIntoChunks(data, testChunkSize)
+		dense, err := GenerateDenseMerkleTree(chunks, testHashChunk, zeroLeaf)
+		if err != nil {
+			t.Fatalf("build dense tree: %v", err)
+		}
+		if dense.Depth != DenseDepth(numChunks) {
+			t.Fatalf("%d chunks: dense depth %d, expected %d", numChunks, dense.Depth, DenseDepth(numChunks))
+		}
+		sparse, err := GenerateSparseMerkleTree(chunks, testMaxDepth, testHashChunk, zeroLeaf)
+		if err != nil {
+			t.Fatalf("build sparse tree: %v", err)
+		}
+
+		for name, tree := range map[string]Tree{"dense": dense, "sparse": sparse, "stored": sparse.Stored()} {
+			info := tree.Info()
+			if info.NumLeaves != numChunks {
+				t.Fatalf("%s: %d leaves, expected %d", name, info.NumLeaves, numChunks)
+			}
+			for idx := 0; idx < 1<<info.Depth && idx <= numChunks; idx++ {
+				sibs, dirs := tree.GetProof(idx)
+				if len(sibs) != info.Depth || !VerifyProof(tree.GetLeafHash(idx), sibs, dirs, info.Root) {
+					t.Fatalf("%s %d chunks: proof for leaf %d does not verify", name, numChunks, idx)
+				}
+			}
+		}
+	}
+}
+
+func TestVerifyProofRejectsTampering(t *testing.T) {
+	data := make([]byte, 5*testChunkSize)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	chunks := SplitIntoChunks(data, testChunkSize)
+	smt, err := GenerateDenseMerkleTree(chunks, testHashChunk, testZeroLeafHash())
+	if err != nil {
+		t.Fatalf("build dense tree: %v", err)
+	}
+	leaf := smt.GetLeafHash(3)
+	sibs, dirs := smt.GetProof(3)
+	if !VerifyProof(leaf, sibs, dirs, smt.Root) {
+		t.Fatal("valid proof rejected")
+	}
+
+	var other fr.Element
+	other.SetInt64(42)
+	if VerifyProof(other, sibs, dirs, smt.Root) {
+		t.Fatal("proof accepted for the wrong leaf")
+	}
+	badDirs := append([]int(nil), dirs...)
+	badDirs[0] ^= 1
+	if VerifyProof(leaf, sibs, badDirs, smt.Root) {
+		t.Fatal("proof accepted with flipped direction")
+	}
+	badDirs[0] = 2
+	if VerifyProof(leaf, sibs, badDirs, smt.Root) {
+		t.Fatal("proof accepted with non-binary direction")
+	}
+	if VerifyProof(leaf, sibs[1:], dirs, smt.Root) {
+		t.Fatal("proof accepted with missing sibling")
+	}
+}
+
+// TestLeafHashFr checks the migration adapter for *big.Int leaf hashes.
+func TestLeafHashFr(t *testing.T) {
+	legacy := func(chunk []byte) *big.Int {
+		h := testHashChunk(chunk)
+		out := new(big.Int)
+		h.BigInt(out)
+		return out
+	}
+	chunk := make([]byte, testChunkSize)
+	chunk[7] = 1
+	if LeafHashFr(legacy)(chunk) != testHashChunk(chunk) {
+		t.Fatal("adapted leaf hash mismatch")
+	}
+}
+
 func fmtChunks(n int) string {
 	return "chunks_" + itoa(n)
 }
@@ -0,0 +1,324 @@
+package merkle
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// ---------------------------------------------------------------------------
+// Memory-mapped SMT
+// ---------------------------------------------------------------------------
+//
+// An MmapSMT keeps every level of a sparse Merkle tree in a flat file that is
+// memory-mapped on open, so the OS pages node hashes in and out on demand and
+// many trees can be open at once without holding them on the heap.
+//
+// File layout (all integers big-endian):
+//   [8]byte magic "MURIMMAP" | uint32(version) | uint32(depth) |
+//   uint64(numLeaves) | [32]byte(zeroLeafHash) | 8 bytes reserved
+//   For each level 0..depth:
+//     levelSize(numLeaves, level) × [32]byte(hash as big-endian fr.Element)
+//
+// Updates are written through the shared mapping; call Sync to flush them
+// to disk. The leaf count is fixed when the file is created.
+
+const (
+	mmapMagic      = "MURIMMAP"
+	mmapVersion    = 1
+	mmapHeaderSize = 64
+	mmapEntrySize  = 32
+)
+
+// errMmapClosed is returned by MmapSMT methods called after Close.
+var errMmapClosed = errors.New("memory-mapped tree is closed")
+
+// MmapSMT is a sparse Merkle tree stored in a memory-mapped file. Its methods
+// are safe for concurrent use; Close must be called to release the mapping.
+// After Close, UpdateLeaves and Sync return an error and the accessors
+// without an error result panic.
+type MmapSMT struct {
+	mu   sync.RWMutex
+	file *os.File
+	data []byte
+	tree StoredSMT
+}
+
+// mmapLevels is the LevelStore over a mapped file.
+type mmapLevels struct {
+	data    []byte
+	offsets []int // byte offset of each level
+	sizes   []int // entry count of each level
+}
+
+func (m *mmapLevels) LevelLen(level int) int { return m.sizes[level] }
+
+func (m *mmapLevels) Node(level, index int) fr.Element {
+	off := m.offsets[level] + index*mmapEntrySize
+	var h fr.Element
+	h.SetBytes(m.data[off : off+mmapEntrySize])
+	return h
+}
+
+func (m *mmapLevels) SetNode(level, index int, h fr.Element) {
+	off := m.offsets[level] + index*mmapEntrySize
+	b := h.Bytes()
+	copy(m.data[off:off+mmapEntrySize], b[:])
+}
+
+// mmapLayout returns the byte offset and entry count of every level and the
+// total file size for a tree of the given shape.
+func mmapLayout(depth, numLeaves int) (offsets, sizes []int, fileSize int) {
+	offsets = make([]int, depth+1)
+	sizes = make([]int, depth+1)
+	off := mmapHeaderSize
+	for lvl := 0; lvl <= depth; lvl++ {
+		offsets[lvl] = off
+		sizes[lvl] = levelSize(numLeaves, lvl)
+		off += sizes[lvl] * mmapEntrySize
+	}
+	return offsets, sizes, off
+}
+
+// CreateMmapSMT writes smt to a new file at path and maps it.
+func CreateMmapSMT(path string, smt *SparseMerkleTree) (*MmapSMT, error) {
+	m, err := createMmapFile(path, smt.Depth, smt.NumLeaves, smt.ZeroHashes)
+	if err != nil {
+		return nil, err
+	}
+	for lvl, entries := range smt.Levels {
+		for i := range entries {
+			m.tree.Store.SetNode(lvl, i, entries[i])
+		}
+	}
+	return m, nil
+}
+
+// BuildMmapSMT builds a tree from pre-computed leaf hashes directly in a new
+// file at path, so only the leaf hashes are ever held on the heap.
+func BuildMmapSMT(path string, leafHashes []fr.Element, depth int, zeroLeafHash fr.Element) (*MmapSMT, error) {
+	if err := validateLeafCapacity(depth, len(leafHashes)); err != nil {
+		return nil, err
+	}
+	m, err := createMmapFile(path, depth, len(leafHashes), PrecomputeZeroHashes(depth, zeroLeafHash))
+	if err != nil {
+		return nil, err
+	}
+	store := m.tree.Store
+	for i := range leafHashes {
+		store.SetNode(0, i, leafHashes[i])
+	}
+	parents := make([]int, 0, len(leafHashes))
+	for lvl := 0; lvl < depth; lvl++ {
+		parents = parents[:store.LevelLen(lvl+1)]
+		for p := range parents {
+			parents[p] = p
+		}
+		rehashParents(store, lvl, m.tree.ZeroHashes[lvl], parents)
+	}
+	return m, nil
+}
+
+// createMmapFile creates a zero-filled tree file with a valid header and
+// maps it. The file is removed again on error.
+func createMmapFile(path string, depth, numLeaves int, zeroHashes []fr.Element) (*MmapSMT, error) {
+	if err := validateLeafCapacity(depth, numLeaves); err != nil {
+		return nil, err
+	}
+	_, _, fileSize := mmapLayout(depth, numLeaves)
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("create tree file: %w", err)
+	}
+	if err := f.Truncate(int64(fileSize)); err != nil {
+		f.Close()
+		os.Remove(path)
+		return nil, fmt.Errorf("size tree file: %w", err)
+	}
+
+	var header [mmapHeaderSize]byte
+	copy(header[:8], mmapMagic)
+	binary.BigEndian.PutUint32(header[8:12], mmapVersion)
+	binary.BigEndian.PutUint32(header[12:16], uint32(depth))
+	binary.BigEndian.PutUint64(header[16:24], uint64(numLeaves))
+	zl := zeroHashes[0].Bytes()
+	copy(header[24:56], zl[:])
+	if _, err := f.WriteAt(header[:], 0); err != nil {
+		f.Close()
+		os.Remove(path)
+		return nil, fmt.Errorf("write tree header: %w", err)
+	}
+
+	m, err := mapTreeFile(f, depth, numLeaves, zeroHashes, fileSize)
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return m, nil
+}
+
+// OpenMmapSMT maps an existing tree file written by CreateMmapSMT or
+// BuildMmapSMT. zeroLeafHash must match the one the file was built with.
+func OpenMmapSMT(path string, zeroLeafHash fr.Element) (*MmapSMT, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open tree file: %w", err)
+	}
+
+	var header [mmapHeaderSize]byte
+	if _, err := f.ReadAt(header[:], 0); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("read tree header: %w", err)
+	}
+	if string(header[:8]) != mmapMagic {
+		f.Close()
+		return nil, fmt.Errorf("not a memory-mapped tree file")
+	}
+	if v := binary.BigEndian.Uint32(header[8:12]); v != mmapVersion {
+		f.Close()
+		return nil, fmt.Errorf("unsupported tree file version %d", v)
+	}
+	depth := int(binary.BigEndian.Uint32(header[12:16]))
+	numLeaves64 := binary.BigEndian.Uint64(header[16:24])
+	maxLeaves, err := maxLeavesForDepth(depth)
+	if err != nil || numLeaves64 > uint64(maxLeaves) {
+		f.Close()
+		return nil, fmt.Errorf("invalid tree shape: depth %d, %d leaves", depth, numLeaves64)
+	}
+	numLeaves := int(numLeaves64)
+	zl := zeroLeafHash.Bytes()
+	if !bytes.Equal(header[24:56], zl[:]) {
+		f.Close()
+		return nil, fmt.Errorf("tree file was built with a different zero leaf hash")
+	}
+
+	_, _, fileSize := mmapLayout(depth, numLeaves)
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("stat tree file: %w", err)
+	}
+	if info.Size() != int64(fileSize) {
+		f.Close()
+		return nil, fmt.Errorf("tree file is %d bytes, want %d", info.Size(), fileSize)
+	}
+
+	return mapTreeFile(f, depth, numLeaves, PrecomputeZeroHashes(depth, zeroLeafHash), fileSize)
+}
+
+// mapTreeFile maps fileSize bytes of f and wraps them in an MmapSMT. f is
+// closed on error.
+func mapTreeFile(f *os.File, depth, numLeaves int, zeroHashes []fr.Element, fileSize int) (*MmapSMT, error) {
+	data, err := mapFile(f, fileSize)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("map tree file: %w", err)
+	}
+	offsets, sizes, _ := mmapLayout(depth, numLeaves)
+	return &MmapSMT{
+		file: f,
+		data: data,
+		tree: StoredSMT{
+			Depth:      depth,
+			NumLeaves:  numLeaves,
+			ZeroHashes: zeroHashes,
+			Store:      &mmapLevels{data: data, offsets: offsets, sizes: sizes},
+		},
+	}, nil
+}
+
+// Depth returns the tree depth.
+func (m *MmapSMT) Depth() int { return m.tree.Depth }
+
+// NumLeaves returns the number of real leaves.
+func (m *MmapSMT) NumLeaves() int { return m.tree.NumLeaves }
+
+// mustBeOpen panics if the tree has been closed, instead of letting a read
+// fault on the released mapping. The caller must hold m.mu.
+func (m *MmapSMT) mustBeOpen() {
+	if m.data == nil {
+		panic("merkle: " + errMmapClosed.Error())
+	}
+}
+
+// Root returns the current root hash.
+func (m *MmapSMT) Root() fr.Element {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	m.mustBeOpen()
+	return m.tree.Root()
+}
+
+// Info returns the root and shape of the tree.
+func (m *MmapSMT) Info() TreeInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	m.mustBeOpen()
+	return m.tree.Info()
+}
+
+// GetProof returns a fixed-size Merkle proof for the leaf at the given index.
+func (m *MmapSMT) GetProof(leafIndex int) ([]fr.Element, []int) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	m.mustBeOpen()
+	return m.tree.GetProof(leafIndex)
+}
+
+// GetLeafHash returns the hash at the given leaf index, using the zero leaf
+// hash for positions beyond the real leaves.
+func (m *MmapSMT) GetLeafHash(leafIndex int) fr.Element {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	m.mustBeOpen()
+	return m.tree.GetLeafHash(leafIndex)
+}
+
+// UpdateLeaf replaces the hash of real leaf leafIndex and rehashes its path.
+func (m *MmapSMT) UpdateLeaf(leafIndex int, leafHash fr.Element) error {
+	return m.UpdateLeaves(map[int]fr.Element{leafIndex: leafHash})
+}
+
+// UpdateLeaves replaces the hashes of the given real leaves and rehashes
+// their paths (see StoredSMT.UpdateLeaves).
+func (m *MmapSMT) UpdateLeaves(updates map[int]fr.Element) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.data == nil {
+		return errMmapClosed
+	}
+	return m.tree.UpdateLeaves(updates)
+}
+
+// Sync flushes updates in the mapping to the underlying file.
+func (m *MmapSMT) Sync() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.data == nil {
+		return errMmapClosed
+	}
+	return syncMapping(m.data)
+}
+
+// Close unmaps and closes the file. Closing a closed tree is a no-op.
+func (m *MmapSMT) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.data == nil {
+		return nil
+	}
+	err := unmapFile(m.data)
+	// Drop every reference into the released mapping.
+	m.data = nil
+	m.tree.Store = nil
+	if cerr := m.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
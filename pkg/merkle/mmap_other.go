@@ -0,0 +1,16 @@
+//go:build !unix
+
+package merkle
+
+import (
+	"errors"
+	"os"
+)
+
+var errMmapUnsupported = errors.New("memory-mapped trees are not supported on this platform")
+
+func mapFile(f *os.File, size int) ([]byte, error) { return nil, errMmapUnsupported }
+
+func unmapFile(data []byte) error { return errMmapUnsupported }
+
+func syncMapping(data []byte) error { return errMmapUnsupported }
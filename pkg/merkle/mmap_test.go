@@ -0,0 +1,216 @@
+//go:build unix
+
+package merkle
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// TestMmapSMT checks that a memory-mapped tree serves the same proofs as the
+// in-memory tree it was created from, that updates match in-memory updates,
+// and that they persist across Close and OpenMmapSMT.
+func TestMmapSMT(t *testing.T) {
+	const depth = 12
+	const numLeaves = 1500
+	leafHashes := make([]fr.Element, numLeaves)
+	for i := range leafHashes {
+		leafHashes[i].SetInt64(int64(i + 1))
+	}
+	zeroLeaf := testZeroLeafHash()
+
+	smt, err := BuildSMTFromLeafHashes(leafHashes, depth, zeroLeaf)
+	if err != nil {
+		t.Fatalf("build SMT: %v", err)
+	}
+
+	dir := t.TempDir()
+	created, err := CreateMmapSMT(filepath.Join(dir, "created.smt"), smt)
+	if err != nil {
+		t.Fatalf("create mmap SMT: %v", err)
+	}
+	defer created.Close()
+	built, err := BuildMmapSMT(filepath.Join(dir, "built.smt"), leafHashes, depth, zeroLeaf)
+	if err != nil {
+		t.Fatalf("build mmap SMT: %v", err)
+	}
+
+	checkAgainst := func(name string, m *MmapSMT) {
+		t.Helper()
+		if m.Info() != smt.Info() || m.Depth() != depth || m.NumLeaves() != numLeaves {
+			t.Fatalf("%s: root or shape mismatch", name)
+		}
+		for _, idx := range []int{0, 1, 777, numLeaves - 1, numLeaves, 1<<depth - 1} {
+			wantSibs, wantDirs := smt.GetProof(idx)
+			sibs, dirs := m.GetProof(idx)
+			for lvl := range wantSibs {
+				if sibs[lvl] != wantSibs[lvl] || dirs[lvl] != wantDirs[lvl] {
+					t.Fatalf("%s: proof for leaf %d differs at level %d", name, idx, lvl)
+				}
+			}
+			if m.GetLeafHash(idx) != smt.GetLeafHash(idx) {
+				t.Fatalf("%s: leaf hash %d mismatch", name, idx)
+			}
+		}
+	}
+	checkAgainst("created", created)
+	checkAgainst("built", built)
+
+	updates := make(map[int]fr.Element)
+	for idx := 0; idx < numLeaves; idx += 3 {
+		var h fr.Element
+		h.SetInt64(int64(500000 + idx))
+		updates[idx] = h
+	}
+	if err := smt.UpdateLeaves(updates); err != nil {
+		t.Fatalf("update in-memory SMT: %v", err)
+	}
+	if err := built.UpdateLeaves(updates); err != nil {
+		t.Fatalf("update mmap SMT: %v", err)
+	}
+	checkAgainst("updated", built)
+
+	if err := built.Sync(); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if err := built.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	// A closed tree fails cleanly instead of faulting on the mapping.
+	if err := built.Close(); err != nil {
+		t.Fatalf("second close: %v", err)
+	}
+	if err := built.UpdateLeaves(updates); err == nil {
+		t.Fatal("expected error updating a closed tree")
+	}
+	if err := built.Sync(); err == nil {
+		t.Fatal("expected error syncing a closed tree")
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected panic reading a closed tree")
+			}
+		}()
+		built.GetProof(0)
+	}()
+
+	reopened, err := OpenMmapSMT(filepath.Join(dir, "built.smt"), zeroLeaf)
+	if err != nil {
+		t.Fatalf("reopen mmap SMT: %v", err)
+	}
+	defer reopened.Close()
+	checkAgainst("reopened", reopened)
+
+	if err := reopened.UpdateLeaf(numLeaves, zeroLeaf); err == nil {
+		t.Fatal("expected error updating a padding leaf")
+	}
+}
+
+// TestMmapSMTConcurrent reads proofs from several mapped trees while one of
+// them is being updated.
+func TestMmapSMTConcurrent(t *testing.T) {
+	const depth = 10
+	zeroLeaf := testZeroLeafHash()
+	dir := t.TempDir()
+
+	trees := make([]*MmapSMT, 4)
+	for n := range trees {
+		leafHashes := make([]fr.Element, 300+n*100)
+		for i := range leafHashes {
+			leafHashes[i].SetInt64(int64(n*10000 + i + 1))
+		}
+		m, err := BuildMmapSMT(filepath.Join(dir, "tree"+itoa(n)), leafHashes, depth, zeroLeaf)
+		if err != nil {
+			t.Fatalf("build mmap SMT %d: %v", n, err)
+		}
+		defer m.Close()
+		trees[n] = m
+	}
+
+	var wg sync.WaitGroup
+	for n, m := range trees {
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func(m *MmapSMT) {
+				defer wg.Done()
+				for idx := 0; idx < m.NumLeaves(); idx += 7 {
+					root := m.Root()
+					sibs, dirs := m.GetProof(idx)
+					if n != 0 && !VerifyProof(m.GetLeafHash(idx), sibs, dirs, root) {
+						t.Errorf("tree %d: proof for leaf %d does not verify", n, idx)
+						return
+					}
+				}
+			}(m)
+		}
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for idx := 0; idx < trees[0].NumLeaves(); idx += 5 {
+			var h fr.Element
+			h.SetInt64(int64(idx + 7))
+			if err := trees[0].UpdateLeaf(idx, h); err != nil {
+				t.Errorf("update leaf %d: %v", idx, err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+}
+
+func TestOpenMmapSMTRejectsInvalid(t *testing.T) {
+	leafHashes := make([]fr.Element, 10)
+	for i := range leafHashes {
+		leafHashes[i].SetInt64(int64(i + 1))
+	}
+	zeroLeaf := testZeroLeafHash()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tree.smt")
+
+	m, err := BuildMmapSMT(path, leafHashes, 8, zeroLeaf)
+	if err != nil {
+		t.Fatalf("build mmap SMT: %v", err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	if _, err := BuildMmapSMT(path, leafHashes, 8, zeroLeaf); err == nil {
+		t.Fatal("expected error overwriting an existing tree file")
+	}
+
+	var otherZero fr.Element
+	otherZero.SetInt64(12345)
+	if _, err := OpenMmapSMT(path, otherZero); err == nil {
+		t.Fatal("expected zero leaf hash mismatch error")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read tree file: %v", err)
+	}
+	truncated := filepath.Join(dir, "truncated.smt")
+	if err := os.WriteFile(truncated, data[:len(data)-32], 0o644); err != nil {
+		t.Fatalf("write truncated file: %v", err)
+	}
+	if _, err := OpenMmapSMT(truncated, zeroLeaf); err == nil {
+		t.Fatal("expected truncated file error")
+	}
+
+	badMagic := filepath.Join(dir, "magic.smt")
+	corrupt := append([]byte(nil), data...)
+	corrupt[0] ^= 0xff
+	if err := os.WriteFile(badMagic, corrupt, 0o644); err != nil {
+		t.Fatalf("write corrupt file: %v", err)
+	}
+	if _, err := OpenMmapSMT(badMagic, zeroLeaf); err == nil {
+		t.Fatal("expected bad magic error")
+	}
+}
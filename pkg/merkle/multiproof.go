@@ -0,0 +1,243 @@
+package merkle
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// ---------------------------------------------------------------------------
+// Multi-proofs (batched openings)
+// ---------------------------------------------------------------------------
+//
+// A MultiProof opens several leaves of one sparse Merkle tree at once. Paths
+// of nearby leaves share their upper levels, so instead of Depth siblings
+// per leaf it carries only the sibling hashes the verifier cannot compute
+// from the opened leaves themselves.
+//
+// Siblings are stored in a canonical order: level by level from the leaves
+// up, and within a level by ascending node index. Generation and
+// verification walk the tree in the same order, so no per-sibling position
+// is transmitted.
+
+// MultiProof is a deduplicated Merkle proof for a set of leaves.
+type MultiProof struct {
+	Depth    int
+	Indices  []int        // opened leaf indices, sorted ascending without duplicates
+	Siblings []fr.Element // sibling hashes in canonical order
+}
+
+// GetMultiProof returns a multi-proof for the given leaf indices. Indices may
+// be in any order and contain duplicates; the proof records them sorted and
+// deduplicated. Indices beyond the real leaves open padding leaves.
+func (smt *SparseMerkleTree) GetMultiProof(indices []int) (*MultiProof, error) {
+	known, err := canonicalIndices(indices, smt.Depth)
+	if err != nil {
+		return nil, err
+	}
+	proof := &MultiProof{
+		Depth:   smt.Depth,
+		Indices: append([]int(nil), known...),
+	}
+
+	for lvl := 0; lvl < smt.Depth; lvl++ {
+		level := smt.Levels[lvl]
+		next := known[:0]
+		for i := 0; i < len(known); i++ {
+			idx := known[i]
+			if idx%2 == 0 && i+1 < len(known) && known[i+1] == idx+1 {
+				// Both children are known; no sibling needed.
+				i++
+			} else {
+				sib := idx ^ 1
+				if sib < len(level) {
+					proof.Siblings = append(proof.Siblings, level[sib])
+				} else {
+					proof.Siblings = append(proof.Siblings, smt.ZeroHashes[lvl])
+				}
+			}
+			next = append(next, idx/2)
+		}
+		known = next
+	}
+
+	return proof, nil
+}
+
+// VerifyMultiProof checks that leafHashes, where leafHashes[i] is the hash of
+// leaf proof.Indices[i], are leaves of the depth-deep tree with the given
+// root. Proofs declaring another depth are rejected, so a proof cannot open
+// an inner node of the tree as if it were a leaf of a shallower subtree.
+func VerifyMultiProof(root fr.Element, depth int, proof *MultiProof, leafHashes []fr.Element) bool {
+	if proof == nil || proof.Depth != depth || len(proof.Indices) == 0 || len(leafHashes) != len(proof.Indices) {
+		return false
+	}
+	known, err := canonicalIndices(proof.Indices, proof.Depth)
+	if err != nil || len(known) != len(proof.Indices) {
+		return false
+	}
+	for i := range known {
+		if known[i] != proof.Indices[i] {
+			return false
+		}
+	}
+
+	hashes := append([]fr.Element(nil), leafHashes...)
+	siblings := proof.Siblings
+	for lvl := 0; lvl < proof.Depth; lvl++ {
+		next := known[:0]
+		nextHashes := hashes[:0]
+		for i := 0; i < len(known); i++ {
+			idx := known[i]
+			var parent fr.Element
+			if idx%2 == 0 && i+1 < len(known) && known[i+1] == idx+1 {
+				parent = HashNodesFr(hashes[i], hashes[i+1])
+				i++
+			} else {
+				if len(siblings) == 0 {
+					return false
+				}
+				if idx%2 == 0 {
+					parent = HashNodesFr(hashes[i], siblings[0])
+				} else {
+					parent = HashNodesFr(siblings[0], hashes[i])
+				}
+				siblings = siblings[1:]
+			}
+			next = append(next, idx/2)
+			nextHashes = append(nextHashes, parent)
+		}
+		known, hashes = next, nextHashes
+	}
+
+	return len(siblings) == 0 && hashes[0] == root
+}
+
+// canonicalIndices returns a sorted, deduplicated copy of indices after
+// checking that each lies within a depth-deep tree.
+func canonicalIndices(indices []int, depth int) ([]int, error) {
+	maxLeaves, err := maxLeavesForDepth(depth)
+	if err != nil {
+		return nil, err
+	}
+	if len(indices) == 0 {
+		return nil, fmt.Errorf("multi-proof needs at least one leaf index")
+	}
+	out := append([]int(nil), indices...)
+	sort.Ints(out)
+	n := 0
+	for _, idx := range out {
+		if idx < 0 || idx >= maxLeaves {
+			return nil, fmt.Errorf("leaf index %d out of range [0, %d)", idx, maxLeaves)
+		}
+		if n == 0 || out[n-1] != idx {
+			out[n] = idx
+			n++
+		}
+	}
+	return out[:n], nil
+}
+
+// ---------------------------------------------------------------------------
+// MultiProof serialization
+// ---------------------------------------------------------------------------
+//
+// Format:
+//   uint32(depth) | uint32(numIndices)
+//   For each index: uint32(index)
+//   uint32(numSiblings)
+//   For each sibling: [32]byte(hash as big-endian fr.Element)
+
+// Save writes the multi-proof to w in a deterministic binary format.
+func (mp *MultiProof) Save(w io.Writer) error {
+	if err := binary.Write(w, binary.BigEndian, uint32(mp.Depth)); err != nil {
+		return fmt.Errorf("write depth: %w", err)
+	}
+	if err := binary.Write(w, binary.BigEndian, uint32(len(mp.Indices))); err != nil {
+		return fmt.Errorf("write index count: %w", err)
+	}
+	for _, idx := range mp.Indices {
+		if err := binary.Write(w, binary.BigEndian, uint32(idx)); err != nil {
+			return fmt.Errorf("write index %d: %w", idx, err)
+		}
+	}
+	if err := binary.Write(w, binary.BigEndian, uint32(len(mp.Siblings))); err != nil {
+		return fmt.Errorf("write sibling count: %w", err)
+	}
+	for i := range mp.Siblings {
+		b := mp.Siblings[i].Bytes()
+		if _, err := w.Write(b[:]); err != nil {
+			return fmt.Errorf("write sibling %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// multiProofLoadBatch bounds how many indices or siblings LoadMultiProof
+// allocates before reading them.
+const multiProofLoadBatch = 1 << 12
+
+// LoadMultiProof reads a multi-proof written by Save. Counts are bounded by
+// the declared depth, and indices and siblings are read in bounded batches,
+// so a malformed proof cannot force an allocation larger than its data.
+func LoadMultiProof(r io.Reader) (*MultiProof, error) {
+	var depth, numIndices uint32
+	if err := binary.Read(r, binary.BigEndian, &depth); err != nil {
+		return nil, fmt.Errorf("read depth: %w", err)
+	}
+	maxLeaves, err := maxLeavesForDepth(int(depth))
+	if err != nil || depth > 32 {
+		return nil, fmt.Errorf("invalid multi-proof depth %d", depth)
+	}
+	if err := binary.Read(r, binary.BigEndian, &numIndices); err != nil {
+		return nil, fmt.Errorf("read index count: %w", err)
+	}
+	if numIndices == 0 || uint64(numIndices) > uint64(maxLeaves) {
+		return nil, fmt.Errorf("invalid index count %d for depth %d", numIndices, depth)
+	}
+
+	var indices []int
+	var idxBuf [4]byte
+	for len(indices) < int(numIndices) {
+		n := min(multiProofLoadBatch, int(numIndices)-len(indices))
+		indices = append(indices, make([]int, n)...)
+		for i := len(indices) - n; i < len(indices); i++ {
+			if _, err := io.ReadFull(r, idxBuf[:]); err != nil {
+				return nil, fmt.Errorf("read index %d: %w", i, err)
+			}
+			indices[i] = int(binary.BigEndian.Uint32(idxBuf[:]))
+		}
+	}
+
+	var numSiblings uint32
+	if err := binary.Read(r, binary.BigEndian, &numSiblings); err != nil {
+		return nil, fmt.Errorf("read sibling count: %w", err)
+	}
+	if uint64(numSiblings) > uint64(numIndices)*uint64(depth) {
+		return nil, fmt.Errorf("sibling count %d exceeds %d indices at depth %d", numSiblings, numIndices, depth)
+	}
+
+	siblings := []fr.Element{}
+	var hashBuf [32]byte
+	for len(siblings) < int(numSiblings) {
+		n := min(multiProofLoadBatch, int(numSiblings)-len(siblings))
+		siblings = append(siblings, make([]fr.Element, n)...)
+		for i := len(siblings) - n; i < len(siblings); i++ {
+			if _, err := io.ReadFull(r, hashBuf[:]); err != nil {
+				return nil, fmt.Errorf("read sibling %d: %w", i, err)
+			}
+			if err := siblings[i].SetBytesCanonical(hashBuf[:]); err != nil {
+				return nil, fmt.Errorf("sibling %d: %w", i, err)
+			}
+		}
+	}
+
+	return &MultiProof{
+		Depth:    int(depth),
+		Indices:  indices,
+		Siblings: siblings,
+	}, nil
+}
@@ -0,0 +1,251 @@
+package merkle
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// ---------------------------------------------------------------------------
+// Checkpoint scheme planning
+// ---------------------------------------------------------------------------
+//
+// The preset schemes are tuned for one file size and machine. PlanScheme
+// instead picks the checkpoint levels for a given file from an operator's
+// space and rebuild-time budget, using a simple cost model of RebuildProof:
+//
+//   - Bottom gap (level 0 → first checkpoint c): the real leaves of one
+//     2^c-leaf subtree are hashed by Workers goroutines, then folded up.
+//   - Every other gap (p → l): the stored entries of one 2^(l-p)-entry
+//     subtree at level p are folded up with HashNodesFr.
+//   - Gaps are rebuilt concurrently, so the predicted wall-clock time is the
+//     slowest gap.
+//
+// Space is the exact size written by CheckpointedSMT.Save.
+
+const (
+	// defaultLeafHashTime is the single-core cost of hashing one 16 KB chunk
+	// assumed by the presets.
+	defaultLeafHashTime = 4 * time.Millisecond
+	// defaultNodeHashTime is the cost of one HashNodesFr call.
+	defaultNodeHashTime = 12 * time.Microsecond
+)
+
+// PlanConfig describes a file and the budget PlanScheme must meet. At least
+// one of MaxBytes and MaxRebuild must be set.
+type PlanConfig struct {
+	NumLeaves int
+	Depth     int
+
+	// MaxBytes bounds the serialized checkpoint size (0 = unbounded).
+	MaxBytes int64
+	// MaxRebuild bounds the predicted per-opening rebuild time
+	// (0 = unbounded).
+	MaxRebuild time.Duration
+
+	// Hash costs and parallelism of the proving machine. Zero values use
+	// 4 ms per leaf, 12 µs per node and runtime.NumCPU() workers;
+	// MeasureHashCosts measures the first two.
+	LeafHashTime time.Duration
+	NodeHashTime time.Duration
+	Workers      int
+}
+
+// SchemeEstimate is a checkpoint scheme with its predicted cost.
+type SchemeEstimate struct {
+	Scheme  CheckpointScheme
+	Bytes   int64         // serialized checkpoint size
+	Rebuild time.Duration // per-opening RebuildProof wall-clock time
+}
+
+// PlanScheme computes the optimal checkpoint scheme for cfg. With MaxRebuild
+// set it returns the smallest scheme that meets it (and MaxBytes, if set);
+// with only MaxBytes set it returns the fastest scheme that fits. It fails if
+// no scheme meets the budget.
+func PlanScheme(cfg PlanConfig) (SchemeEstimate, error) {
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return SchemeEstimate{}, err
+	}
+	if cfg.MaxBytes <= 0 && cfg.MaxRebuild <= 0 {
+		return SchemeEstimate{}, fmt.Errorf("checkpoint plan needs a space or rebuild-time budget")
+	}
+
+	if cfg.MaxRebuild > 0 {
+		levels, bytes := cfg.minBytesWithin(cfg.MaxRebuild)
+		if levels == nil {
+			return SchemeEstimate{}, fmt.Errorf("no checkpoint scheme rebuilds within %v", cfg.MaxRebuild)
+		}
+		if cfg.MaxBytes > 0 && bytes > cfg.MaxBytes {
+			return SchemeEstimate{}, fmt.Errorf("rebuilding within %v needs %d bytes, over the %d-byte budget", cfg.MaxRebuild, bytes, cfg.MaxBytes)
+		}
+		return cfg.estimate(levels), nil
+	}
+
+	// Without a time target, try gap-time limits from fastest to slowest
+	// and keep the first whose smallest scheme fits the space budget.
+	for _, limit := range cfg.gapTimes() {
+		if levels, bytes := cfg.minBytesWithin(limit); levels != nil && bytes <= cfg.MaxBytes {
+			return cfg.estimate(levels), nil
+		}
+	}
+	return SchemeEstimate{}, fmt.Errorf("no checkpoint scheme fits in %d bytes", cfg.MaxBytes)
+}
+
+// EstimateScheme predicts the space and rebuild time of scheme for cfg's
+// file and machine; the budget fields of cfg are ignored.
+func EstimateScheme(scheme CheckpointScheme, cfg PlanConfig) (SchemeEstimate, error) {
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return SchemeEstimate{}, err
+	}
+	if err := validateScheme(scheme, cfg.Depth); err != nil {
+		return SchemeEstimate{}, err
+	}
+	return cfg.estimate(scheme.Levels), nil
+}
+
+// MeasureHashCosts times hashLeaf on a chunkSize chunk and HashNodesFr on
+// this machine, for use as PlanConfig.LeafHashTime and NodeHashTime.
+func MeasureHashCosts(hashLeaf HashFuncFr, chunkSize int) (leaf, node time.Duration) {
+	chunk := make([]byte, chunkSize)
+	const leafSamples = 8
+	start := time.Now()
+	for i := 0; i < leafSamples; i++ {
+		hashLeaf(chunk)
+	}
+	leaf = time.Since(start) / leafSamples
+
+	const nodeSamples = 1000
+	h := hashLeaf(chunk)
+	start = time.Now()
+	for i := 0; i < nodeSamples; i++ {
+		h = HashNodesFr(h, h)
+	}
+	node = time.Since(start) / nodeSamples
+	return leaf, node
+}
+
+func (cfg PlanConfig) withDefaults() (PlanConfig, error) {
+	if cfg.Depth > 62 {
+		return cfg, fmt.Errorf("tree depth %d is too large", cfg.Depth)
+	}
+	if cfg.NumLeaves < 0 {
+		return cfg, fmt.Errorf("leaf count must be non-negative")
+	}
+	if err := validateLeafCapacity(cfg.Depth, cfg.NumLeaves); err != nil {
+		return cfg, err
+	}
+	if cfg.LeafHashTime <= 0 {
+		cfg.LeafHashTime = defaultLeafHashTime
+	}
+	if cfg.NodeHashTime <= 0 {
+		cfg.NodeHashTime = defaultNodeHashTime
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.NumCPU()
+	}
+	return cfg, nil
+}
+
+// levelBytes is the serialized size of checkpoint level lvl: its level
+// number, its entry count and one (index, hash) pair per entry.
+func (cfg PlanConfig) levelBytes(lvl int) int64 {
+	return 4 + 4 + 36*int64(levelSize(cfg.NumLeaves, lvl))
+}
+
+// subtreeEntries is the number of stored entries at level lo under one node
+// of level hi.
+func (cfg PlanConfig) subtreeEntries(lo, hi int) int {
+	n := levelSize(cfg.NumLeaves, lo)
+	if g := hi - lo; g < 62 && 1<<g < n {
+		n = 1 << g
+	}
+	return n
+}
+
+// gapTime predicts the time to rebuild the gap from level lo up to the
+// checkpoint at level hi. hashLeaves marks the bottom gap when level 0 is
+// not stored, whose base entries are hashed from chunk data.
+func (cfg PlanConfig) gapTime(lo, hi int, hashLeaves bool) time.Duration {
+	if lo == hi {
+		return 0
+	}
+	n := cfg.subtreeEntries(lo, hi)
+	if n == 0 {
+		return 0
+	}
+	nodes := time.Duration(n - 1 + hi - lo)
+	if !hashLeaves {
+		return nodes * cfg.NodeHashTime
+	}
+	rounds := time.Duration((n + cfg.Workers - 1) / cfg.Workers)
+	return rounds*cfg.LeafHashTime + nodes*cfg.NodeHashTime
+}
+
+// gapTimes returns every distinct predicted gap time, ascending.
+func (cfg PlanConfig) gapTimes() []time.Duration {
+	seen := make(map[time.Duration]bool)
+	var times []time.Duration
+	for lo := 0; lo <= cfg.Depth; lo++ {
+		for hi := lo; hi <= cfg.Depth; hi++ {
+			for _, t := range []time.Duration{cfg.gapTime(lo, hi, false), cfg.gapTime(lo, hi, true)} {
+				if !seen[t] {
+					seen[t] = true
+					times = append(times, t)
+				}
+			}
+		}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	return times
+}
+
+// minBytesWithin returns the smallest scheme whose gaps all rebuild within
+// limit, or nil if there is none. Levels are chosen by dynamic programming
+// over the last checkpoint level: best[l] is the smallest size of a scheme
+// prefix ending with a checkpoint at l.
+func (cfg PlanConfig) minBytesWithin(limit time.Duration) ([]int, int64) {
+	const header = 12 // depth, numLeaves, level count
+	best := make([]int64, cfg.Depth+1)
+	prev := make([]int, cfg.Depth+1)
+	for l := 0; l <= cfg.Depth; l++ {
+		best[l], prev[l] = math.MaxInt64, -1
+		if cfg.gapTime(0, l, true) <= limit {
+			best[l] = header + cfg.levelBytes(l)
+		}
+		for p := 0; p < l; p++ {
+			if best[p] == math.MaxInt64 || cfg.gapTime(p, l, false) > limit {
+				continue
+			}
+			if b := best[p] + cfg.levelBytes(l); b < best[l] {
+				best[l], prev[l] = b, p
+			}
+		}
+	}
+	if best[cfg.Depth] == math.MaxInt64 {
+		return nil, 0
+	}
+
+	var levels []int
+	for l := cfg.Depth; l >= 0; l = prev[l] {
+		levels = append([]int{l}, levels...)
+	}
+	return levels, best[cfg.Depth]
+}
+
+func (cfg PlanConfig) estimate(levels []int) SchemeEstimate {
+	est := SchemeEstimate{
+		Scheme: CheckpointScheme{Levels: append([]int(nil), levels...)},
+		Bytes:  12,
+	}
+	prev := 0
+	for i, lvl := range levels {
+		est.Bytes += cfg.levelBytes(lvl)
+		est.Rebuild = max(est.Rebuild, cfg.gapTime(prev, lvl, i == 0))
+		prev = lvl
+	}
+	return est
+}
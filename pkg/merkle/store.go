@@ -0,0 +1,190 @@
+package merkle
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// ---------------------------------------------------------------------------
+// Level storage
+// ---------------------------------------------------------------------------
+//
+// A LevelStore holds the node hashes of a fixed-depth sparse Merkle tree,
+// level by level, with the same shape as SparseMerkleTree.Levels: level l
+// has levelSize(numLeaves, l) entries and every position beyond them is the
+// zero subtree hash of that level. StoredSMT implements proofs and updates
+// on top of any LevelStore, so trees can live in RAM or in a memory-mapped
+// file (see MmapSMT) behind the same API.
+
+// LevelStore provides indexed access to the stored node hashes of a tree.
+// SetNode may be called concurrently for distinct (level, index) pairs.
+type LevelStore interface {
+	// LevelLen returns the number of stored entries at level.
+	LevelLen(level int) int
+	// Node returns the hash at (level, index), index < LevelLen(level).
+	Node(level, index int) fr.Element
+	// SetNode overwrites the hash at (level, index), index < LevelLen(level).
+	SetNode(level, index int, hash fr.Element)
+}
+
+// memLevels is the in-memory LevelStore backing SparseMerkleTree.
+type memLevels [][]fr.Element
+
+func (m memLevels) LevelLen(level int) int                 { return len(m[level]) }
+func (m memLevels) Node(level, index int) fr.Element       { return m[level][index] }
+func (m memLevels) SetNode(level, index int, h fr.Element) { m[level][index] = h }
+
+// StoredSMT is a fixed-depth sparse Merkle tree over an arbitrary LevelStore.
+// Real leaves occupy indices 0..NumLeaves-1.
+type StoredSMT struct {
+	Depth      int
+	NumLeaves  int
+	ZeroHashes []fr.Element
+	Store      LevelStore
+}
+
+// Stored returns a StoredSMT view sharing smt's in-memory levels. The view
+// is invalidated by Append, which may reallocate the levels.
+func (smt *SparseMerkleTree) Stored() *StoredSMT {
+	return &StoredSMT{
+		Depth:      smt.Depth,
+		NumLeaves:  smt.NumLeaves,
+		ZeroHashes: smt.ZeroHashes,
+		Store:      memLevels(smt.Levels),
+	}
+}
+
+// Root returns the root hash, or the zero subtree hash for an empty tree.
+func (t *StoredSMT) Root() fr.Element {
+	if t.Store.LevelLen(t.Depth) > 0 {
+		return t.Store.Node(t.Depth, 0)
+	}
+	return t.ZeroHashes[t.Depth]
+}
+
+// Info returns the root and shape of the tree.
+func (t *StoredSMT) Info() TreeInfo {
+	return TreeInfo{Root: t.Root(), Depth: t.Depth, NumLeaves: t.NumLeaves}
+}
+
+// GetProof returns a fixed-size Merkle proof for the leaf at the given index.
+func (t *StoredSMT) GetProof(leafIndex int) ([]fr.Element, []int) {
+	siblings := make([]fr.Element, t.Depth)
+	directions := make([]int, t.Depth)
+
+	idx := leafIndex
+	for lvl := 0; lvl < t.Depth; lvl++ {
+		var sibIdx int
+		if idx%2 == 0 {
+			sibIdx = idx + 1
+			directions[lvl] = 0
+		} else {
+			sibIdx = idx - 1
+			directions[lvl] = 1
+		}
+
+		if sibIdx >= 0 && sibIdx < t.Store.LevelLen(lvl) {
+			siblings[lvl] = t.Store.Node(lvl, sibIdx)
+		} else {
+			siblings[lvl] = t.ZeroHashes[lvl]
+		}
+
+		idx /= 2
+	}
+
+	return siblings, directions
+}
+
+// GetLeafHash returns the hash at the given leaf index, using the zero leaf
+// hash for positions beyond the real leaves.
+func (t *StoredSMT) GetLeafHash(leafIndex int) fr.Element {
+	if leafIndex >= 0 && leafIndex < t.Store.LevelLen(0) {
+		return t.Store.Node(0, leafIndex)
+	}
+	return t.ZeroHashes[0]
+}
+
+// UpdateLeaf replaces the hash of real leaf leafIndex and rehashes its path
+// to the root.
+func (t *StoredSMT) UpdateLeaf(leafIndex int, leafHash fr.Element) error {
+	return t.UpdateLeaves(map[int]fr.Element{leafIndex: leafHash})
+}
+
+// UpdateLeaves replaces the hashes of the given real leaves and rehashes only
+// the nodes on their paths, each shared ancestor once. Levels with more dirty
+// parents than parallelBuildThreshold are rehashed by parallel workers.
+// Indices must be in [0, NumLeaves). On error the tree is left unchanged.
+func (t *StoredSMT) UpdateLeaves(updates map[int]fr.Element) error {
+	if len(updates) == 0 {
+		return nil
+	}
+	dirty := make([]int, 0, len(updates))
+	for idx := range updates {
+		if idx < 0 || idx >= t.NumLeaves {
+			return fmt.Errorf("leaf index %d out of range [0, %d)", idx, t.NumLeaves)
+		}
+		dirty = append(dirty, idx)
+	}
+	sort.Ints(dirty)
+
+	for _, idx := range dirty {
+		t.Store.SetNode(0, idx, updates[idx])
+	}
+
+	for lvl := 0; lvl < t.Depth; lvl++ {
+		// Parents of sorted children are sorted; drop duplicates in place.
+		parents := dirty[:0]
+		for _, idx := range dirty {
+			p := idx / 2
+			if len(parents) == 0 || parents[len(parents)-1] != p {
+				parents = append(parents, p)
+			}
+		}
+		rehashParents(t.Store, lvl, t.ZeroHashes[lvl], parents)
+		dirty = parents
+	}
+	return nil
+}
+
+// rehashParents recomputes the level+1 entry of every p in parents from its
+// two children at level, using parallel workers for more than
+// parallelBuildThreshold parents.
+func rehashParents(store LevelStore, level int, zh fr.Element, parents []int) {
+	curLen := store.LevelLen(level)
+	hashParent := func(p int) {
+		right := zh
+		if 2*p+1 < curLen {
+			right = store.Node(level, 2*p+1)
+		}
+		store.SetNode(level+1, p, HashNodesFr(store.Node(level, 2*p), right))
+	}
+
+	numWorkers := runtime.NumCPU()
+	if len(parents) <= parallelBuildThreshold || numWorkers < 2 {
+		for _, p := range parents {
+			hashParent(p)
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	work := make(chan int, len(parents))
+	for w := 0; w < numWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range work {
+				hashParent(p)
+			}
+		}()
+	}
+	for _, p := range parents {
+		work <- p
+	}
+	close(work)
+	wg.Wait()
+}
@@ -0,0 +1,174 @@
+package merkle
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// ---------------------------------------------------------------------------
+// Streaming construction
+// ---------------------------------------------------------------------------
+//
+// The builders below read a file from an io.Reader instead of a pre-split
+// [][]byte. Chunks are read into a small pool of reusable buffers and hashed
+// by a worker pool as they arrive, so only the 32-byte leaf hashes are kept.
+// Chunking matches SplitIntoChunks: the last chunk is zero-padded and an
+// empty input yields a single zero chunk.
+
+// GenerateSparseMerkleTreeFromReader builds the same tree as
+// GenerateSparseMerkleTree(SplitIntoChunks(data, chunkSize), ...) while
+// holding at most a few chunks of r in memory at a time.
+func GenerateSparseMerkleTreeFromReader(r io.Reader, chunkSize, depth int, hashLeaf HashFuncFr, zeroLeafHash fr.Element) (*SparseMerkleTree, error) {
+	leafHashes, err := hashChunksFromReader(r, chunkSize, depth, hashLeaf)
+	if err != nil {
+		return nil, err
+	}
+	return BuildSMTFromLeafHashes(leafHashes, depth, zeroLeafHash)
+}
+
+// GenerateCheckpointedSMTFromReader streams r like
+// GenerateSparseMerkleTreeFromReader but keeps only the levels in scheme,
+// discarding every other level as soon as its parents are built. The result
+// is identical to building the full tree and saving it with
+// SaveCheckpointed; call Save on it to persist it.
+func GenerateCheckpointedSMTFromReader(r io.Reader, chunkSize, depth int, hashLeaf HashFuncFr, zeroLeafHash fr.Element, scheme CheckpointScheme) (*CheckpointedSMT, error) {
+	if err := validateScheme(scheme, depth); err != nil {
+		return nil, err
+	}
+	leafHashes, err := hashChunksFromReader(r, chunkSize, depth, hashLeaf)
+	if err != nil {
+		return nil, err
+	}
+
+	zeroHashes := PrecomputeZeroHashes(depth, zeroLeafHash)
+	keep := make(map[int]bool, len(scheme.Levels))
+	for _, lvl := range scheme.Levels {
+		keep[lvl] = true
+	}
+
+	levels := make(map[int][]fr.Element, len(scheme.Levels))
+	cur := leafHashes
+	for lvl := 0; ; lvl++ {
+		if keep[lvl] {
+			levels[lvl] = cur
+		}
+		if lvl == depth {
+			break
+		}
+		next := make([]fr.Element, (len(cur)+1)/2)
+		hashLevel(cur, next, zeroHashes[lvl])
+		cur = next
+	}
+
+	return &CheckpointedSMT{
+		Root:       cur[0],
+		Depth:      depth,
+		NumLeaves:  len(leafHashes),
+		Scheme:     scheme,
+		Levels:     levels,
+		ZeroHashes: zeroHashes,
+	}, nil
+}
+
+// hashChunksFromReader reads r in chunkSize chunks and returns their leaf
+// hashes in order. It fails once the chunk count exceeds the capacity of a
+// depth-deep tree.
+func hashChunksFromReader(r io.Reader, chunkSize, depth int, hashLeaf HashFuncFr) ([]fr.Element, error) {
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("chunk size must be positive, got %d", chunkSize)
+	}
+	if _, err := maxLeavesForDepth(depth); err != nil {
+		return nil, err
+	}
+
+	type job struct {
+		idx int
+		buf []byte
+	}
+	type result struct {
+		idx  int
+		hash fr.Element
+		buf  []byte
+	}
+
+	numWorkers := runtime.NumCPU()
+
+	// Buffers circulate reader → worker → collector → reader, so at most
+	// len(free) chunks are resident at once.
+	free := make(chan []byte, 2*numWorkers)
+	for i := 0; i < cap(free); i++ {
+		free <- make([]byte, chunkSize)
+	}
+	jobs := make(chan job, numWorkers)
+	results := make(chan result, numWorkers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < numWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				results <- result{idx: j.idx, hash: hashLeaf(j.buf), buf: j.buf}
+			}
+		}()
+	}
+
+	// Only the collector touches leafHashes until results is closed.
+	var leafHashes []fr.Element
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for res := range results {
+			if res.idx >= len(leafHashes) {
+				leafHashes = append(leafHashes, make([]fr.Element, res.idx+1-len(leafHashes))...)
+			}
+			leafHashes[res.idx] = res.hash
+			free <- res.buf
+		}
+	}()
+
+	numLeaves := 0
+	var readErr error
+	for {
+		buf := <-free
+		n, err := io.ReadFull(r, buf)
+		last := false
+		switch {
+		case err == nil:
+		case errors.Is(err, io.EOF) && numLeaves > 0:
+			// Input ended on a chunk boundary.
+		case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+			// Zero-pad the final (or only, for empty input) chunk.
+			clear(buf[n:])
+			last = true
+		default:
+			readErr = fmt.Errorf("read chunk %d: %w", numLeaves, err)
+		}
+		if readErr != nil || (err != nil && !last) {
+			break
+		}
+		if readErr = validateLeafCapacity(depth, numLeaves+1); readErr != nil {
+			break
+		}
+		jobs <- job{idx: numLeaves, buf: buf}
+		numLeaves++
+		if last {
+			break
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+	close(results)
+	<-collected
+
+	if readErr != nil {
+		return nil, readErr
+	}
+	return leafHashes[:numLeaves], nil
+}
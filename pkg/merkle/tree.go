@@ -0,0 +1,92 @@
+package merkle
+
+import (
+	"math/big"
+	"math/bits"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// ---------------------------------------------------------------------------
+// Unified tree API
+// ---------------------------------------------------------------------------
+//
+// Every tree in this package has the shape the circuits verify: a binary
+// tree of fixed depth whose leaves are hashed by the caller's HashFuncFr,
+// whose inner nodes are hashed with HashNodesFr (DomainTagNode), and whose
+// positions past the real leaves hold the zero leaf hash. A proof from any
+// Tree therefore verifies in a circuit instantiated at the tree's depth.
+//
+// A dense tree is such a tree at the smallest depth that fits its leaves;
+// a sparse tree uses a fixed depth (e.g. a circuit's MaxTreeDepth) so files
+// of any size share one circuit.
+//
+// Migrating from the removed *big.Int MerkleTree:
+//   - GenerateMerkleTree(chunks, chunkSize, hashLeaf) becomes
+//     GenerateDenseMerkleTree(chunks, LeafHashFr(hashLeaf), zeroLeafHash), or
+//     GenerateSparseMerkleTree at the circuit depth. Padding positions now
+//     hold the zero leaf hash instead of repeated chunks, so roots change.
+//   - GetRoot becomes Info().Root (RootBigInt for a *big.Int).
+//   - GetMerkleProof becomes GetProof. Siblings still run from the leaf up;
+//     direction 0 (sibling on the right) replaces the old true.
+//   - VerifyMerkleProof becomes VerifyProof.
+
+// Tree is a fixed-depth Merkle tree that serves circuit-compatible proofs.
+// It is implemented by *SparseMerkleTree (dense or sparse), *StoredSMT and
+// *MmapSMT.
+type Tree interface {
+	Info() TreeInfo
+	// GetProof returns Depth siblings from the leaf up and the leaf's
+	// position at each level (0 = left child, 1 = right child).
+	GetProof(leafIndex int) ([]fr.Element, []int)
+	GetLeafHash(leafIndex int) fr.Element
+}
+
+// HashFunc is the *big.Int leaf hash signature of the removed MerkleTree API.
+//
+// Deprecated: use HashFuncFr, or wrap an existing HashFunc with LeafHashFr.
+type HashFunc func(chunk []byte) *big.Int
+
+// LeafHashFr adapts a *big.Int leaf hash function to HashFuncFr.
+func LeafHashFr(hashLeaf HashFunc) HashFuncFr {
+	return func(chunk []byte) fr.Element {
+		var h fr.Element
+		h.SetBigInt(hashLeaf(chunk))
+		return h
+	}
+}
+
+// DenseDepth returns the smallest tree depth holding numLeaves leaves. It is
+// at least 1, so every leaf has a non-empty proof.
+func DenseDepth(numLeaves int) int {
+	if numLeaves <= 2 {
+		return 1
+	}
+	return bits.Len(uint(numLeaves - 1))
+}
+
+// GenerateDenseMerkleTree builds the tree of chunks at DenseDepth, padding
+// the last level with zeroLeafHash.
+func GenerateDenseMerkleTree(chunks [][]byte, hashLeaf HashFuncFr, zeroLeafHash fr.Element) (*SparseMerkleTree, error) {
+	return GenerateSparseMerkleTree(chunks, DenseDepth(len(chunks)), hashLeaf, zeroLeafHash)
+}
+
+// VerifyProof checks a GetProof proof for leafHash against root, hashing in
+// the same order as the circuits.
+func VerifyProof(leafHash fr.Element, siblings []fr.Element, directions []int, root fr.Element) bool {
+	if len(siblings) != len(directions) {
+		return false
+	}
+	cur := leafHash
+	for lvl, sib := range siblings {
+		switch directions[lvl] {
+		case 0:
+			cur = HashNodesFr(cur, sib)
+		case 1:
+			cur = HashNodesFr(sib, cur)
+		default:
+			return false
+		}
+	}
+	return cur == root
+}
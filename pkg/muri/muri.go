@@ -0,0 +1,155 @@
+// Package muri implements the native (out-of-circuit) Archive MURI sealing
+// transform and its inverse.
+//
+// The archive is viewed as a flat sequence of N = numChunks *
+// ElementsPerChunk field elements. Sealing runs two sequential passes:
+//
+//	Pass 1 (L→R):  enc1[j] = orig[j] + key1[j]
+//	  key1[0] = H(KeySeed1, r)
+//	  key1[j] = H(KeyElem1, enc1[j-1], enc1[bp1_0(j)], enc1[bp1_1(j)], r)
+//
+//	Pass 2 (R→L):  enc2[j] = enc1[j] + key2[j]
+//	  key2[N-1] = H(KeySeed2, r)
+//	  key2[j]   = H(KeyElem2, enc2[j+1], enc2[bp2_0(j)], enc2[bp2_1(j)], r)
+//
+// Back-pointers are derived by bit-slicing seed = H(BackPtrX, j, r) into
+// PointerWindowBits-bit windows and reducing each window modulo the number
+// of eligible positions, which never exceeds BackPointerWindow:
+//
+//	bp1_i(j) = j - 1 - (window_i mod min(j, W))        ∈ [j-W, j-1]
+//	bp2_i(j) = j + 1 + (window_i mod min(N-1-j, W))    ∈ [j+1, j+W]
+//
+// The sealed replica is enc2. Because every key depends only on elements at
+// most BackPointerWindow positions away, both directions stream through
+// chunk storage holding a single window of elements in memory.
+package muri
+
+import (
+	"encoding/binary"
+
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+const (
+	FileSize         = 16 * 1024                                       // 16 KB chunk size (must match PoI)
+	ElementSize      = 31                                              // bytes per field element (must match PoI)
+	ElementsPerChunk = int((FileSize + ElementSize - 1) / ElementSize) // 529 — field elements per chunk
+
+	BackPointerCount  = 2       // back-pointers per element and pass
+	BackPointerWindow = 1 << 16 // back-pointers reach at most this many elements away
+	PointerWindowBits = 64      // seed bits consumed per back-pointer
+)
+
+// PointerReduction holds the modular reduction of each back-pointer window:
+// window_i = Quotients[i] * Modulus + Remainders[i]. The circuit re-checks
+// this reduction, so witness builders need the full decomposition.
+type PointerReduction struct {
+	Modulus    uint64
+	Quotients  [BackPointerCount]uint64
+	Remainders [BackPointerCount]uint64
+}
+
+// SeedWindows slices seed into BackPointerCount windows of PointerWindowBits
+// bits, least significant first: window i covers bits [64i, 64i+64).
+func SeedWindows(seed fr.Element) [BackPointerCount]uint64 {
+	b := seed.Bytes()
+	var w [BackPointerCount]uint64
+	for i := range w {
+		end := len(b) - 8*i
+		w[i] = binary.BigEndian.Uint64(b[end-8 : end])
+	}
+	return w
+}
+
+// reducePointers derives the back-pointer seed for element j with the given
+// domain tag (the native equivalent of crypto.DeriveBackPointers) and
+// reduces each window modulo modulus.
+func reducePointers(tag, j int, r fr.Element, modulus int) PointerReduction {
+	var jFr fr.Element
+	jFr.SetUint64(uint64(j))
+	windows := SeedWindows(crypto.SpongeHashFr(tag, jFr, r))
+
+	red := PointerReduction{Modulus: uint64(modulus)}
+	for i, w := range windows {
+		red.Quotients[i] = w / red.Modulus
+		red.Remainders[i] = w % red.Modulus
+	}
+	return red
+}
+
+// Pass1Pointers returns the pass-1 back-pointers of element j. Element 0 has
+// no predecessors; its pointers are all 0 and unused (key1[0] is the seed).
+func Pass1Pointers(j int, r fr.Element) ([BackPointerCount]int, PointerReduction) {
+	modulus := 1
+	if j > 0 {
+		modulus = min(j, BackPointerWindow)
+	}
+	red := reducePointers(crypto.DomainTagBackPtr1, j, r, modulus)
+
+	var pos [BackPointerCount]int
+	if j > 0 {
+		for i, rem := range red.Remainders {
+			pos[i] = j - 1 - int(rem)
+		}
+	}
+	return pos, red
+}
+
+// Pass2Pointers returns the pass-2 back-pointers of element j in a sequence
+// of n elements. Element n-1 has no successors; its pointers all equal j and
+// are unused (key2[n-1] is the seed).
+func Pass2Pointers(j, n int, r fr.Element) ([BackPointerCount]int, PointerReduction) {
+	last := j == n-1
+	modulus := 1
+	if !last {
+		modulus = min(n-1-j, BackPointerWindow)
+	}
+	red := reducePointers(crypto.DomainTagBackPtr2, j, r, modulus)
+
+	var pos [BackPointerCount]int
+	for i, rem := range red.Remainders {
+		if last {
+			pos[i] = j
+		} else {
+			pos[i] = j + 1 + int(rem)
+		}
+	}
+	return pos, red
+}
+
+// KeySeed1 returns key1[0] = H(KeySeed1, r).
+func KeySeed1(r fr.Element) fr.Element {
+	return crypto.SpongeHashFr(crypto.DomainTagKeySeed1, r)
+}
+
+// KeySeed2 returns key2[N-1] = H(KeySeed2, r).
+func KeySeed2(r fr.Element) fr.Element {
+	return crypto.SpongeHashFr(crypto.DomainTagKeySeed2, r)
+}
+
+// Key1 computes key1[j] for j > 0 (crypto.DeriveKeyElem1). enc1 returns the
+// pass-1 encoding of any position in [j-BackPointerWindow, j-1].
+func Key1(j int, r fr.Element, enc1 func(int) fr.Element) fr.Element {
+	ptrs, _ := Pass1Pointers(j, r)
+	inputs := make([]fr.Element, 0, BackPointerCount+2)
+	inputs = append(inputs, enc1(j-1))
+	for _, p := range ptrs {
+		inputs = append(inputs, enc1(p))
+	}
+	inputs = append(inputs, r)
+	return crypto.SpongeHash(crypto.DomainTagKeyElem1, inputs)
+}
+
+// Key2 computes key2[j] for j < n-1 (crypto.DeriveKeyElem2). sealed returns
+// the sealed value of any position in [j+1, j+BackPointerWindow].
+func Key2(j, n int, r fr.Element, sealed func(int) fr.Element) fr.Element {
+	ptrs, _ := Pass2Pointers(j, n, r)
+	inputs := make([]fr.Element, 0, BackPointerCount+2)
+	inputs = append(inputs, sealed(j+1))
+	for _, p := range ptrs {
+		inputs = append(inputs, sealed(p))
+	}
+	inputs = append(inputs, r)
+	return crypto.SpongeHash(crypto.DomainTagKeyElem2, inputs)
+}
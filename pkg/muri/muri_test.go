@@ -0,0 +1,246 @@
+package muri_test
+
+import (
+	"context"
+	"crypto/rand"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/MuriData/muri-zkproof/pkg/merkle"
+	"github.com/MuriData/muri-zkproof/pkg/muri"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// randomChunks returns n random FileSize-byte chunks.
+func randomChunks(t *testing.T, n int) [][]byte {
+	t.Helper()
+	data := make([]byte, n*muri.FileSize)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatalf("generate random data: %v", err)
+	}
+	return merkle.SplitIntoChunks(data, muri.FileSize)
+}
+
+// randomR returns a random replica randomness.
+func randomR(t *testing.T) fr.Element {
+	t.Helper()
+	var r fr.Element
+	if _, err := r.SetRandom(); err != nil {
+		t.Fatalf("generate r: %v", err)
+	}
+	return r
+}
+
+// referenceSeal is a direct, whole-sequence transcription of the sealing
+// specification using the big.Int crypto helpers.
+func referenceSeal(orig []fr.Element, r fr.Element) []fr.Element {
+	n := len(orig)
+	rBig := new(big.Int)
+	r.BigInt(rBig)
+	mask := new(big.Int).SetUint64(^uint64(0))
+
+	pointers := func(tag, j, modulus int) []int {
+		seed := crypto.DeriveBackPointers(tag, big.NewInt(int64(j)), rBig)
+		out := make([]int, muri.BackPointerCount)
+		for i := range out {
+			w := new(big.Int).Rsh(seed, uint(i*muri.PointerWindowBits))
+			w.And(w, mask)
+			out[i] = int(new(big.Int).Mod(w, big.NewInt(int64(modulus))).Int64())
+		}
+		return out
+	}
+	toBig := func(e fr.Element) *big.Int {
+		b := new(big.Int)
+		e.BigInt(b)
+		return b
+	}
+
+	enc := make([]fr.Element, n)
+	for j := 0; j < n; j++ {
+		var key fr.Element
+		if j == 0 {
+			key.SetBigInt(crypto.DeriveKeySeed1(rBig))
+		} else {
+			inputs := []*big.Int{toBig(enc[j-1])}
+			for _, rem := range pointers(crypto.DomainTagBackPtr1, j, min(j, muri.BackPointerWindow)) {
+				inputs = append(inputs, toBig(enc[j-1-rem]))
+			}
+			key.SetBigInt(crypto.DeriveKeyElem1(append(inputs, rBig)))
+		}
+		enc[j].Add(&orig[j], &key)
+	}
+	for j := n - 1; j >= 0; j-- {
+		var key fr.Element
+		if j == n-1 {
+			key.SetBigInt(crypto.DeriveKeySeed2(rBig))
+		} else {
+			inputs := []*big.Int{toBig(enc[j+1])}
+			for _, rem := range pointers(crypto.DomainTagBackPtr2, j, min(n-1-j, muri.BackPointerWindow)) {
+				inputs = append(inputs, toBig(enc[j+1+rem]))
+			}
+			key.SetBigInt(crypto.DeriveKeyElem2(append(inputs, rBig)))
+		}
+		enc[j].Add(&enc[j], &key)
+	}
+	return enc
+}
+
+// TestSealMatchesReference checks the streaming sealer against the
+// specification and the sealed tree against its leaves.
+func TestSealMatchesReference(t *testing.T) {
+	chunks := randomChunks(t, 2)
+	r := randomR(t)
+
+	sealed := muri.NewMemoryStore(len(chunks))
+	tree, err := muri.Seal(muri.NewByteElementReader(chunks), sealed, r, 20)
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+
+	var orig []fr.Element
+	for _, c := range chunks {
+		orig = append(orig, crypto.ChunkToElements(c, muri.ElementSize, muri.ElementsPerChunk)...)
+	}
+	want := referenceSeal(orig, r)
+	got := sealed.Elements()
+	for j := range want {
+		if !got[j].Equal(&want[j]) {
+			t.Fatalf("sealed element %d mismatch", j)
+		}
+	}
+
+	for c := range chunks {
+		leaf := muri.HashSealedChunk(got[c*muri.ElementsPerChunk : (c+1)*muri.ElementsPerChunk])
+		treeLeaf := tree.GetLeafHash(c)
+		if !leaf.Equal(&treeLeaf) {
+			t.Fatalf("sealed tree leaf %d mismatch", c)
+		}
+	}
+	if tree.NumLeaves != len(chunks) {
+		t.Fatalf("sealed tree has %d leaves, want %d", tree.NumLeaves, len(chunks))
+	}
+}
+
+// TestSealUnsealRoundTrip seals and unseals sequences shorter and longer
+// than BackPointerWindow and recovers the original bytes.
+func TestSealUnsealRoundTrip(t *testing.T) {
+	for _, numChunks := range []int{1, 3, 130} {
+		chunks := randomChunks(t, numChunks)
+		r := randomR(t)
+
+		sealed := muri.NewMemoryStore(numChunks)
+		if _, err := muri.Seal(muri.NewByteElementReader(chunks), sealed, r, 20); err != nil {
+			t.Fatalf("%d chunks: seal: %v", numChunks, err)
+		}
+
+		unsealed := muri.NewMemoryStore(numChunks)
+		if err := muri.Unseal(sealed, unsealed, r); err != nil {
+			t.Fatalf("%d chunks: unseal: %v", numChunks, err)
+		}
+		for c := range chunks {
+			elems, err := unsealed.ReadChunk(c)
+			if err != nil {
+				t.Fatalf("%d chunks: read chunk %d: %v", numChunks, c, err)
+			}
+			data, err := muri.ChunkFromElements(elems)
+			if err != nil {
+				t.Fatalf("%d chunks: chunk %d: %v", numChunks, c, err)
+			}
+			if string(data) != string(chunks[c]) {
+				t.Fatalf("%d chunks: chunk %d does not round-trip", numChunks, c)
+			}
+		}
+	}
+}
+
+// TestFileStore seals from a merkle.ChunkStore and into a file-backed store
+// and checks both against the in-memory result.
+func TestFileStore(t *testing.T) {
+	chunks := randomChunks(t, 4)
+	r := randomR(t)
+
+	mem := muri.NewMemoryStore(len(chunks))
+	memTree, err := muri.Seal(muri.NewByteElementReader(chunks), mem, r, 20)
+	if err != nil {
+		t.Fatalf("seal to memory: %v", err)
+	}
+
+	// Sealing from a merkle.ChunkStore over the original file matches.
+	origPath := filepath.Join(t.TempDir(), "orig.bin")
+	var orig []byte
+	for _, chunk := range chunks {
+		orig = append(orig, chunk...)
+	}
+	if err := os.WriteFile(origPath, orig, 0o644); err != nil {
+		t.Fatalf("write original file: %v", err)
+	}
+	origStore, err := merkle.OpenFileChunkStore(origPath, muri.FileSize)
+	if err != nil {
+		t.Fatalf("open original store: %v", err)
+	}
+	defer origStore.Close()
+	src, err := muri.NewStoreElementReader(context.Background(), origStore)
+	if err != nil {
+		t.Fatalf("new store element reader: %v", err)
+	}
+	storeTree, err := muri.Seal(src, muri.NewMemoryStore(len(chunks)), r, 20)
+	if err != nil {
+		t.Fatalf("seal from chunk store: %v", err)
+	}
+	if !storeTree.Root.Equal(&memTree.Root) {
+		t.Fatal("chunk-store sealed root differs from in-memory root")
+	}
+	halfStore, err := merkle.OpenFileChunkStore(origPath, muri.FileSize/2)
+	if err != nil {
+		t.Fatalf("open half-size store: %v", err)
+	}
+	defer halfStore.Close()
+	if _, err := muri.NewStoreElementReader(context.Background(), halfStore); err == nil {
+		t.Fatal("expected chunk size mismatch error")
+	}
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "replica.bin"))
+	if err != nil {
+		t.Fatalf("create replica file: %v", err)
+	}
+	defer f.Close()
+
+	disk := muri.NewFileStore(f, len(chunks))
+	diskTree, err := muri.Seal(muri.NewByteElementReader(chunks), disk, r, 20)
+	if err != nil {
+		t.Fatalf("seal to file: %v", err)
+	}
+	if !diskTree.Root.Equal(&memTree.Root) {
+		t.Fatal("file-backed sealed root differs from in-memory root")
+	}
+
+	unsealed := muri.NewMemoryStore(len(chunks))
+	if err := muri.Unseal(disk, unsealed, r); err != nil {
+		t.Fatalf("unseal from file: %v", err)
+	}
+	elems, err := unsealed.ReadChunk(len(chunks) - 1)
+	if err != nil {
+		t.Fatalf("read chunk: %v", err)
+	}
+	data, err := muri.ChunkFromElements(elems)
+	if err != nil {
+		t.Fatalf("chunk from elements: %v", err)
+	}
+	if string(data) != string(chunks[len(chunks)-1]) {
+		t.Fatal("last chunk does not round-trip through file store")
+	}
+}
+
+// TestSealRejectsMismatchedStores checks store size validation.
+func TestSealRejectsMismatchedStores(t *testing.T) {
+	chunks := randomChunks(t, 2)
+	if _, err := muri.Seal(muri.NewByteElementReader(chunks), muri.NewMemoryStore(3), randomR(t), 20); err == nil {
+		t.Fatal("expected error for mismatched destination size")
+	}
+	if _, err := muri.Seal(muri.NewByteElementReader(nil), muri.NewMemoryStore(0), randomR(t), 20); err == nil {
+		t.Fatal("expected error for empty source")
+	}
+}
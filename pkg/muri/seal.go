@@ -0,0 +1,176 @@
+package muri
+
+import (
+	"fmt"
+
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/MuriData/muri-zkproof/pkg/merkle"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// window is a ring buffer holding the BackPointerWindow most recently
+// processed elements of a pass. Every key input lies within that distance,
+// so a pass never needs more of the sequence in memory.
+type window []fr.Element
+
+func newWindow() window {
+	return make(window, BackPointerWindow)
+}
+
+func (w window) get(j int) fr.Element {
+	return w[j&(BackPointerWindow-1)]
+}
+
+func (w window) set(j int, v fr.Element) {
+	w[j&(BackPointerWindow-1)] = v
+}
+
+// checkStores validates that src and dst describe the same non-empty
+// sequence and returns its element count.
+func checkStores(src ElementReader, dst ElementStore) (int, error) {
+	numChunks := src.NumChunks()
+	if numChunks == 0 {
+		return 0, fmt.Errorf("source has no chunks")
+	}
+	if dst.NumChunks() != numChunks {
+		return 0, fmt.Errorf("destination has %d chunks, source has %d", dst.NumChunks(), numChunks)
+	}
+	return numChunks * ElementsPerChunk, nil
+}
+
+// Seal applies both MURI passes to the original elements in src under the
+// replica randomness r, writing the sealed replica to dst, and returns the
+// sealed sparse Merkle tree of the given depth.
+//
+// Pass 1 streams src left to right into dst; pass 2 then streams dst right
+// to left, overwriting each chunk with its sealed value. Memory use is one
+// BackPointerWindow of elements plus one leaf hash per chunk, independent of
+// the replica size.
+func Seal(src ElementReader, dst ElementStore, r fr.Element, depth int) (*merkle.SparseMerkleTree, error) {
+	n, err := checkStores(src, dst)
+	if err != nil {
+		return nil, err
+	}
+	numChunks := n / ElementsPerChunk
+
+	// Pass 1 (L→R): enc1[j] = orig[j] + key1[j].
+	enc1 := newWindow()
+	seed1 := KeySeed1(r)
+	for c := 0; c < numChunks; c++ {
+		chunk, err := src.ReadChunk(c)
+		if err != nil {
+			return nil, fmt.Errorf("pass 1: %w", err)
+		}
+		for o := 0; o < ElementsPerChunk; o++ {
+			j := c*ElementsPerChunk + o
+			key := seed1
+			if j > 0 {
+				key = Key1(j, r, enc1.get)
+			}
+			chunk[o].Add(&chunk[o], &key)
+			enc1.set(j, chunk[o])
+		}
+		if err := dst.WriteChunk(c, chunk); err != nil {
+			return nil, fmt.Errorf("pass 1: %w", err)
+		}
+	}
+
+	// Pass 2 (R→L): enc2[j] = enc1[j] + key2[j].
+	enc2 := enc1 // pass 1 is done; reuse its buffer
+	seed2 := KeySeed2(r)
+	leafHashes := make([]fr.Element, numChunks)
+	for c := numChunks - 1; c >= 0; c-- {
+		chunk, err := dst.ReadChunk(c)
+		if err != nil {
+			return nil, fmt.Errorf("pass 2: %w", err)
+		}
+		for o := ElementsPerChunk - 1; o >= 0; o-- {
+			j := c*ElementsPerChunk + o
+			key := seed2
+			if j < n-1 {
+				key = Key2(j, n, r, enc2.get)
+			}
+			chunk[o].Add(&chunk[o], &key)
+			enc2.set(j, chunk[o])
+		}
+		if err := dst.WriteChunk(c, chunk); err != nil {
+			return nil, fmt.Errorf("pass 2: %w", err)
+		}
+		leafHashes[c] = HashSealedChunk(chunk)
+	}
+
+	tree, err := merkle.BuildSMTFromLeafHashes(leafHashes, depth, ZeroLeafHash())
+	if err != nil {
+		return nil, fmt.Errorf("build sealed tree: %w", err)
+	}
+	return tree, nil
+}
+
+// Unseal inverts Seal: it reads the sealed replica from src and writes the
+// original elements to dst. Pass 2 is undone right to left (key2 depends
+// only on sealed values), then pass 1 left to right over dst.
+func Unseal(src ElementReader, dst ElementStore, r fr.Element) error {
+	n, err := checkStores(src, dst)
+	if err != nil {
+		return err
+	}
+	numChunks := n / ElementsPerChunk
+
+	// Undo pass 2 (R→L): enc1[j] = enc2[j] - key2[j].
+	sealed := newWindow()
+	seed2 := KeySeed2(r)
+	for c := numChunks - 1; c >= 0; c-- {
+		chunk, err := src.ReadChunk(c)
+		if err != nil {
+			return fmt.Errorf("unseal pass 2: %w", err)
+		}
+		for o := ElementsPerChunk - 1; o >= 0; o-- {
+			j := c*ElementsPerChunk + o
+			key := seed2
+			if j < n-1 {
+				key = Key2(j, n, r, sealed.get)
+			}
+			sealed.set(j, chunk[o])
+			chunk[o].Sub(&chunk[o], &key)
+		}
+		if err := dst.WriteChunk(c, chunk); err != nil {
+			return fmt.Errorf("unseal pass 2: %w", err)
+		}
+	}
+
+	// Undo pass 1 (L→R): orig[j] = enc1[j] - key1[j].
+	enc1 := sealed // pass 2 is undone; reuse its buffer
+	seed1 := KeySeed1(r)
+	for c := 0; c < numChunks; c++ {
+		chunk, err := dst.ReadChunk(c)
+		if err != nil {
+			return fmt.Errorf("unseal pass 1: %w", err)
+		}
+		for o := 0; o < ElementsPerChunk; o++ {
+			j := c*ElementsPerChunk + o
+			key := seed1
+			if j > 0 {
+				key = Key1(j, r, enc1.get)
+			}
+			enc1.set(j, chunk[o])
+			chunk[o].Sub(&chunk[o], &key)
+		}
+		if err := dst.WriteChunk(c, chunk); err != nil {
+			return fmt.Errorf("unseal pass 1: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// HashSealedChunk hashes one sealed chunk (ElementsPerChunk full field
+// elements) into its replica tree leaf: H(DomainTagReal, elems...).
+func HashSealedChunk(elems []fr.Element) fr.Element {
+	return crypto.SpongeHash(crypto.DomainTagReal, elems)
+}
+
+// ZeroLeafHash returns the padding leaf of sealed replica trees, identical
+// to the padding leaf of the original file trees.
+func ZeroLeafHash() fr.Element {
+	return crypto.ComputeZeroLeafHashFr(ElementSize, ElementsPerChunk)
+}
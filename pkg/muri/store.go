@@ -0,0 +1,224 @@
+package muri
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/MuriData/muri-zkproof/pkg/crypto"
+	"github.com/MuriData/muri-zkproof/pkg/merkle"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// ElementReader provides random read access to a sequence of chunks, each
+// ElementsPerChunk field elements long. Raw byte chunks, such as those of a
+// merkle.ChunkStore, are adapted with NewByteElementReader or
+// NewStoreElementReader.
+type ElementReader interface {
+	NumChunks() int
+	ReadChunk(index int) ([]fr.Element, error)
+}
+
+// ElementStore is an ElementReader that can also overwrite chunks. Sealing
+// and unsealing use the destination store for their intermediate pass, so
+// it must return previously written chunks.
+type ElementStore interface {
+	ElementReader
+	WriteChunk(index int, elems []fr.Element) error
+}
+
+// ---------------------------------------------------------------------------
+// In-memory store
+// ---------------------------------------------------------------------------
+
+// MemoryStore is an ElementStore backed by a flat element slice.
+type MemoryStore struct {
+	elems []fr.Element
+}
+
+// NewMemoryStore allocates a zeroed store of numChunks chunks.
+func NewMemoryStore(numChunks int) *MemoryStore {
+	return &MemoryStore{elems: make([]fr.Element, numChunks*ElementsPerChunk)}
+}
+
+// NumChunks returns the number of chunks in the store.
+func (m *MemoryStore) NumChunks() int {
+	return len(m.elems) / ElementsPerChunk
+}
+
+// ReadChunk returns a copy of chunk index.
+func (m *MemoryStore) ReadChunk(index int) ([]fr.Element, error) {
+	if index < 0 || index >= m.NumChunks() {
+		return nil, fmt.Errorf("chunk index %d out of range [0, %d)", index, m.NumChunks())
+	}
+	out := make([]fr.Element, ElementsPerChunk)
+	copy(out, m.elems[index*ElementsPerChunk:(index+1)*ElementsPerChunk])
+	return out, nil
+}
+
+// WriteChunk overwrites chunk index with elems.
+func (m *MemoryStore) WriteChunk(index int, elems []fr.Element) error {
+	if index < 0 || index >= m.NumChunks() {
+		return fmt.Errorf("chunk index %d out of range [0, %d)", index, m.NumChunks())
+	}
+	if len(elems) != ElementsPerChunk {
+		return fmt.Errorf("chunk has %d elements, expected %d", len(elems), ElementsPerChunk)
+	}
+	copy(m.elems[index*ElementsPerChunk:], elems)
+	return nil
+}
+
+// Elements returns the flattened contents of the store. The slice aliases
+// the store's memory.
+func (m *MemoryStore) Elements() []fr.Element {
+	return m.elems
+}
+
+// ---------------------------------------------------------------------------
+// File-backed store
+// ---------------------------------------------------------------------------
+
+// elementBytes is the on-disk size of one field element (32-byte big-endian).
+const elementBytes = fr.Bytes
+
+// ReadWriterAt is the random-access file interface used by FileStore
+// (satisfied by *os.File).
+type ReadWriterAt interface {
+	io.ReaderAt
+	io.WriterAt
+}
+
+// FileStore is an ElementStore that keeps chunks on disk as consecutive
+// 32-byte big-endian field elements, so replicas of any size can be sealed
+// with bounded memory.
+type FileStore struct {
+	f         ReadWriterAt
+	numChunks int
+}
+
+// NewFileStore wraps f as a store of numChunks chunks. Chunk i occupies
+// bytes [i*ElementsPerChunk*32, (i+1)*ElementsPerChunk*32).
+func NewFileStore(f ReadWriterAt, numChunks int) *FileStore {
+	return &FileStore{f: f, numChunks: numChunks}
+}
+
+// NumChunks returns the number of chunks in the store.
+func (s *FileStore) NumChunks() int {
+	return s.numChunks
+}
+
+// ReadChunk reads and decodes chunk index.
+func (s *FileStore) ReadChunk(index int) ([]fr.Element, error) {
+	if index < 0 || index >= s.numChunks {
+		return nil, fmt.Errorf("chunk index %d out of range [0, %d)", index, s.numChunks)
+	}
+	buf := make([]byte, ElementsPerChunk*elementBytes)
+	if _, err := s.f.ReadAt(buf, int64(index)*int64(len(buf))); err != nil {
+		return nil, fmt.Errorf("read chunk %d: %w", index, err)
+	}
+	elems := make([]fr.Element, ElementsPerChunk)
+	for i := range elems {
+		if err := elems[i].SetBytesCanonical(buf[i*elementBytes : (i+1)*elementBytes]); err != nil {
+			return nil, fmt.Errorf("decode chunk %d element %d: %w", index, i, err)
+		}
+	}
+	return elems, nil
+}
+
+// WriteChunk encodes and writes chunk index.
+func (s *FileStore) WriteChunk(index int, elems []fr.Element) error {
+	if index < 0 || index >= s.numChunks {
+		return fmt.Errorf("chunk index %d out of range [0, %d)", index, s.numChunks)
+	}
+	if len(elems) != ElementsPerChunk {
+		return fmt.Errorf("chunk has %d elements, expected %d", len(elems), ElementsPerChunk)
+	}
+	buf := make([]byte, ElementsPerChunk*elementBytes)
+	for i := range elems {
+		b := elems[i].Bytes()
+		copy(buf[i*elementBytes:], b[:])
+	}
+	if _, err := s.f.WriteAt(buf, int64(index)*int64(len(buf))); err != nil {
+		return fmt.Errorf("write chunk %d: %w", index, err)
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// Raw chunk adapters
+// ---------------------------------------------------------------------------
+
+// byteElementReader adapts raw FileSize-byte chunks to an ElementReader.
+type byteElementReader [][]byte
+
+// NewByteElementReader returns an ElementReader over raw original chunks,
+// using the same element layout as the leaf hash (crypto.ChunkToElements).
+func NewByteElementReader(chunks [][]byte) ElementReader {
+	return byteElementReader(chunks)
+}
+
+func (b byteElementReader) NumChunks() int {
+	return len(b)
+}
+
+func (b byteElementReader) ReadChunk(index int) ([]fr.Element, error) {
+	if index < 0 || index >= len(b) {
+		return nil, fmt.Errorf("chunk index %d out of range [0, %d)", index, len(b))
+	}
+	return chunkElements(index, b[index])
+}
+
+// storeElementReader adapts a merkle.ChunkStore to an ElementReader.
+type storeElementReader struct {
+	ctx   context.Context
+	store merkle.ChunkStore
+}
+
+// NewStoreElementReader returns an ElementReader over the raw original
+// chunks of store, so a file served from disk or over HTTP can be sealed
+// without loading it. Reads use ctx. The store's chunk size must be
+// FileSize.
+func NewStoreElementReader(ctx context.Context, store merkle.ChunkStore) (ElementReader, error) {
+	if store.ChunkSize() != FileSize {
+		return nil, fmt.Errorf("store chunk size %d does not match %d", store.ChunkSize(), FileSize)
+	}
+	return storeElementReader{ctx: ctx, store: store}, nil
+}
+
+func (s storeElementReader) NumChunks() int {
+	return s.store.NumChunks()
+}
+
+func (s storeElementReader) ReadChunk(index int) ([]fr.Element, error) {
+	chunk, err := s.store.ReadChunk(s.ctx, index)
+	if err != nil {
+		return nil, err
+	}
+	return chunkElements(index, chunk)
+}
+
+// chunkElements converts raw chunk index to its ElementsPerChunk elements.
+func chunkElements(index int, chunk []byte) ([]fr.Element, error) {
+	if len(chunk) > FileSize {
+		return nil, fmt.Errorf("chunk %d has %d bytes, exceeds %d", index, len(chunk), FileSize)
+	}
+	return crypto.ChunkToElements(chunk, ElementSize, ElementsPerChunk), nil
+}
+
+// ChunkFromElements is the inverse of crypto.ChunkToElements for a full
+// chunk: it packs ElementsPerChunk unsealed elements back into FileSize raw
+// bytes. A short final chunk comes back zero-padded to FileSize.
+func ChunkFromElements(elems []fr.Element) ([]byte, error) {
+	if len(elems) != ElementsPerChunk {
+		return nil, fmt.Errorf("chunk has %d elements, expected %d", len(elems), ElementsPerChunk)
+	}
+	out := make([]byte, ElementsPerChunk*ElementSize)
+	for i := range elems {
+		b := elems[i].Bytes()
+		if b[0] != 0 {
+			return nil, fmt.Errorf("element %d exceeds %d bytes", i, ElementSize)
+		}
+		copy(out[i*ElementSize:], b[elementBytes-ElementSize:])
+	}
+	return out[:FileSize], nil
+}